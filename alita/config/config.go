@@ -24,6 +24,7 @@ type Config struct {
 	OwnerId            int64 `validate:"required,min=1"`
 	MessageDump        int64 `validate:"required,min=1"`
 	DropPendingUpdates bool
+	GbanBroadcast      bool // Whether to log gbans and ungbans to MessageDump
 	AllowedUpdates     []string
 	ValidLangCodes     []string
 
@@ -93,6 +94,13 @@ type Config struct {
 	ResourceMaxGoroutines int `validate:"min=100,max=10000"` // Maximum goroutines before triggering cleanup
 	ResourceMaxMemoryMB   int `validate:"min=100,max=10000"` // Maximum memory usage in MB
 	ResourceGCThresholdMB int `validate:"min=100,max=5000"`  // Memory threshold for triggering GC
+
+	// Scheduled job settings
+	SchedulerWorkers   int  `validate:"min=1,max=20"` // Number of goroutines running scheduled jobs
+	SchedulerRedisLock bool // Also lock jobs in Redis while they run
+
+	// Rate state settings
+	RateStateBackend string `validate:"oneof=memory redis"` // Where antiflood and antispam keep their counters
 }
 
 // Global configuration instance
@@ -115,6 +123,7 @@ var (
 	DropPendingUpdates = true
 	OwnerId            int64
 	MessageDump        int64
+	GbanBroadcast      bool
 	RedisAddress       string
 	RedisPassword      string
 	RedisDB            int
@@ -169,6 +178,13 @@ var (
 	ResourceMaxMemoryMB   int
 	ResourceGCThresholdMB int
 
+	// Scheduled job settings
+	SchedulerWorkers   int
+	SchedulerRedisLock bool
+
+	// Rate state settings
+	RateStateBackend string
+
 	// Global config instance
 	AppConfig *Config
 )
@@ -221,6 +237,12 @@ func ValidateConfig(cfg *Config) error {
 	if cfg.StatsCollectionWorkers <= 0 || cfg.StatsCollectionWorkers > 10 {
 		return fmt.Errorf("STATS_COLLECTION_WORKERS must be between 1 and 10")
 	}
+	if cfg.SchedulerWorkers <= 0 || cfg.SchedulerWorkers > 20 {
+		return fmt.Errorf("SCHEDULER_WORKERS must be between 1 and 20")
+	}
+	if cfg.RateStateBackend != "memory" && cfg.RateStateBackend != "redis" {
+		return fmt.Errorf("RATE_STATE_BACKEND must be either memory or redis")
+	}
 
 	// Cache validation removed - using Redis only
 
@@ -270,6 +292,7 @@ func LoadConfig() (*Config, error) {
 		OwnerId:            typeConvertor{str: os.Getenv("OWNER_ID")}.Int64(),
 		MessageDump:        typeConvertor{str: os.Getenv("MESSAGE_DUMP")}.Int64(),
 		DropPendingUpdates: typeConvertor{str: os.Getenv("DROP_PENDING_UPDATES")}.Bool(),
+		GbanBroadcast:      typeConvertor{str: os.Getenv("GBAN_BROADCAST")}.Bool(),
 
 		// Database configuration
 		DatabaseURL: os.Getenv("DATABASE_URL"),
@@ -335,6 +358,13 @@ func LoadConfig() (*Config, error) {
 		ResourceMaxGoroutines: typeConvertor{str: os.Getenv("RESOURCE_MAX_GOROUTINES")}.Int(),
 		ResourceMaxMemoryMB:   typeConvertor{str: os.Getenv("RESOURCE_MAX_MEMORY_MB")}.Int(),
 		ResourceGCThresholdMB: typeConvertor{str: os.Getenv("RESOURCE_GC_THRESHOLD_MB")}.Int(),
+
+		// Scheduled job settings
+		SchedulerWorkers:   typeConvertor{str: os.Getenv("SCHEDULER_WORKERS")}.Int(),
+		SchedulerRedisLock: typeConvertor{str: os.Getenv("SCHEDULER_REDIS_LOCK")}.Bool(),
+
+		// Rate state settings
+		RateStateBackend: os.Getenv("RATE_STATE_BACKEND"),
 	}
 
 	// Set defaults
@@ -522,6 +552,17 @@ func (cfg *Config) setDefaults() {
 	if cfg.ResourceGCThresholdMB == 0 {
 		cfg.ResourceGCThresholdMB = 400
 	}
+
+	// Set scheduler defaults
+	if cfg.SchedulerWorkers == 0 {
+		cfg.SchedulerWorkers = 4
+	}
+	// SchedulerRedisLock defaults to false, Postgres row locks already keep instances apart
+
+	// Set rate state defaults
+	if cfg.RateStateBackend == "" {
+		cfg.RateStateBackend = "memory"
+	}
 }
 
 // init initializes the logging configuration, loads the global configuration
@@ -564,6 +605,7 @@ func init() {
 	DropPendingUpdates = cfg.DropPendingUpdates
 	OwnerId = cfg.OwnerId
 	MessageDump = cfg.MessageDump
+	GbanBroadcast = cfg.GbanBroadcast
 	RedisAddress = cfg.RedisAddress
 	RedisPassword = cfg.RedisPassword
 	RedisDB = cfg.RedisDB
@@ -602,6 +644,9 @@ func init() {
 	ResourceMaxGoroutines = cfg.ResourceMaxGoroutines
 	ResourceMaxMemoryMB = cfg.ResourceMaxMemoryMB
 	ResourceGCThresholdMB = cfg.ResourceGCThresholdMB
+	SchedulerWorkers = cfg.SchedulerWorkers
+	SchedulerRedisLock = cfg.SchedulerRedisLock
+	RateStateBackend = cfg.RateStateBackend
 	AllowedUpdates = cfg.AllowedUpdates
 	ValidLangCodes = cfg.ValidLangCodes
 
@@ -62,16 +62,17 @@ func SetFlood(chatID int64, limit int) {
 }
 
 // SetFloodMode Set flood mode for a chat
-func SetFloodMode(chatID int64, mode string) {
+// The duration in seconds is only used by the timed tmute and tban modes.
+func SetFloodMode(chatID int64, mode string, duration int64) {
 	floodSrc := checkFloodSetting(chatID)
 	// Check if update is actually needed
-	if floodSrc.Action == mode {
+	if floodSrc.Action == mode && floodSrc.Mode == mode && floodSrc.ActionDuration == duration {
 		return
 	}
-	// create or update the mode in db
+	// create or update the mode in db, the watcher reads the mode column
 	settings := AntifloodSettings{ChatId: chatID}
 	err := DB.Where(AntifloodSettings{ChatId: chatID}).
-		Assign(AntifloodSettings{Action: mode}).
+		Assign(map[string]any{"action": mode, "mode": mode, "action_duration": duration}).
 		FirstOrCreate(&settings).Error
 	if err != nil {
 		log.Errorf("[Database] SetFloodMode: %v - %d", err, chatID)
@@ -100,6 +101,36 @@ func SetFloodMsgDel(chatID int64, val bool) {
 	deleteCache(optimizedAntifloodCacheKey(chatID))
 }
 
+// SetFloodTimer sets how many messages a user may send within the given number of seconds in a chat.
+// A limit of 0 turns the timed mode off.
+func SetFloodTimer(chatID int64, limit, seconds int) {
+	settings := AntifloodSettings{ChatId: chatID}
+	err := DB.Where(AntifloodSettings{ChatId: chatID}).
+		Assign(map[string]any{"timer_limit": limit, "timer_seconds": seconds}).
+		FirstOrCreate(&settings).Error
+	if err != nil {
+		log.Errorf("[Database] SetFloodTimer: %v - %d", err, chatID)
+		return
+	}
+	// Invalidate cache after update
+	deleteCache(optimizedAntifloodCacheKey(chatID))
+}
+
+// SetFloodTimerMode sets the action taken on users tripping the timed mode of a chat.
+// The duration in seconds is only used by the timed tmute and tban modes.
+func SetFloodTimerMode(chatID int64, mode string, duration int64) {
+	settings := AntifloodSettings{ChatId: chatID}
+	err := DB.Where(AntifloodSettings{ChatId: chatID}).
+		Assign(map[string]any{"timer_action": mode, "timer_action_duration": duration}).
+		FirstOrCreate(&settings).Error
+	if err != nil {
+		log.Errorf("[Database] SetFloodTimerMode: %v - %d", err, chatID)
+		return
+	}
+	// Invalidate cache after update
+	deleteCache(optimizedAntifloodCacheKey(chatID))
+}
+
 // LoadAntifloodStats returns the count of chats with antiflood enabled (limit > 0).
 func LoadAntifloodStats() (antiCount int64) {
 	var totalCount int64
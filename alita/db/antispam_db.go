@@ -0,0 +1,156 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Actions taken when a chat exceeds its antispam levels
+const (
+	AntispamActionDrop     = "drop"
+	AntispamActionSlowmode = "slowmode"
+	AntispamActionRestrict = "restrict"
+)
+
+// Scopes an antispam level counts messages in
+const (
+	AntispamScopeChat = "chat"
+	AntispamScopeUser = "user"
+)
+
+// DefaultAntispamActionDuration is how long the slowmode and restrict actions last by default, in seconds.
+const DefaultAntispamActionDuration = 300
+
+// defaultAntispamSettings returns the antispam settings of a chat that hasn't configured them.
+func defaultAntispamSettings(chatId int64) *AntispamSettings {
+	return &AntispamSettings{
+		ChatId:         chatId,
+		Action:         AntispamActionDrop,
+		ActionDuration: DefaultAntispamActionDuration,
+	}
+}
+
+// GetAntispamSettings retrieves the antispam settings of a chat with caching support.
+// Returns the default settings if the chat hasn't configured antispam.
+func GetAntispamSettings(chatId int64) *AntispamSettings {
+	settings, err := getFromCacheOrLoad(antispamSettingsCacheKey(chatId), CacheTTLAntispam, func() (*AntispamSettings, error) {
+		settings := &AntispamSettings{}
+		err := GetRecord(settings, AntispamSettings{ChatId: chatId})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaultAntispamSettings(chatId), nil
+		} else if err != nil {
+			log.Errorf("[Database] GetAntispamSettings: %v - %d", err, chatId)
+			return nil, err
+		}
+		return settings, nil
+	})
+	if err != nil || settings == nil {
+		return defaultAntispamSettings(chatId)
+	}
+	return settings
+}
+
+// UpdateAntispamSettings changes the given antispam settings of a chat, creating them with defaults if needed.
+// Keys of updates are column names.
+func UpdateAntispamSettings(chatId int64, updates map[string]any) error {
+	err := DB.Where("chat_id = ?", chatId).
+		Assign(updates).
+		FirstOrCreate(defaultAntispamSettings(chatId)).Error
+	if err != nil {
+		log.Errorf("[Database] UpdateAntispamSettings: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(antispamSettingsCacheKey(chatId))
+	return nil
+}
+
+// RecordAntispamTrip counts a time the antispam levels of a chat were exceeded.
+func RecordAntispamTrip(chatId int64) error {
+	err := DB.Where("chat_id = ?", chatId).
+		FirstOrCreate(defaultAntispamSettings(chatId)).Error
+	if err == nil {
+		err = DB.Model(&AntispamSettings{}).Where("chat_id = ?", chatId).Updates(map[string]any{
+			"trips":           gorm.Expr("trips + 1"),
+			"last_tripped_at": time.Now(),
+		}).Error
+	}
+	if err != nil {
+		log.Errorf("[Database] RecordAntispamTrip: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(antispamSettingsCacheKey(chatId))
+	return nil
+}
+
+// SetAntispamActive records until when the slowmode or restrict action runs in a chat,
+// along with the permissions to restore when a restrict action ends.
+// A nil activeUntil marks the action as over.
+func SetAntispamActive(chatId int64, activeUntil *time.Time, savedPermissions string) error {
+	return UpdateAntispamSettings(chatId, map[string]any{
+		"active_until":      activeUntil,
+		"saved_permissions": savedPermissions,
+	})
+}
+
+// GetAntispamLevels retrieves the antispam levels configured in a chat with caching support,
+// ordered by scope and window.
+func GetAntispamLevels(chatId int64) []*AntispamLevel {
+	levels, err := getFromCacheOrLoad(antispamLevelsCacheKey(chatId), CacheTTLAntispam, func() ([]*AntispamLevel, error) {
+		var levels []*AntispamLevel
+		err := DB.Where("chat_id = ?", chatId).Order("scope ASC, seconds ASC").Find(&levels).Error
+		if err != nil {
+			log.Errorf("[Database] GetAntispamLevels: %v - %d", err, chatId)
+			return nil, err
+		}
+		return levels, nil
+	})
+	if err != nil {
+		return nil
+	}
+	return levels
+}
+
+// SetAntispamLevel sets the most messages allowed within a window of the given seconds,
+// for the whole chat or for each user depending on scope.
+func SetAntispamLevel(chatId int64, scope string, seconds, limit int) error {
+	err := DB.Where("chat_id = ? AND scope = ? AND seconds = ?", chatId, scope, seconds).
+		Assign(map[string]any{"message_limit": limit}).
+		FirstOrCreate(&AntispamLevel{ChatId: chatId, Scope: scope, Seconds: seconds}).Error
+	if err != nil {
+		log.Errorf("[Database] SetAntispamLevel: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(antispamLevelsCacheKey(chatId))
+	return nil
+}
+
+// RemoveAntispamLevel removes the antispam level with the given scope and window from a chat.
+// Returns true if such a level existed.
+func RemoveAntispamLevel(chatId int64, scope string, seconds int) bool {
+	result := DB.Where("chat_id = ? AND scope = ? AND seconds = ?", chatId, scope, seconds).Delete(&AntispamLevel{})
+	if result.Error != nil {
+		log.Errorf("[Database] RemoveAntispamLevel: %v - %d", result.Error, chatId)
+		return false
+	}
+
+	deleteCache(antispamLevelsCacheKey(chatId))
+	return result.RowsAffected > 0
+}
+
+// ResetAntispamLevels removes all antispam levels of a chat, bringing back the default level.
+func ResetAntispamLevels(chatId int64) error {
+	err := DB.Where("chat_id = ?", chatId).Delete(&AntispamLevel{}).Error
+	if err != nil {
+		log.Errorf("[Database] ResetAntispamLevels: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(antispamLevelsCacheKey(chatId))
+	return nil
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Review states of a ban appeal
+const (
+	AppealStatusPending       = "pending"
+	AppealStatusInfoRequested = "info_requested"
+	AppealStatusApproved      = "approved"
+	AppealStatusDenied        = "denied"
+)
+
+// AreAppealsEnabled checks whether banned users can appeal their ban in a chat.
+// Appeals are disabled for chats without settings.
+func AreAppealsEnabled(chatId int64) bool {
+	settings := &AppealSettings{}
+	err := GetRecord(settings, AppealSettings{ChatId: chatId})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] AreAppealsEnabled: %v - %d", err, chatId)
+		}
+		return false
+	}
+	return settings.Enabled
+}
+
+// SetAppealsEnabled enables or disables ban appeals in a chat.
+func SetAppealsEnabled(chatId int64, enabled bool) error {
+	err := DB.Where("chat_id = ?", chatId).
+		Assign(map[string]any{"enabled": enabled}).
+		FirstOrCreate(&AppealSettings{ChatId: chatId}).Error
+	if err != nil {
+		log.Errorf("[Database] SetAppealsEnabled: %v - %d", err, chatId)
+	}
+	return err
+}
+
+// CreateAppeal stores a new ban appeal awaiting review.
+func CreateAppeal(appeal *BanAppeal) error {
+	appeal.Status = AppealStatusPending
+	err := CreateRecord(appeal)
+	if err != nil {
+		log.Errorf("[Database] CreateAppeal: %v - %d", err, appeal.ChatId)
+	}
+	return err
+}
+
+// GetAppeal retrieves a ban appeal by its ID.
+// Returns nil if the appeal doesn't exist.
+func GetAppeal(id uint) *BanAppeal {
+	appeal := &BanAppeal{}
+	err := DB.Where("id = ?", id).First(appeal).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetAppeal: %v - %d", err, id)
+		}
+		return nil
+	}
+	return appeal
+}
+
+// GetOpenAppeal retrieves the appeal of a user in a chat that is still under review.
+// Returns nil if the user has no open appeal.
+func GetOpenAppeal(chatId, userId int64) *BanAppeal {
+	appeal := &BanAppeal{}
+	err := DB.Where("chat_id = ? AND user_id = ? AND status IN ?", chatId, userId,
+		[]string{AppealStatusPending, AppealStatusInfoRequested}).
+		Order("created_at DESC").First(appeal).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetOpenAppeal: %v - %d", err, chatId)
+		}
+		return nil
+	}
+	return appeal
+}
+
+// GetLastDeniedAppeal retrieves the most recently denied appeal of a user in a chat.
+// Returns nil if none of the user's appeals were denied.
+func GetLastDeniedAppeal(chatId, userId int64) *BanAppeal {
+	appeal := &BanAppeal{}
+	err := DB.Where("chat_id = ? AND user_id = ? AND status = ?", chatId, userId, AppealStatusDenied).
+		Order("reviewed_at DESC").First(appeal).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetLastDeniedAppeal: %v - %d", err, chatId)
+		}
+		return nil
+	}
+	return appeal
+}
+
+// ReviewAppeal moves a pending appeal to a new status on behalf of an admin.
+// Returns false if the appeal was no longer pending, so two admins can't handle it at once.
+func ReviewAppeal(id uint, status string, reviewerId int64) bool {
+	now := time.Now()
+	result := DB.Model(&BanAppeal{}).Where("id = ? AND status = ?", id, AppealStatusPending).Updates(map[string]any{
+		"status":      status,
+		"reviewed_by": reviewerId,
+		"reviewed_at": &now,
+	})
+	if result.Error != nil {
+		log.Errorf("[Database] ReviewAppeal: %v - %d", result.Error, id)
+		return false
+	}
+	return result.RowsAffected > 0
+}
+
+// ReopenAppeal puts an appeal an admin just moved to status back up for review,
+// when the action that came with the review couldn't be carried out.
+func ReopenAppeal(id uint, status string) {
+	err := DB.Model(&BanAppeal{}).Where("id = ? AND status = ?", id, status).Updates(map[string]any{
+		"status":      AppealStatusPending,
+		"reviewed_by": 0,
+		"reviewed_at": nil,
+	}).Error
+	if err != nil {
+		log.Errorf("[Database] ReopenAppeal: %v - %d", err, id)
+	}
+}
+
+// AddAppealInfo appends the extra information an admin asked for to an appeal and puts it back up for review.
+// Returns false if no information was requested for the appeal.
+func AddAppealInfo(id uint, info string) bool {
+	result := DB.Model(&BanAppeal{}).Where("id = ? AND status = ?", id, AppealStatusInfoRequested).Updates(map[string]any{
+		"statement": gorm.Expr("statement || ?", "\n\n"+info),
+		"status":    AppealStatusPending,
+	})
+	if result.Error != nil {
+		log.Errorf("[Database] AddAppealInfo: %v - %d", result.Error, id)
+		return false
+	}
+	return result.RowsAffected > 0
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ApproveUser exempts a user from automated moderation in a chat.
+// Returns true if the user was already approved.
+func ApproveUser(chatId, userId, approvedBy int64) (alreadyApproved bool, err error) {
+	alreadyApproved = IsUserApproved(chatId, userId)
+	if alreadyApproved {
+		return
+	}
+
+	err = DB.Where("chat_id = ? AND user_id = ?", chatId, userId).
+		FirstOrCreate(&ApprovedUser{ChatId: chatId, UserId: userId, ApprovedBy: approvedBy}).Error
+	if err != nil {
+		log.Errorf("[Database] ApproveUser: %v - %d", err, chatId)
+		return
+	}
+
+	deleteCache(approvedUsersCacheKey(chatId))
+	return
+}
+
+// UnapproveUser removes the approval of a user in a chat.
+// Returns true if the user was approved.
+func UnapproveUser(chatId, userId int64) bool {
+	result := DB.Where("chat_id = ? AND user_id = ?", chatId, userId).Delete(&ApprovedUser{})
+	if result.Error != nil {
+		log.Errorf("[Database] UnapproveUser: %v - %d", result.Error, chatId)
+		return false
+	}
+
+	deleteCache(approvedUsersCacheKey(chatId))
+	return result.RowsAffected > 0
+}
+
+// UnapproveAllUsers removes every approved user of a chat.
+// Returns the number of users whose approval was removed.
+func UnapproveAllUsers(chatId int64) int64 {
+	result := DB.Where("chat_id = ?", chatId).Delete(&ApprovedUser{})
+	if result.Error != nil {
+		log.Errorf("[Database] UnapproveAllUsers: %v - %d", result.Error, chatId)
+		return 0
+	}
+
+	deleteCache(approvedUsersCacheKey(chatId))
+	return result.RowsAffected
+}
+
+// GetApprovedUsers retrieves the approved users of a chat, oldest approval first.
+func GetApprovedUsers(chatId int64) (users []*ApprovedUser) {
+	err := DB.Where("chat_id = ?", chatId).Order("created_at ASC, id ASC").Find(&users).Error
+	if err != nil {
+		log.Errorf("[Database] GetApprovedUsers: %v - %d", err, chatId)
+		return nil
+	}
+	return users
+}
+
+// getApprovedUserIds retrieves the IDs of the approved users of a chat with caching support.
+func getApprovedUserIds(chatId int64) []int64 {
+	userIds, err := getFromCacheOrLoad(approvedUsersCacheKey(chatId), CacheTTLApprovals, func() ([]int64, error) {
+		userIds := make([]int64, 0)
+		err := DB.Model(&ApprovedUser{}).Where("chat_id = ?", chatId).Pluck("user_id", &userIds).Error
+		if err != nil {
+			log.Errorf("[Database] getApprovedUserIds: %v - %d", err, chatId)
+			return nil, err
+		}
+		return userIds, nil
+	})
+	if err != nil {
+		return nil
+	}
+	return userIds
+}
+
+// IsUserApproved checks whether a user is exempt from automated moderation in a chat.
+func IsUserApproved(chatId, userId int64) bool {
+	if userId <= 0 {
+		return false
+	}
+	return slices.Contains(getApprovedUserIds(chatId), userId)
+}
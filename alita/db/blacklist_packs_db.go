@@ -0,0 +1,270 @@
+package db
+
+import (
+	"errors"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBlacklistPackNotFound is returned when no blacklist pack has the given name.
+	ErrBlacklistPackNotFound = errors.New("blacklist pack not found")
+	// ErrBlacklistPackNotOwned is returned when a chat tries to change a pack published from another chat.
+	ErrBlacklistPackNotOwned = errors.New("blacklist pack is owned by another chat")
+)
+
+// ImportBlacklist adds the given triggers to the blacklist of a chat in a single transaction.
+// Triggers already blacklisted with the same match mode get the action and reason of the
+// imported ones, and every other trigger of the chat is removed first if replace is set.
+func ImportBlacklist(chatId int64, entries BlacklistSettingsSlice, replace bool) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := tx.Where("chat_id = ?", chatId).Delete(&BlacklistSettings{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, entry := range entries {
+			word := blacklistWord(entry.Word, entry.MatchMode)
+			err := tx.Where("chat_id = ? AND word = ? AND match_mode = ?", chatId, word, entry.MatchMode).
+				Assign(map[string]any{
+					"action":          nullableString(strings.ToLower(entry.Action)),
+					"action_duration": entry.ActionDuration,
+					"reason":          nullableString(entry.Reason),
+				}).
+				FirstOrCreate(&BlacklistSettings{ChatId: chatId, Word: word, MatchMode: entry.MatchMode}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[Database] ImportBlacklist: %v - %d", err, chatId)
+	}
+
+	// Invalidate cache after importing
+	deleteCache(blacklistCacheKey(chatId))
+	return err
+}
+
+// nullableString returns nil for an empty string so it is stored as NULL.
+func nullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// PublishBlacklistPack publishes the current blacklist of a chat as a named pack, replacing
+// the triggers of the pack and bumping its version if the chat already published it.
+// Sticker triggers are included, and chats subscribed to the pack get the new triggers right away.
+// Returns ErrBlacklistPackNotOwned if the name is taken by a pack of another chat.
+func PublishBlacklistPack(name string, ownerChatId int64) (*BlacklistPack, error) {
+	triggers := GetBlacklistSettings(ownerChatId)
+	pack := &BlacklistPack{}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("name = ?", name).First(pack).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			*pack = BlacklistPack{Name: name, OwnerChatId: ownerChatId, Version: 1}
+			if err := tx.Create(pack).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case pack.OwnerChatId != ownerChatId:
+			return ErrBlacklistPackNotOwned
+		default:
+			pack.Version++
+			if err := tx.Model(pack).Update("version", pack.Version).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("pack_id = ?", pack.ID).Delete(&BlacklistPackTrigger{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(triggers) == 0 {
+			return nil
+		}
+		packTriggers := make([]BlacklistPackTrigger, 0, len(triggers))
+		for _, bs := range triggers {
+			packTriggers = append(packTriggers, BlacklistPackTrigger{
+				PackId:         pack.ID,
+				Word:           bs.Word,
+				MatchMode:      bs.MatchMode,
+				Action:         bs.Action,
+				ActionDuration: bs.ActionDuration,
+				Reason:         bs.Reason,
+			})
+		}
+		return tx.Create(&packTriggers).Error
+	})
+	if err != nil {
+		if !errors.Is(err, ErrBlacklistPackNotOwned) {
+			log.Errorf("[Database] PublishBlacklistPack: %v - %d", err, ownerChatId)
+		}
+		return nil, err
+	}
+
+	// Invalidate the triggers of the pack so subscribed chats pick up the new version
+	deleteCache(blacklistPackTriggersCacheKey(pack.ID))
+	return pack, nil
+}
+
+// UnpublishBlacklistPack deletes a pack published from a chat, unsubscribing every chat from it.
+// Returns ErrBlacklistPackNotFound if the pack doesn't exist and ErrBlacklistPackNotOwned
+// if it was published from another chat.
+func UnpublishBlacklistPack(name string, ownerChatId int64) error {
+	pack := GetBlacklistPack(name)
+	if pack == nil {
+		return ErrBlacklistPackNotFound
+	}
+	if pack.OwnerChatId != ownerChatId {
+		return ErrBlacklistPackNotOwned
+	}
+
+	var subscribers []int64
+	if err := DB.Model(&BlacklistPackSubscription{}).Where("pack_id = ?", pack.ID).Pluck("chat_id", &subscribers).Error; err != nil {
+		log.Errorf("[Database] UnpublishBlacklistPack: %v - %d", err, ownerChatId)
+		return err
+	}
+
+	// Triggers and subscriptions of the pack are removed by the cascading foreign keys
+	if err := DB.Delete(&BlacklistPack{}, pack.ID).Error; err != nil {
+		log.Errorf("[Database] UnpublishBlacklistPack: %v - %d", err, ownerChatId)
+		return err
+	}
+
+	deleteCache(blacklistPackTriggersCacheKey(pack.ID))
+	for _, chatId := range subscribers {
+		deleteCache(blacklistPackSubscriptionsCacheKey(chatId))
+	}
+	return nil
+}
+
+// GetBlacklistPack retrieves a blacklist pack by its name.
+// Returns nil if the pack doesn't exist or on error.
+func GetBlacklistPack(name string) *BlacklistPack {
+	pack := &BlacklistPack{}
+	err := GetRecord(pack, BlacklistPack{Name: name})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetBlacklistPack: %v - %s", err, name)
+		}
+		return nil
+	}
+	return pack
+}
+
+// GetBlacklistPacks retrieves every published blacklist pack ordered by name.
+func GetBlacklistPacks() []*BlacklistPack {
+	var packs []*BlacklistPack
+	err := DB.Order("name").Find(&packs).Error
+	if err != nil {
+		log.Errorf("[Database] GetBlacklistPacks: %v", err)
+		return nil
+	}
+	return packs
+}
+
+// SubscribeBlacklistPack subscribes a chat to a blacklist pack, enforcing its triggers along with the chat's own.
+func SubscribeBlacklistPack(chatId, packId int64) {
+	err := DB.Where("chat_id = ? AND pack_id = ?", chatId, packId).
+		FirstOrCreate(&BlacklistPackSubscription{ChatId: chatId, PackId: packId}).Error
+	if err != nil {
+		log.Errorf("[Database] SubscribeBlacklistPack: %v - %d", err, chatId)
+	}
+
+	// Invalidate cache after subscribing
+	deleteCache(blacklistPackSubscriptionsCacheKey(chatId))
+}
+
+// UnsubscribeBlacklistPack unsubscribes a chat from a blacklist pack.
+// Returns true if the chat was subscribed to the pack.
+func UnsubscribeBlacklistPack(chatId, packId int64) bool {
+	result := DB.Where("chat_id = ? AND pack_id = ?", chatId, packId).Delete(&BlacklistPackSubscription{})
+	if result.Error != nil {
+		log.Errorf("[Database] UnsubscribeBlacklistPack: %v - %d", result.Error, chatId)
+		return false
+	}
+
+	// Invalidate cache if something was deleted
+	if result.RowsAffected > 0 {
+		deleteCache(blacklistPackSubscriptionsCacheKey(chatId))
+	}
+	return result.RowsAffected > 0
+}
+
+// GetBlacklistPackSubscriptions retrieves the blacklist packs a chat is subscribed to with caching support.
+func GetBlacklistPackSubscriptions(chatId int64) []*BlacklistPack {
+	packs, err := getFromCacheOrLoad(blacklistPackSubscriptionsCacheKey(chatId), CacheTTLBlacklist, func() ([]*BlacklistPack, error) {
+		var packs []*BlacklistPack
+		err := DB.Joins("JOIN blacklist_pack_subscriptions ON blacklist_pack_subscriptions.pack_id = blacklist_packs.id").
+			Where("blacklist_pack_subscriptions.chat_id = ?", chatId).
+			Order("blacklist_packs.name").
+			Find(&packs).Error
+		if err != nil {
+			log.Errorf("[Database] GetBlacklistPackSubscriptions: %v - %d", err, chatId)
+			return nil, err
+		}
+		return packs, nil
+	})
+	if err != nil {
+		return nil
+	}
+	return packs
+}
+
+// GetBlacklistPackTriggers retrieves the triggers of a blacklist pack with caching support,
+// as blacklist settings without a chat.
+func GetBlacklistPackTriggers(packId int64) BlacklistSettingsSlice {
+	result, err := getFromCacheOrLoad(blacklistPackTriggersCacheKey(packId), CacheTTLBlacklist, func() (BlacklistSettingsSlice, error) {
+		var packTriggers []*BlacklistPackTrigger
+		err := GetRecords(&packTriggers, BlacklistPackTrigger{PackId: packId})
+		if err != nil {
+			log.Errorf("[Database] GetBlacklistPackTriggers: %v - %d", err, packId)
+			return BlacklistSettingsSlice{}, err
+		}
+		triggers := make(BlacklistSettingsSlice, 0, len(packTriggers))
+		for _, pt := range packTriggers {
+			triggers = append(triggers, &BlacklistSettings{
+				Word:           pt.Word,
+				MatchMode:      pt.MatchMode,
+				Action:         pt.Action,
+				ActionDuration: pt.ActionDuration,
+				Reason:         pt.Reason,
+			})
+		}
+		return triggers, nil
+	})
+	if err != nil {
+		return BlacklistSettingsSlice{}
+	}
+	return result
+}
+
+// GetEffectiveBlacklist returns the triggers enforced in a chat: its own blacklist followed by
+// the triggers of the packs it is subscribed to. A trigger of the chat takes precedence over
+// the same trigger in a pack, and the first pack wins when several packs share a trigger.
+func GetEffectiveBlacklist(chatId int64) BlacklistSettingsSlice {
+	own := GetBlacklistSettings(chatId)
+	packs := GetBlacklistPackSubscriptions(chatId)
+	if len(packs) == 0 {
+		return own
+	}
+
+	effective := make(BlacklistSettingsSlice, 0, len(own))
+	effective = append(effective, own...)
+	for _, pack := range packs {
+		for _, bs := range GetBlacklistPackTriggers(pack.ID) {
+			if effective.Get(bs.Word, bs.MatchMode) == nil {
+				effective = append(effective, bs)
+			}
+		}
+	}
+	return effective
+}
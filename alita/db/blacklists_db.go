@@ -1,34 +1,58 @@
 package db
 
 import (
+	"errors"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
-// AddBlacklist adds a new blacklist word to a chat with default 'warn' action.
-// The trigger is converted to lowercase before storage.
-func AddBlacklist(chatId int64, trigger string) {
+// DefaultBlacklistAction is the blacklist action of chats that haven't chosen one.
+const DefaultBlacklistAction = "warn"
+
+// DefaultBlacklistNormalization is the text normalization level of chats that haven't chosen one.
+const DefaultBlacklistNormalization = "off"
+
+// blacklistWord returns a trigger the way it is stored for its match mode.
+// Triggers are lowercased, except for regexes which are matched case-insensitively
+// anyway and sticker ids which are case-sensitive.
+func blacklistWord(trigger, matchMode string) string {
+	if matchMode == "regex" || matchMode == "sticker" {
+		return trigger
+	}
+	return strings.ToLower(trigger)
+}
+
+// AddBlacklist adds a new blacklist trigger to a chat, matched according to matchMode.
+// An empty action or reason makes the trigger use the chat default, and the duration
+// in seconds is only used by the timed tmute and tban actions.
+// Returns an error if the trigger couldn't be saved.
+func AddBlacklist(chatId int64, trigger, matchMode, action string, duration int64, reason string) error {
 	// Create a new blacklist entry
 	blacklist := &BlacklistSettings{
-		ChatId: chatId,
-		Word:   strings.ToLower(trigger),
-		Action: "warn", // default action
+		ChatId:         chatId,
+		Word:           blacklistWord(trigger, matchMode),
+		MatchMode:      matchMode,
+		Action:         strings.ToLower(action),
+		ActionDuration: duration,
+		Reason:         reason,
 	}
 
 	err := CreateRecord(blacklist)
 	if err != nil {
 		log.Errorf("[Database] AddBlacklist: %v - %d", err, chatId)
+		return err
 	}
 
 	// Invalidate cache after adding blacklist
 	deleteCache(blacklistCacheKey(chatId))
+	return nil
 }
 
-// RemoveBlacklist removes a specific blacklist word from a chat.
-// The trigger is converted to lowercase before removal.
-func RemoveBlacklist(chatId int64, trigger string) {
-	result := DB.Where("chat_id = ? AND word = ?", chatId, strings.ToLower(trigger)).Delete(&BlacklistSettings{})
+// RemoveBlacklist removes a specific blacklist trigger with the given match mode from a chat.
+func RemoveBlacklist(chatId int64, trigger, matchMode string) {
+	result := DB.Where("chat_id = ? AND word = ? AND match_mode = ?", chatId, blacklistWord(trigger, matchMode), matchMode).Delete(&BlacklistSettings{})
 	if result.Error != nil {
 		log.Errorf("[Database] RemoveBlacklist: %v - %d", result.Error, chatId)
 	}
@@ -50,16 +74,54 @@ func RemoveAllBlacklist(chatId int64) {
 	deleteCache(blacklistCacheKey(chatId))
 }
 
-// SetBlacklistAction updates the action for all blacklist entries in a chat.
-// The action is converted to lowercase before storage.
-func SetBlacklistAction(chatId int64, action string) {
-	err := DB.Model(&BlacklistSettings{}).Where("chat_id = ?", chatId).Update("action", strings.ToLower(action)).Error
+// SetBlacklistAction updates the default action and reason of a chat, used by the triggers without one of their own.
+// The action is converted to lowercase before storage, and the duration in seconds
+// is only used by the timed tmute and tban actions.
+func SetBlacklistAction(chatId int64, action string, duration int64, reason string) {
+	err := DB.Where("chat_id = ?", chatId).
+		Assign(map[string]any{"action": strings.ToLower(action), "action_duration": duration, "reason": reason}).
+		FirstOrCreate(&BlacklistChatSettings{ChatId: chatId}).Error
 	if err != nil {
 		log.Errorf("[Database] SetBlacklistAction: %v - %d", err, chatId)
 	}
 
 	// Invalidate cache after updating action
-	deleteCache(blacklistCacheKey(chatId))
+	deleteCache(blacklistChatSettingsCacheKey(chatId))
+}
+
+// SetBlacklistNormalization updates the text normalization level applied before matching
+// the blacklists and filters of a chat.
+func SetBlacklistNormalization(chatId int64, level string) {
+	err := DB.Where("chat_id = ?", chatId).
+		Assign(map[string]any{"normalization": level}).
+		FirstOrCreate(&BlacklistChatSettings{ChatId: chatId, Action: DefaultBlacklistAction}).Error
+	if err != nil {
+		log.Errorf("[Database] SetBlacklistNormalization: %v - %d", err, chatId)
+	}
+
+	// Invalidate cache after updating normalization
+	deleteCache(blacklistChatSettingsCacheKey(chatId))
+}
+
+// GetBlacklistChatSettings retrieves the default blacklist action of a chat with caching support.
+// Returns the default settings if the chat hasn't chosen an action.
+func GetBlacklistChatSettings(chatId int64) *BlacklistChatSettings {
+	defaults := &BlacklistChatSettings{ChatId: chatId, Action: DefaultBlacklistAction, Normalization: DefaultBlacklistNormalization}
+	settings, err := getFromCacheOrLoad(blacklistChatSettingsCacheKey(chatId), CacheTTLBlacklist, func() (*BlacklistChatSettings, error) {
+		settings := &BlacklistChatSettings{}
+		err := GetRecord(settings, BlacklistChatSettings{ChatId: chatId})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return defaults, nil
+		} else if err != nil {
+			log.Errorf("[Database] GetBlacklistChatSettings: %v - %d", err, chatId)
+			return nil, err
+		}
+		return settings, nil
+	})
+	if err != nil || settings == nil {
+		return defaults
+	}
+	return settings
 }
 
 // GetBlacklistSettings retrieves all blacklist settings for a chat with caching support.
@@ -22,6 +22,12 @@ const (
 	CacheTTLWarnSettings = 30 * time.Minute
 	CacheTTLAntiflood    = 30 * time.Minute
 	CacheTTLDisabledCmds = 30 * time.Minute
+	CacheTTLFedChat      = 30 * time.Minute
+	CacheTTLGban         = 30 * time.Minute
+	CacheTTLLogChannel   = 30 * time.Minute
+	CacheTTLApprovals    = 30 * time.Minute
+	CacheTTLLockDomains  = 30 * time.Minute
+	CacheTTLAntispam     = 30 * time.Minute
 )
 
 // Singleflight group for preventing cache stampede
@@ -65,6 +71,61 @@ func disabledCommandsCacheKey(chatID int64) string {
 	return fmt.Sprintf("alita:disabled_cmds:%d", chatID)
 }
 
+// fedChatCacheKey generates a cache key for the federation a chat belongs to.
+func fedChatCacheKey(chatID int64) string {
+	return fmt.Sprintf("alita:fed_chat:%d", chatID)
+}
+
+// gbanCacheKey generates a cache key for the gban status of a user.
+func gbanCacheKey(userID int64) string {
+	return fmt.Sprintf("alita:gban:%d", userID)
+}
+
+// logChannelCacheKey generates a cache key for the log channel settings of a chat.
+func logChannelCacheKey(chatID int64) string {
+	return fmt.Sprintf("alita:log_channel:%d", chatID)
+}
+
+// approvedUsersCacheKey generates a cache key for the approved users of a chat.
+func approvedUsersCacheKey(chatID int64) string {
+	return fmt.Sprintf("alita:approved_users:%d", chatID)
+}
+
+// lockDomainsCacheKey generates a cache key for the allowed and blocked domains of a chat.
+func lockDomainsCacheKey(chatID int64) string {
+	return fmt.Sprintf("alita:lock_domains:%d", chatID)
+}
+
+// gbanSettingsCacheKey generates a cache key for chat gban enforcement settings.
+func gbanSettingsCacheKey(chatID int64) string {
+	return fmt.Sprintf("alita:gban_settings:%d", chatID)
+}
+
+// blacklistChatSettingsCacheKey generates a cache key for the default blacklist action of a chat.
+func blacklistChatSettingsCacheKey(chatID int64) string {
+	return fmt.Sprintf("alita:blacklist_chat_settings:%d", chatID)
+}
+
+// blacklistPackTriggersCacheKey generates a cache key for the triggers of a blacklist pack.
+func blacklistPackTriggersCacheKey(packID int64) string {
+	return fmt.Sprintf("alita:blacklist_pack_triggers:%d", packID)
+}
+
+// blacklistPackSubscriptionsCacheKey generates a cache key for the blacklist packs a chat is subscribed to.
+func blacklistPackSubscriptionsCacheKey(chatID int64) string {
+	return fmt.Sprintf("alita:blacklist_pack_subscriptions:%d", chatID)
+}
+
+// antispamSettingsCacheKey generates a cache key for chat antispam settings.
+func antispamSettingsCacheKey(chatID int64) string {
+	return fmt.Sprintf("alita:antispam_settings:%d", chatID)
+}
+
+// antispamLevelsCacheKey generates a cache key for chat antispam levels.
+func antispamLevelsCacheKey(chatID int64) string {
+	return fmt.Sprintf("alita:antispam_levels:%d", chatID)
+}
+
 // getFromCacheOrLoad is a generic helper to get from cache or load from database with stampede protection.
 // Uses singleflight pattern with timeout to prevent cache stampede and goroutine accumulation.
 func getFromCacheOrLoad[T any](key string, ttl time.Duration, loader func() (T, error)) (T, error) {
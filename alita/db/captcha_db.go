@@ -203,6 +203,24 @@ func GetCaptchaAttempt(userID, chatID int64) (*CaptchaAttempts, error) {
 	return attempt, nil
 }
 
+// GetCaptchaAttemptByID retrieves a captcha attempt by its ID, even if it has expired.
+// Returns nil if the attempt no longer exists.
+func GetCaptchaAttemptByID(attemptID uint) (*CaptchaAttempts, error) {
+	attempt := &CaptchaAttempts{}
+	err := DB.Where("id = ?", attemptID).First(attempt).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		log.Errorf("[Database][GetCaptchaAttemptByID]: %v", err)
+		return nil, err
+	}
+
+	return attempt, nil
+}
+
 // IncrementCaptchaAttempts increments the attempt counter for a captcha.
 // Returns the updated attempt record.
 func IncrementCaptchaAttempts(userID, chatID int64) (*CaptchaAttempts, error) {
@@ -178,6 +178,7 @@ type WarnSettings struct {
 	ChatId    int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"_id,omitempty"`
 	WarnLimit int       `gorm:"column:warn_limit;default:3" json:"warn_limit" default:"3"`
 	WarnMode  string    `gorm:"column:warn_mode" json:"warn_mode,omitempty"`
+	WarnTime  int64     `gorm:"column:warn_time;default:0" json:"warn_time,omitempty"`
 	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
 	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
 }
@@ -205,6 +206,40 @@ func (Warns) TableName() string {
 	return "warns_users"
 }
 
+// WarnRecord represents a single warning issued to a user in a chat
+type WarnRecord struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	UserId    int64      `gorm:"column:user_id;not null;index:idx_warn_records_user_chat" json:"user_id,omitempty"`
+	ChatId    int64      `gorm:"column:chat_id;not null;index:idx_warn_records_user_chat" json:"chat_id,omitempty"`
+	IssuedBy  int64      `gorm:"column:issued_by;default:0" json:"issued_by,omitempty"`
+	Reason    string     `gorm:"column:reason" json:"reason,omitempty"`
+	ExpiresAt *time.Time `gorm:"column:expires_at;index" json:"expires_at,omitempty"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at,omitempty"`
+}
+
+// TableName returns the database table name for the WarnRecord model.
+// This method overrides GORM's default table naming convention.
+func (WarnRecord) TableName() string {
+	return "warn_records"
+}
+
+// WarnLadderStep represents a punishment applied when a user reaches a number of warnings
+type WarnLadderStep struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId    int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_warn_ladder_steps_chat_count" json:"chat_id,omitempty"`
+	WarnCount int       `gorm:"column:warn_count;not null;uniqueIndex:uk_warn_ladder_steps_chat_count" json:"warn_count,omitempty"`
+	Action    string    `gorm:"column:action;not null" json:"action,omitempty"`
+	Duration  int64     `gorm:"column:duration;default:0" json:"duration,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the WarnLadderStep model.
+// This method overrides GORM's default table naming convention.
+func (WarnLadderStep) TableName() string {
+	return "warn_ladder_steps"
+}
+
 // WelcomeSettings represents welcome message settings
 type WelcomeSettings struct {
 	CleanWelcome  bool        `gorm:"column:clean_old;default:false" json:"clean_old" default:"false"`
@@ -245,11 +280,14 @@ func (GreetingSettings) TableName() string {
 	return "greetings"
 }
 
-// ChatFilters represents chat filters
+// ChatFilters represents a trigger of a chat filter
+// Triggers added together for one reply share the same GroupId.
 type ChatFilters struct {
 	ID          uint        `gorm:"primaryKey;autoIncrement" json:"-"`
 	ChatId      int64       `gorm:"column:chat_id;not null;index:idx_filters_chat_keyword" json:"chat_id,omitempty"`
 	KeyWord     string      `gorm:"column:keyword;not null;index:idx_filters_chat_keyword" json:"keyword,omitempty"`
+	MatchMode   string      `gorm:"column:match_mode;not null;default:substring" json:"match_mode,omitempty"`
+	GroupId     int64       `gorm:"column:group_id;not null;default:0" json:"group_id,omitempty"`
 	FilterReply string      `gorm:"column:filter_reply" json:"filter_reply,omitempty"`
 	MsgType     int         `gorm:"column:msgtype" json:"msgtype,omitempty"`
 	FileID      string      `gorm:"column:fileid" json:"fileid,omitempty"`
@@ -280,15 +318,18 @@ func (AdminSettings) TableName() string {
 	return "admin"
 }
 
-// BlacklistSettings represents blacklist settings for a chat
+// BlacklistSettings represents a blacklisted trigger of a chat
+// An empty Action or Reason means the chat default from BlacklistChatSettings is used.
 type BlacklistSettings struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
-	ChatId    int64     `gorm:"column:chat_id;not null;index:idx_blacklist_chat_word" json:"chat_id,omitempty"`
-	Word      string    `gorm:"column:word;not null;index:idx_blacklist_chat_word" json:"word,omitempty"`
-	Action    string    `gorm:"column:action;default:'warn'" json:"action,omitempty"`
-	Reason    string    `gorm:"column:reason" json:"reason,omitempty"`
-	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
-	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId         int64     `gorm:"column:chat_id;not null;index:idx_blacklist_chat_word" json:"chat_id,omitempty"`
+	Word           string    `gorm:"column:word;not null;index:idx_blacklist_chat_word" json:"word,omitempty"`
+	MatchMode      string    `gorm:"column:match_mode;not null;default:word" json:"match_mode,omitempty"`
+	Action         string    `gorm:"column:action;default:null" json:"action,omitempty"`
+	Reason         string    `gorm:"column:reason;default:null" json:"reason,omitempty"`
+	ActionDuration int64     `gorm:"column:action_duration;default:0" json:"action_duration,omitempty"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt      time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
 }
 
 // BlacklistSettingsSlice is a custom type for []*BlacklistSettings with additional methods
@@ -304,22 +345,31 @@ func (bss BlacklistSettingsSlice) Triggers() []string {
 	return triggers
 }
 
-// Action returns the action for the first blacklist setting in the slice.
-// All blacklist settings for a chat should have the same action, so we return the first one.
-func (bss BlacklistSettingsSlice) Action() string {
-	if len(bss) > 0 {
-		return bss[0].Action
+// Get returns the blacklist setting of a trigger with the given match mode, or nil if it isn't blacklisted.
+func (bss BlacklistSettingsSlice) Get(trigger, matchMode string) *BlacklistSettings {
+	for _, bs := range bss {
+		if bs.Word == trigger && bs.MatchMode == matchMode {
+			return bs
+		}
 	}
-	return "warn" // default
+	return nil
+}
+
+// ActionFor returns the action of the trigger along with its duration in seconds,
+// falling back to the default action of the chat.
+func (bs *BlacklistSettings) ActionFor(defaults *BlacklistChatSettings) (string, int64) {
+	if bs.Action != "" {
+		return bs.Action, bs.ActionDuration
+	}
+	return defaults.Action, defaults.ActionDuration
 }
 
-// Reason returns the reason for the first blacklist setting in the slice.
-// If no settings exist, returns an empty string.
-func (bss BlacklistSettingsSlice) Reason() string {
-	if len(bss) > 0 {
-		return bss[0].Reason
+// ReasonFor returns the reason of the trigger, falling back to the default reason of the chat.
+func (bs *BlacklistSettings) ReasonFor(defaults *BlacklistChatSettings) string {
+	if bs.Reason != "" {
+		return bs.Reason
 	}
-	return ""
+	return defaults.Reason
 }
 
 // TableName returns the database table name for the BlacklistSettings model.
@@ -328,6 +378,72 @@ func (BlacklistSettings) TableName() string {
 	return "blacklists"
 }
 
+// BlacklistChatSettings represents the blacklist action used for triggers without one of their own
+type BlacklistChatSettings struct {
+	ID             uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId         int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	Action         string    `gorm:"column:action;not null;default:warn" json:"action"`
+	ActionDuration int64     `gorm:"column:action_duration;not null;default:0" json:"action_duration"`
+	Reason         string    `gorm:"column:reason" json:"reason,omitempty"`
+	Normalization  string    `gorm:"column:normalization;not null;default:off" json:"normalization"`
+	CreatedAt      time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt      time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the BlacklistChatSettings model.
+// This method overrides GORM's default table naming convention.
+func (BlacklistChatSettings) TableName() string {
+	return "blacklist_chat_settings"
+}
+
+// BlacklistPack represents a named blacklist published from a chat for other chats to subscribe to
+type BlacklistPack struct {
+	ID          int64     `gorm:"primaryKey;autoIncrement" json:"id,omitempty"`
+	Name        string    `gorm:"column:name;uniqueIndex;not null" json:"name"`
+	OwnerChatId int64     `gorm:"column:owner_chat_id;not null" json:"owner_chat_id,omitempty"`
+	Version     int       `gorm:"column:version;not null;default:1" json:"version"`
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the BlacklistPack model.
+// This method overrides GORM's default table naming convention.
+func (BlacklistPack) TableName() string {
+	return "blacklist_packs"
+}
+
+// BlacklistPackTrigger represents a trigger of a blacklist pack
+// An empty Action or Reason means the default of the subscribed chat is used.
+type BlacklistPackTrigger struct {
+	ID             int64  `gorm:"primaryKey;autoIncrement" json:"-"`
+	PackId         int64  `gorm:"column:pack_id;not null;index" json:"pack_id,omitempty"`
+	Word           string `gorm:"column:word;not null" json:"word"`
+	MatchMode      string `gorm:"column:match_mode;not null;default:word" json:"match_mode"`
+	Action         string `gorm:"column:action;default:null" json:"action,omitempty"`
+	ActionDuration int64  `gorm:"column:action_duration;not null;default:0" json:"action_duration,omitempty"`
+	Reason         string `gorm:"column:reason;default:null" json:"reason,omitempty"`
+}
+
+// TableName returns the database table name for the BlacklistPackTrigger model.
+// This method overrides GORM's default table naming convention.
+func (BlacklistPackTrigger) TableName() string {
+	return "blacklist_pack_triggers"
+}
+
+// BlacklistPackSubscription represents a blacklist pack enforced in a chat
+type BlacklistPackSubscription struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId    int64     `gorm:"column:chat_id;not null;uniqueIndex:uq_blacklist_pack_subscriptions" json:"chat_id"`
+	PackId    int64     `gorm:"column:pack_id;not null;uniqueIndex:uq_blacklist_pack_subscriptions" json:"pack_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+}
+
+// TableName returns the database table name for the BlacklistPackSubscription model.
+// This method overrides GORM's default table naming convention.
+func (BlacklistPackSubscription) TableName() string {
+	return "blacklist_pack_subscriptions"
+}
+
 // PinSettings represents pin settings for a chat
 type PinSettings struct {
 	ID             uint      `gorm:"primaryKey;autoIncrement" json:"-"`
@@ -417,7 +533,12 @@ type AntifloodSettings struct {
 	Limit                  int       `gorm:"column:flood_limit;default:5" json:"limit,omitempty"`
 	Action                 string    `gorm:"column:action;default:'mute'" json:"action,omitempty"`
 	Mode                   string    `gorm:"column:mode;default:'mute'" json:"mode,omitempty"` // Alias for Action for compatibility
+	ActionDuration         int64     `gorm:"column:action_duration;default:0" json:"action_duration,omitempty"`
 	DeleteAntifloodMessage bool      `gorm:"column:delete_antiflood_message;default:false" json:"delete_antiflood_message,omitempty"`
+	TimerLimit             int       `gorm:"column:timer_limit;default:0" json:"timer_limit,omitempty"`
+	TimerSeconds           int       `gorm:"column:timer_seconds;default:0" json:"timer_seconds,omitempty"`
+	TimerAction            string    `gorm:"column:timer_action;default:'mute'" json:"timer_action,omitempty"`
+	TimerActionDuration    int64     `gorm:"column:timer_action_duration;default:0" json:"timer_action_duration,omitempty"`
 	CreatedAt              time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
 	UpdatedAt              time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
 }
@@ -511,12 +632,16 @@ func (RulesSettings) TableName() string {
 
 // LockSettings represents lock settings for a chat
 type LockSettings struct {
-	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
-	ChatId    int64     `gorm:"column:chat_id;not null;index:idx_lock_chat_type" json:"chat_id,omitempty"`
-	LockType  string    `gorm:"column:lock_type;not null;index:idx_lock_chat_type" json:"lock_type,omitempty"`
-	Locked    bool      `gorm:"column:locked;default:false" json:"locked,omitempty"`
-	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
-	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId         int64      `gorm:"column:chat_id;not null;index:idx_lock_chat_type" json:"chat_id,omitempty"`
+	LockType       string     `gorm:"column:lock_type;not null;index:idx_lock_chat_type" json:"lock_type,omitempty"`
+	Locked         bool       `gorm:"column:locked;default:false" json:"locked,omitempty"`
+	ExpiresAt      *time.Time `gorm:"column:expires_at" json:"expires_at,omitempty"`
+	Action         string     `gorm:"column:action;default:delete" json:"action,omitempty"`
+	ActionDuration int64      `gorm:"column:action_duration;default:0" json:"action_duration,omitempty"`
+	Threshold      int        `gorm:"column:threshold;default:0" json:"threshold,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at" json:"updated_at,omitempty"`
 }
 
 // TableName returns the database table name for the LockSettings model.
@@ -629,6 +754,345 @@ func (StoredMessages) TableName() string {
 	return "stored_messages"
 }
 
+// Federation represents a group of chats sharing a single ban list
+type Federation struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	FedId     string    `gorm:"column:fed_id;uniqueIndex;not null" json:"fed_id,omitempty"`
+	FedName   string    `gorm:"column:fed_name;not null" json:"fed_name,omitempty"`
+	OwnerId   int64     `gorm:"column:owner_id;not null;index" json:"owner_id,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the Federation model.
+// This method overrides GORM's default table naming convention.
+func (Federation) TableName() string {
+	return "federations"
+}
+
+// FedChat represents the membership of a chat in a federation
+type FedChat struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	FedId     string    `gorm:"column:fed_id;not null;index" json:"fed_id,omitempty"`
+	ChatId    int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+}
+
+// TableName returns the database table name for the FedChat model.
+// This method overrides GORM's default table naming convention.
+func (FedChat) TableName() string {
+	return "fed_chats"
+}
+
+// FedAdmin represents a user allowed to manage bans of a federation
+type FedAdmin struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	FedId     string    `gorm:"column:fed_id;not null" json:"fed_id,omitempty"`
+	UserId    int64     `gorm:"column:user_id;not null;index" json:"user_id,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+}
+
+// TableName returns the database table name for the FedAdmin model.
+// This method overrides GORM's default table naming convention.
+func (FedAdmin) TableName() string {
+	return "fed_admins"
+}
+
+// FedBan represents a user banned in every chat of a federation
+type FedBan struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	FedId     string    `gorm:"column:fed_id;not null" json:"fed_id,omitempty"`
+	UserId    int64     `gorm:"column:user_id;not null;index" json:"user_id,omitempty"`
+	Reason    string    `gorm:"column:reason" json:"reason,omitempty"`
+	BannedBy  int64     `gorm:"column:banned_by;not null" json:"banned_by,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the FedBan model.
+// This method overrides GORM's default table naming convention.
+func (FedBan) TableName() string {
+	return "fed_bans"
+}
+
+// Gban represents a user banned by the bot team in every chat
+type Gban struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	UserId    int64     `gorm:"column:user_id;uniqueIndex;not null" json:"user_id,omitempty"`
+	Reason    string    `gorm:"column:reason;not null" json:"reason,omitempty"`
+	BannedBy  int64     `gorm:"column:banned_by;not null" json:"banned_by,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the Gban model.
+// This method overrides GORM's default table naming convention.
+func (Gban) TableName() string {
+	return "gbans"
+}
+
+// GbanSettings represents whether global bans are enforced in a chat
+type GbanSettings struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId    int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	Enabled   bool      `gorm:"column:enabled;default:true" json:"enabled,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the GbanSettings model.
+// This method overrides GORM's default table naming convention.
+func (GbanSettings) TableName() string {
+	return "gban_settings"
+}
+
+// ModAction represents a moderation action recorded in the audit log of a chat
+type ModAction struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId      int64     `gorm:"column:chat_id;not null;index:idx_mod_actions_chat_created" json:"chat_id,omitempty"`
+	ActorId     int64     `gorm:"column:actor_id;not null" json:"actor_id,omitempty"`
+	TargetId    int64     `gorm:"column:target_id;default:0" json:"target_id,omitempty"`
+	Action      string    `gorm:"column:action;not null" json:"action,omitempty"`
+	Reason      string    `gorm:"column:reason" json:"reason,omitempty"`
+	Duration    int64     `gorm:"column:duration;default:0" json:"duration,omitempty"`
+	MessageLink string    `gorm:"column:message_link" json:"message_link,omitempty"`
+	CreatedAt   time.Time `gorm:"column:created_at;index:idx_mod_actions_chat_created" json:"created_at,omitempty"`
+}
+
+// TableName returns the database table name for the ModAction model.
+// This method overrides GORM's default table naming convention.
+func (ModAction) TableName() string {
+	return "mod_actions"
+}
+
+// LogChannelSettings represents the log channel of a chat and which events are posted there
+type LogChannelSettings struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId        int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	ChannelId     int64     `gorm:"column:channel_id;not null" json:"channel_id,omitempty"`
+	LogModeration bool      `gorm:"column:log_moderation;default:true" json:"log_moderation"`
+	LogSettings   bool      `gorm:"column:log_settings;default:true" json:"log_settings"`
+	LogCaptcha    bool      `gorm:"column:log_captcha;default:true" json:"log_captcha"`
+	LogReports    bool      `gorm:"column:log_reports;default:true" json:"log_reports"`
+	LogJoins      bool      `gorm:"column:log_joins;default:true" json:"log_joins"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt     time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the LogChannelSettings model.
+// This method overrides GORM's default table naming convention.
+func (LogChannelSettings) TableName() string {
+	return "log_channels"
+}
+
+// IsLogCategoryEnabled checks whether a category of events is posted to the log channel.
+func (s *LogChannelSettings) IsLogCategoryEnabled(category string) bool {
+	switch category {
+	case LogCategoryModeration:
+		return s.LogModeration
+	case LogCategorySettings:
+		return s.LogSettings
+	case LogCategoryCaptcha:
+		return s.LogCaptcha
+	case LogCategoryReports:
+		return s.LogReports
+	case LogCategoryJoins:
+		return s.LogJoins
+	}
+	return false
+}
+
+// ScheduledJob represents a delayed job that is persisted so it survives restarts
+type ScheduledJob struct {
+	ID          uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	JobType     string     `gorm:"column:job_type;not null" json:"job_type"`
+	JobKey      string     `gorm:"column:job_key" json:"job_key,omitempty"`
+	Payload     string     `gorm:"column:payload;type:jsonb;default:'{}'" json:"payload"`
+	RunAt       time.Time  `gorm:"column:run_at;not null" json:"run_at"`
+	Status      string     `gorm:"column:status;not null;default:pending" json:"status"`
+	Attempts    int        `gorm:"column:attempts;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"column:max_attempts;default:5" json:"max_attempts"`
+	LastError   string     `gorm:"column:last_error" json:"last_error,omitempty"`
+	LockedUntil *time.Time `gorm:"column:locked_until" json:"locked_until,omitempty"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the ScheduledJob model.
+// This method overrides GORM's default table naming convention.
+func (ScheduledJob) TableName() string {
+	return "scheduled_jobs"
+}
+
+// ScheduledMessage represents a message posted to a chat at a set time or on a recurring schedule
+type ScheduledMessage struct {
+	ID              uint        `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId          int64       `gorm:"column:chat_id;not null;index" json:"chat_id,omitempty"`
+	CreatedBy       int64       `gorm:"column:created_by;not null" json:"created_by,omitempty"`
+	NoteContent     string      `gorm:"column:note_content;type:text" json:"note_content,omitempty"`
+	FileID          string      `gorm:"column:file_id" json:"file_id,omitempty"`
+	MsgType         int         `gorm:"column:msg_type;default:1" json:"msg_type,omitempty"`
+	Buttons         ButtonArray `gorm:"column:buttons;type:jsonb" json:"buttons,omitempty"`
+	WebPreview      bool        `gorm:"column:web_preview;default:false" json:"web_preview,omitempty"`
+	IsProtected     bool        `gorm:"column:is_protected;default:false" json:"is_protected,omitempty"`
+	NoNotif         bool        `gorm:"column:no_notif;default:false" json:"no_notif,omitempty"`
+	ScheduleType    string      `gorm:"column:schedule_type;not null" json:"schedule_type"`
+	Spec            string      `gorm:"column:spec;not null" json:"spec"`
+	CronExpr        string      `gorm:"column:cron_expr" json:"cron_expr,omitempty"`
+	IntervalSeconds int64       `gorm:"column:interval_seconds;default:0" json:"interval_seconds,omitempty"`
+	NextRunAt       time.Time   `gorm:"column:next_run_at;not null" json:"next_run_at"`
+	AutoPin         bool        `gorm:"column:auto_pin;default:false" json:"auto_pin,omitempty"`
+	DeletePrevious  bool        `gorm:"column:delete_previous;default:false" json:"delete_previous,omitempty"`
+	LastMessageId   int64       `gorm:"column:last_message_id;default:0" json:"last_message_id,omitempty"`
+	CreatedAt       time.Time   `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt       time.Time   `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the ScheduledMessage model.
+// This method overrides GORM's default table naming convention.
+func (ScheduledMessage) TableName() string {
+	return "scheduled_messages"
+}
+
+// AsNote returns the content of the scheduled message as a note, so it can be sent like one.
+func (s *ScheduledMessage) AsNote() *Notes {
+	return &Notes{
+		ChatId:      s.ChatId,
+		NoteContent: s.NoteContent,
+		FileID:      s.FileID,
+		MsgType:     s.MsgType,
+		Buttons:     s.Buttons,
+		WebPreview:  s.WebPreview,
+		IsProtected: s.IsProtected,
+		NoNotif:     s.NoNotif,
+	}
+}
+
+// ApprovedUser represents a member exempt from automated moderation in a chat
+type ApprovedUser struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId     int64     `gorm:"column:chat_id;not null;uniqueIndex:uq_approved_users_chat_user" json:"chat_id,omitempty"`
+	UserId     int64     `gorm:"column:user_id;not null;uniqueIndex:uq_approved_users_chat_user" json:"user_id,omitempty"`
+	ApprovedBy int64     `gorm:"column:approved_by;not null" json:"approved_by,omitempty"`
+	CreatedAt  time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+}
+
+// TableName returns the database table name for the ApprovedUser model.
+// This method overrides GORM's default table naming convention.
+func (ApprovedUser) TableName() string {
+	return "approved_users"
+}
+
+// AppealSettings represents whether banned users can appeal their ban in a chat
+type AppealSettings struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId    int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	Enabled   bool      `gorm:"column:enabled;default:false" json:"enabled"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the AppealSettings model.
+// This method overrides GORM's default table naming convention.
+func (AppealSettings) TableName() string {
+	return "appeal_settings"
+}
+
+// BanAppeal represents an appeal sent by a banned user and its review status
+type BanAppeal struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId     int64      `gorm:"column:chat_id;not null" json:"chat_id,omitempty"`
+	UserId     int64      `gorm:"column:user_id;not null" json:"user_id,omitempty"`
+	Statement  string     `gorm:"column:statement;type:text;not null" json:"statement"`
+	Status     string     `gorm:"column:status;not null;default:pending" json:"status"`
+	ReviewedBy int64      `gorm:"column:reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `gorm:"column:reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt  time.Time  `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the BanAppeal model.
+// This method overrides GORM's default table naming convention.
+func (BanAppeal) TableName() string {
+	return "ban_appeals"
+}
+
+// NightModeSettings represents the daily window during which a chat is restricted
+type NightModeSettings struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId           int64     `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	Enabled          bool      `gorm:"column:enabled;default:false" json:"enabled"`
+	StartMinute      int       `gorm:"column:start_minute;not null;default:1380" json:"start_minute"`
+	EndMinute        int       `gorm:"column:end_minute;not null;default:420" json:"end_minute"`
+	Timezone         string    `gorm:"column:timezone;not null;default:UTC" json:"timezone"`
+	Preset           string    `gorm:"column:preset;not null;default:readonly" json:"preset"`
+	StartMessage     string    `gorm:"column:start_message" json:"start_message,omitempty"`
+	EndMessage       string    `gorm:"column:end_message" json:"end_message,omitempty"`
+	Active           bool      `gorm:"column:active;default:false" json:"active"`
+	SavedPermissions string    `gorm:"column:saved_permissions" json:"saved_permissions,omitempty"`
+	CreatedAt        time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt        time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the NightModeSettings model.
+// This method overrides GORM's default table naming convention.
+func (NightModeSettings) TableName() string {
+	return "night_mode"
+}
+
+// LockDomain represents a domain on the allowlist or blocklist of a chat
+type LockDomain struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId    int64     `gorm:"column:chat_id;not null;uniqueIndex:uq_lock_domains_chat_domain" json:"chat_id,omitempty"`
+	Domain    string    `gorm:"column:domain;not null;uniqueIndex:uq_lock_domains_chat_domain" json:"domain,omitempty"`
+	ListType  string    `gorm:"column:list_type;not null" json:"list_type,omitempty"`
+	AddedBy   int64     `gorm:"column:added_by;not null" json:"added_by,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+}
+
+// TableName returns the database table name for the LockDomain model.
+// This method overrides GORM's default table naming convention.
+func (LockDomain) TableName() string {
+	return "lock_domains"
+}
+
+// AntispamSettings represents what happens when a chat exceeds its antispam levels
+type AntispamSettings struct {
+	ID               uint       `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId           int64      `gorm:"column:chat_id;uniqueIndex;not null" json:"chat_id,omitempty"`
+	Action           string     `gorm:"column:action;not null;default:drop" json:"action"`
+	ActionDuration   int64      `gorm:"column:action_duration;not null;default:300" json:"action_duration"`
+	ActiveUntil      *time.Time `gorm:"column:active_until" json:"active_until,omitempty"`
+	SavedPermissions string     `gorm:"column:saved_permissions" json:"saved_permissions,omitempty"`
+	Trips            int64      `gorm:"column:trips;not null;default:0" json:"trips"`
+	LastTrippedAt    *time.Time `gorm:"column:last_tripped_at" json:"last_tripped_at,omitempty"`
+	CreatedAt        time.Time  `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt        time.Time  `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the AntispamSettings model.
+// This method overrides GORM's default table naming convention.
+func (AntispamSettings) TableName() string {
+	return "antispam_settings"
+}
+
+// AntispamLevel represents the most messages a chat or each of its users may send within a window
+type AntispamLevel struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement" json:"-"`
+	ChatId       int64     `gorm:"column:chat_id;not null;uniqueIndex:uk_antispam_levels_chat_scope_seconds" json:"chat_id,omitempty"`
+	Scope        string    `gorm:"column:scope;not null;uniqueIndex:uk_antispam_levels_chat_scope_seconds" json:"scope"`
+	Seconds      int       `gorm:"column:seconds;not null;uniqueIndex:uk_antispam_levels_chat_scope_seconds" json:"seconds"`
+	MessageLimit int       `gorm:"column:message_limit;not null" json:"message_limit"`
+	CreatedAt    time.Time `gorm:"column:created_at" json:"created_at,omitempty"`
+	UpdatedAt    time.Time `gorm:"column:updated_at" json:"updated_at,omitempty"`
+}
+
+// TableName returns the database table name for the AntispamLevel model.
+// This method overrides GORM's default table naming convention.
+func (AntispamLevel) TableName() string {
+	return "antispam_levels"
+}
+
 // Database instance
 var DB *gorm.DB
 
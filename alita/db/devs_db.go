@@ -28,12 +28,12 @@ func GetTeamMemInfo(userID int64) (devrc *DevSettings) {
 }
 
 // GetTeamMembers returns a map of all team members with their roles.
-// Currently only supports 'dev' role and returns nil on error.
+// Users with both flags are reported as 'dev'. Returns nil on error.
 func GetTeamMembers() map[int64]string {
 	var teamArray []*DevSettings
 	array := make(map[int64]string)
 
-	err := GetRecords(&teamArray, DevSettings{IsDev: true})
+	err := DB.Where("is_dev = ? OR sudo = ?", true, true).Find(&teamArray).Error
 	if err != nil {
 		log.Error(err)
 		return nil
@@ -42,6 +42,8 @@ func GetTeamMembers() map[int64]string {
 	for _, result := range teamArray {
 		if result.IsDev {
 			array[result.UserId] = "dev"
+		} else if result.Sudo {
+			array[result.UserId] = "sudo"
 		}
 	}
 
@@ -75,6 +77,27 @@ func RemDev(userID int64) {
 	}
 }
 
+// AddSudo grants sudo privileges to a user.
+// Creates a new record if the user doesn't exist in DevSettings.
+func AddSudo(userID int64) {
+	err := DB.Where("user_id = ?", userID).
+		Assign(map[string]any{"sudo": true}).
+		FirstOrCreate(&DevSettings{UserId: userID}).Error
+	if err != nil {
+		log.Errorf("[Database] AddSudo: %v - %d", err, userID)
+		return
+	}
+	log.Infof("[Database] AddSudo: %d", userID)
+}
+
+// RemSudo revokes sudo privileges from a user, keeping any developer role intact.
+func RemSudo(userID int64) {
+	err := DB.Model(&DevSettings{}).Where("user_id = ?", userID).Update("sudo", false).Error
+	if err != nil {
+		log.Errorf("[Database] RemSudo: %v - %d", err, userID)
+	}
+}
+
 // LoadAllStats generates a comprehensive statistics report for the bot.
 // Includes user counts, chat statistics, feature usage, activity metrics, and system information.
 func LoadAllStats() string {
@@ -93,6 +116,7 @@ func LoadAllStats() string {
 	enabledWelcome, enabledGoodbye, cleanServiceEnabled, cleanWelcomeEnabled, cleanGoodbyeEnabled := LoadGreetingsStats()
 	notesNum, notesChats := LoadNotesStats()
 	numChannels := LoadChannelStats()
+	gbannedUsers := LoadGbanStats()
 
 	// Get webhook status information
 	var deploymentMode, webhookInfo string
@@ -168,7 +192,8 @@ func LoadAllStats() string {
 			humanize.Comma(notesNum),
 			humanize.Comma(notesChats),
 		) +
-		fmt.Sprintf("\n<b>Channels Stored</b>: %s", humanize.Comma(numChannels))
+		fmt.Sprintf("\n<b>Channels Stored</b>: %s", humanize.Comma(numChannels)) +
+		fmt.Sprintf("\n<b>Gbanned Users</b>: %s", humanize.Comma(gbannedUsers))
 
 	return result
 }
@@ -0,0 +1,253 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Federation errors
+var (
+	ErrFedAlreadyOwned  = errors.New("FED_ALREADY_OWNED")
+	ErrFedNotFound      = errors.New("FED_NOT_FOUND")
+	ErrChatAlreadyInFed = errors.New("CHAT_ALREADY_IN_FED")
+)
+
+// CreateFederation creates a new federation owned by the given user.
+// A user can only own a single federation; ErrFedAlreadyOwned is returned otherwise.
+func CreateFederation(ownerId int64, fedName string) (*Federation, error) {
+	if GetFederationByOwner(ownerId) != nil {
+		return nil, ErrFedAlreadyOwned
+	}
+
+	fed := &Federation{
+		FedId:   uuid.NewString(),
+		FedName: fedName,
+		OwnerId: ownerId,
+	}
+	if err := CreateRecord(fed); err != nil {
+		log.Errorf("[Database] CreateFederation: %v - %d", err, ownerId)
+		return nil, err
+	}
+	return fed, nil
+}
+
+// GetFederation retrieves a federation by its ID.
+// Returns nil if the federation does not exist or on error.
+func GetFederation(fedId string) *Federation {
+	fed := &Federation{}
+	err := GetRecord(fed, Federation{FedId: fedId})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetFederation: %v - %s", err, fedId)
+		}
+		return nil
+	}
+	return fed
+}
+
+// GetFederationByOwner retrieves the federation owned by a user.
+// Returns nil if the user does not own a federation.
+func GetFederationByOwner(ownerId int64) *Federation {
+	fed := &Federation{}
+	err := GetRecord(fed, Federation{OwnerId: ownerId})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetFederationByOwner: %v - %d", err, ownerId)
+		}
+		return nil
+	}
+	return fed
+}
+
+// getChatFedId returns the ID of the federation a chat belongs to, with caching support.
+// Returns an empty string if the chat is not part of any federation.
+func getChatFedId(chatId int64) string {
+	fedId, err := getFromCacheOrLoad(fedChatCacheKey(chatId), CacheTTLFedChat, func() (string, error) {
+		fedChat := &FedChat{}
+		err := GetRecord(fedChat, FedChat{ChatId: chatId})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		} else if err != nil {
+			log.Errorf("[Database] getChatFedId: %v - %d", err, chatId)
+			return "", err
+		}
+		return fedChat.FedId, nil
+	})
+	if err != nil {
+		return ""
+	}
+	return fedId
+}
+
+// GetChatFederation retrieves the federation a chat is part of.
+// Returns nil if the chat has not joined a federation.
+func GetChatFederation(chatId int64) *Federation {
+	fedId := getChatFedId(chatId)
+	if fedId == "" {
+		return nil
+	}
+	return GetFederation(fedId)
+}
+
+// JoinFederation adds a chat to a federation.
+// Returns ErrChatAlreadyInFed if the chat is already part of a federation.
+func JoinFederation(chatId int64, fedId string) error {
+	if GetFederation(fedId) == nil {
+		return ErrFedNotFound
+	}
+	if getChatFedId(chatId) != "" {
+		return ErrChatAlreadyInFed
+	}
+
+	err := CreateRecord(&FedChat{FedId: fedId, ChatId: chatId})
+	if err != nil {
+		log.Errorf("[Database] JoinFederation: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(fedChatCacheKey(chatId))
+	return nil
+}
+
+// LeaveFederation removes a chat from the federation it is part of.
+func LeaveFederation(chatId int64) error {
+	err := DB.Where("chat_id = ?", chatId).Delete(&FedChat{}).Error
+	if err != nil {
+		log.Errorf("[Database] LeaveFederation: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(fedChatCacheKey(chatId))
+	return nil
+}
+
+// GetFedChats returns the IDs of all chats that are part of a federation.
+func GetFedChats(fedId string) (chatIds []int64) {
+	err := DB.Model(&FedChat{}).Where("fed_id = ?", fedId).Pluck("chat_id", &chatIds).Error
+	if err != nil {
+		log.Errorf("[Database] GetFedChats: %v - %s", err, fedId)
+		return nil
+	}
+	return
+}
+
+// IsFedAdmin checks whether a user can manage a federation.
+// The federation owner is always considered an admin.
+func IsFedAdmin(fed *Federation, userId int64) bool {
+	if fed.OwnerId == userId {
+		return true
+	}
+
+	var count int64
+	err := DB.Model(&FedAdmin{}).Where("fed_id = ? AND user_id = ?", fed.FedId, userId).Count(&count).Error
+	if err != nil {
+		log.Errorf("[Database] IsFedAdmin: %v - %d", err, userId)
+		return false
+	}
+	return count > 0
+}
+
+// AddFedAdmin promotes a user to admin of a federation.
+// Does nothing if the user is already an admin.
+func AddFedAdmin(fedId string, userId int64) error {
+	err := DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&FedAdmin{FedId: fedId, UserId: userId}).Error
+	if err != nil {
+		log.Errorf("[Database] AddFedAdmin: %v - %d", err, userId)
+		return err
+	}
+	return nil
+}
+
+// RemoveFedAdmin demotes a federation admin.
+// Returns true if the user was an admin and has been removed.
+func RemoveFedAdmin(fedId string, userId int64) bool {
+	result := DB.Where("fed_id = ? AND user_id = ?", fedId, userId).Delete(&FedAdmin{})
+	if result.Error != nil {
+		log.Errorf("[Database] RemoveFedAdmin: %v - %d", result.Error, userId)
+		return false
+	}
+	return result.RowsAffected > 0
+}
+
+// GetFedAdmins returns the user IDs of all admins of a federation, excluding the owner.
+func GetFedAdmins(fedId string) (userIds []int64) {
+	err := DB.Model(&FedAdmin{}).Where("fed_id = ?", fedId).Order("created_at ASC").Pluck("user_id", &userIds).Error
+	if err != nil {
+		log.Errorf("[Database] GetFedAdmins: %v - %s", err, fedId)
+		return nil
+	}
+	return
+}
+
+// FedBanUser adds a user to the ban list of a federation, updating the reason if already banned.
+// Returns true if the user was already fbanned.
+func FedBanUser(fedId string, userId, bannedBy int64, reason string) (alreadyBanned bool, err error) {
+	alreadyBanned = GetFedBan(fedId, userId) != nil
+
+	err = DB.Where("fed_id = ? AND user_id = ?", fedId, userId).
+		Assign(map[string]any{"reason": reason, "banned_by": bannedBy}).
+		FirstOrCreate(&FedBan{FedId: fedId, UserId: userId}).Error
+	if err != nil {
+		log.Errorf("[Database] FedBanUser: %v - %d", err, userId)
+	}
+	return
+}
+
+// FedUnbanUser removes a user from the ban list of a federation.
+// Returns true if the user was fbanned and has been removed.
+func FedUnbanUser(fedId string, userId int64) bool {
+	result := DB.Where("fed_id = ? AND user_id = ?", fedId, userId).Delete(&FedBan{})
+	if result.Error != nil {
+		log.Errorf("[Database] FedUnbanUser: %v - %d", result.Error, userId)
+		return false
+	}
+	return result.RowsAffected > 0
+}
+
+// GetFedBan retrieves the fban record of a user in a federation.
+// Returns nil if the user is not banned in the federation.
+func GetFedBan(fedId string, userId int64) *FedBan {
+	fban := &FedBan{}
+	err := GetRecord(fban, FedBan{FedId: fedId, UserId: userId})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetFedBan: %v - %d", err, userId)
+		}
+		return nil
+	}
+	return fban
+}
+
+// GetChatFedBan checks whether a user is banned in the federation a chat belongs to.
+// Returns the federation and fban record, or nil values if the chat is not in a federation
+// or the user is not fbanned.
+func GetChatFedBan(chatId, userId int64) (*Federation, *FedBan) {
+	fedId := getChatFedId(chatId)
+	if fedId == "" {
+		return nil, nil
+	}
+
+	fban := GetFedBan(fedId, userId)
+	if fban == nil {
+		return nil, nil
+	}
+	return GetFederation(fedId), fban
+}
+
+// GetFedStats returns the number of chats and banned users in a federation.
+func GetFedStats(fedId string) (chats, bans int64) {
+	err := DB.Model(&FedChat{}).Where("fed_id = ?", fedId).Count(&chats).Error
+	if err != nil {
+		log.Errorf("[Database] GetFedStats (chats): %v - %s", err, fedId)
+	}
+
+	err = DB.Model(&FedBan{}).Where("fed_id = ?", fedId).Count(&bans).Error
+	if err != nil {
+		log.Errorf("[Database] GetFedStats (bans): %v - %s", err, fedId)
+	}
+	return
+}
@@ -5,39 +5,41 @@ import (
 	"gorm.io/gorm"
 )
 
+// FilterTrigger is a trigger of a filter along with the way it is matched.
+type FilterTrigger struct {
+	KeyWord   string
+	MatchMode string
+}
+
+// GetFilters retrieves all filter triggers of a chat, using the cache shared with the filters watcher.
+// Returns an empty slice if no filters are found or an error occurs.
+func GetFilters(chatID int64) []*ChatFilters {
+	filters, err := GetOptimizedQueries().GetChatFiltersCached(chatID)
+	if err != nil {
+		log.Errorf("[Database] GetFilters: %v - %d", err, chatID)
+		return []*ChatFilters{}
+	}
+	return filters
+}
+
 // GetFiltersList retrieves a list of all filter keywords for a specific chat ID.
 // Uses caching to improve performance for frequently accessed data.
 // Returns an empty slice if no filters are found or an error occurs.
 func GetFiltersList(chatID int64) (allFilterWords []string) {
-	// Try to get from cache first
-	cacheKey := filterListCacheKey(chatID)
-	result, err := getFromCacheOrLoad(cacheKey, CacheTTLFilterList, func() ([]string, error) {
-		var results []*ChatFilters
-		var filterWords []string
-		err := GetRecords(&results, map[string]any{"chat_id": chatID})
-		if err != nil {
-			log.Errorf("[Database] GetFiltersList: %v - %d", err, chatID)
-			return []string{}, err
-		}
-
-		for _, j := range results {
-			filterWords = append(filterWords, j.KeyWord)
-		}
-		return filterWords, nil
-	})
-	if err != nil {
-		return []string{}
+	allFilterWords = []string{}
+	for _, filter := range GetFilters(chatID) {
+		allFilterWords = append(allFilterWords, filter.KeyWord)
 	}
-	return result
+	return
 }
 
-// DoesFilterExists checks whether a filter with the given keyword exists in the specified chat.
+// DoesFilterExists checks whether a filter with the given keyword and match mode exists in the specified chat.
 // Performs a case-insensitive comparison of the keyword.
 // Returns false if the filter doesn't exist or an error occurs.
 // Uses LIMIT 1 optimization for better performance than COUNT.
-func DoesFilterExists(chatId int64, keyword string) bool {
+func DoesFilterExists(chatId int64, keyword, matchMode string) bool {
 	var filter ChatFilters
-	err := DB.Where("chat_id = ? AND LOWER(keyword) = LOWER(?)", chatId, keyword).Take(&filter).Error
+	err := DB.Where("chat_id = ? AND LOWER(keyword) = LOWER(?) AND match_mode = ?", chatId, keyword, matchMode).Take(&filter).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return false
@@ -48,48 +50,67 @@ func DoesFilterExists(chatId int64, keyword string) bool {
 	return true
 }
 
-// AddFilter creates a new filter in the database for the specified chat.
-// Does nothing if a filter with the same keyword already exists.
-// Invalidates the filter list cache after successful addition.
-func AddFilter(chatID int64, keyWord, replyText, fileID string, buttons []Button, filtType int) {
-	// Check if filter already exists using optimized query
-	var existingFilter ChatFilters
-	err := DB.Where("chat_id = ? AND keyword = ?", chatID, keyWord).Take(&existingFilter).Error
-	if err != nil {
-		if err != gorm.ErrRecordNotFound {
-			log.Errorf("[Database][AddFilter] checking existence: %d - %v", chatID, err)
-			return
+// AddFilter creates a filter replying to several triggers in the database for the specified chat.
+// The triggers are added in a single transaction and grouped under the id of the first one,
+// replacing the filters that already exist for any of them.
+// Invalidates the filter list cache after successful addition and returns the error otherwise.
+func AddFilter(chatID int64, triggers []FilterTrigger, replyText, fileID string, buttons []Button, filtType int) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var groupId int64
+		added := make(map[FilterTrigger]bool, len(triggers))
+		for _, trigger := range triggers {
+			if added[trigger] {
+				continue // Trigger given twice
+			}
+			added[trigger] = true
+
+			// replace the filter if the trigger already has one
+			err := tx.Where("chat_id = ? AND keyword = ? AND match_mode = ?", chatID, trigger.KeyWord, trigger.MatchMode).Delete(&ChatFilters{}).Error
+			if err != nil {
+				return err
+			}
+
+			// add the filter
+			newFilter := ChatFilters{
+				ChatId:      chatID,
+				KeyWord:     trigger.KeyWord,
+				MatchMode:   trigger.MatchMode,
+				GroupId:     groupId,
+				FilterReply: replyText,
+				MsgType:     filtType,
+				FileID:      fileID,
+				Buttons:     ButtonArray(buttons),
+			}
+			if err := tx.Create(&newFilter).Error; err != nil {
+				return err
+			}
+
+			// the first trigger of the filter gives its id to the group
+			if groupId == 0 {
+				groupId = int64(newFilter.ID)
+				if err := tx.Model(&newFilter).Update("group_id", groupId).Error; err != nil {
+					return err
+				}
+			}
 		}
-		// Filter doesn't exist, continue with creation
-	} else {
-		return // Filter already exists
-	}
-
-	// add the filter
-	newFilter := ChatFilters{
-		ChatId:      chatID,
-		KeyWord:     keyWord,
-		FilterReply: replyText,
-		MsgType:     filtType,
-		FileID:      fileID,
-		Buttons:     ButtonArray(buttons),
-	}
-
-	err = CreateRecord(&newFilter)
+		return nil
+	})
 	if err != nil {
 		log.Errorf("[Database][AddFilter]: %d - %v", chatID, err)
-		return
+		return err
 	}
 
 	// Invalidate cache after adding filter
 	deleteCache(filterListCacheKey(chatID))
+	return nil
 }
 
-// RemoveFilter deletes a filter with the specified keyword from the chat.
+// RemoveFilter deletes a filter trigger with the specified keyword and match mode from the chat.
+// The other triggers of its group keep replying.
 // Invalidates the filter list cache if a filter was successfully removed.
-func RemoveFilter(chatID int64, keyWord string) {
+func RemoveFilter(chatID int64, keyWord, matchMode string) {
 	// Directly attempt to delete the filter without checking existence first
-	result := DB.Where("chat_id = ? AND keyword = ?", chatID, keyWord).Delete(&ChatFilters{})
+	result := DB.Where("chat_id = ? AND keyword = ? AND match_mode = ?", chatID, keyWord, matchMode).Delete(&ChatFilters{})
 	if result.Error != nil {
 		log.Errorf("[Database][RemoveFilter]: %d - %v", chatID, result.Error)
 		return
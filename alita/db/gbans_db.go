@@ -0,0 +1,103 @@
+package db
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// GbanUser adds a user to the global ban list, updating the reason if already gbanned.
+// Returns true if the user was already gbanned.
+func GbanUser(userId, bannedBy int64, reason string) (alreadyBanned bool, err error) {
+	alreadyBanned = GetGban(userId) != nil
+
+	err = DB.Where("user_id = ?", userId).
+		Assign(map[string]any{"reason": reason, "banned_by": bannedBy}).
+		FirstOrCreate(&Gban{UserId: userId}).Error
+	if err != nil {
+		log.Errorf("[Database] GbanUser: %v - %d", err, userId)
+		return
+	}
+
+	deleteCache(gbanCacheKey(userId))
+	return
+}
+
+// UngbanUser removes a user from the global ban list.
+// Returns true if the user was gbanned and has been removed.
+func UngbanUser(userId int64) bool {
+	result := DB.Where("user_id = ?", userId).Delete(&Gban{})
+	if result.Error != nil {
+		log.Errorf("[Database] UngbanUser: %v - %d", result.Error, userId)
+		return false
+	}
+
+	deleteCache(gbanCacheKey(userId))
+	return result.RowsAffected > 0
+}
+
+// GetGban retrieves the gban record of a user with caching support.
+// Returns nil if the user is not gbanned.
+func GetGban(userId int64) *Gban {
+	gban, err := getFromCacheOrLoad(gbanCacheKey(userId), CacheTTLGban, func() (Gban, error) {
+		gban := Gban{}
+		err := GetRecord(&gban, Gban{UserId: userId})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// cache an empty record so that non-gbanned users don't hit the database
+			return Gban{}, nil
+		} else if err != nil {
+			log.Errorf("[Database] GetGban: %v - %d", err, userId)
+			return Gban{}, err
+		}
+		return gban, nil
+	})
+	if err != nil || gban.UserId == 0 {
+		return nil
+	}
+	return &gban
+}
+
+// IsGbanEnforced checks whether global bans are enforced in a chat.
+// Enforcement is enabled by default for chats without custom settings.
+func IsGbanEnforced(chatId int64) bool {
+	enabled, err := getFromCacheOrLoad(gbanSettingsCacheKey(chatId), CacheTTLGban, func() (bool, error) {
+		settings := &GbanSettings{}
+		err := GetRecord(settings, GbanSettings{ChatId: chatId})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		} else if err != nil {
+			log.Errorf("[Database] IsGbanEnforced: %v - %d", err, chatId)
+			return true, err
+		}
+		return settings.Enabled, nil
+	})
+	if err != nil {
+		return true
+	}
+	return enabled
+}
+
+// SetGbanEnforcement enables or disables global ban enforcement in a chat.
+func SetGbanEnforcement(chatId int64, enabled bool) error {
+	err := DB.Where("chat_id = ?", chatId).
+		Assign(map[string]any{"enabled": enabled}).
+		FirstOrCreate(&GbanSettings{ChatId: chatId}).Error
+	if err != nil {
+		log.Errorf("[Database] SetGbanEnforcement: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(gbanSettingsCacheKey(chatId))
+	return nil
+}
+
+// LoadGbanStats returns the total number of gbanned users.
+func LoadGbanStats() (gbannedUsers int64) {
+	err := DB.Model(&Gban{}).Count(&gbannedUsers).Error
+	if err != nil {
+		log.Errorf("[Database] LoadGbanStats: %v", err)
+		return 0
+	}
+	return
+}
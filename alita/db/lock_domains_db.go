@@ -0,0 +1,64 @@
+package db
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// Lists a domain can be on for the url lock
+const (
+	DomainListAllow = "allow"
+	DomainListBlock = "block"
+)
+
+// AddLockDomain puts a normalized domain on the allowlist or blocklist of a chat,
+// moving it over if it was on the other list.
+func AddLockDomain(chatId int64, domain, listType string, addedBy int64) error {
+	err := DB.Where("chat_id = ? AND domain = ?", chatId, domain).
+		Assign(map[string]any{"list_type": listType, "added_by": addedBy}).
+		FirstOrCreate(&LockDomain{ChatId: chatId, Domain: domain}).Error
+	if err != nil {
+		log.Errorf("[Database] AddLockDomain: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(lockDomainsCacheKey(chatId))
+	return nil
+}
+
+// RemoveLockDomain takes a domain off the allowlist or blocklist of a chat.
+// Returns true if the domain was on either list.
+func RemoveLockDomain(chatId int64, domain string) bool {
+	result := DB.Where("chat_id = ? AND domain = ?", chatId, domain).Delete(&LockDomain{})
+	if result.Error != nil {
+		log.Errorf("[Database] RemoveLockDomain: %v - %d", result.Error, chatId)
+		return false
+	}
+
+	deleteCache(lockDomainsCacheKey(chatId))
+	return result.RowsAffected > 0
+}
+
+// GetLockDomains retrieves the allowed and blocked domains of a chat with caching support.
+func GetLockDomains(chatId int64) (allowed, blocked []string) {
+	lockDomains, err := getFromCacheOrLoad(lockDomainsCacheKey(chatId), CacheTTLLockDomains, func() ([]*LockDomain, error) {
+		var lockDomains []*LockDomain
+		err := DB.Where("chat_id = ?", chatId).Order("domain ASC").Find(&lockDomains).Error
+		if err != nil {
+			log.Errorf("[Database] GetLockDomains: %v - %d", err, chatId)
+			return nil, err
+		}
+		return lockDomains, nil
+	})
+	if err != nil {
+		return nil, nil
+	}
+
+	for _, lockDomain := range lockDomains {
+		if lockDomain.ListType == DomainListBlock {
+			blocked = append(blocked, lockDomain.Domain)
+		} else {
+			allowed = append(allowed, lockDomain.Domain)
+		}
+	}
+	return allowed, blocked
+}
@@ -2,11 +2,26 @@ package db
 
 import (
 	"errors"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// Punishments applied to users who break a lock, on top of deleting their message
+const (
+	LockActionDelete = "delete"
+	LockActionWarn   = "warn"
+	LockActionMute   = "mute"
+	LockActionTMute  = "tmute"
+	LockActionKick   = "kick"
+	LockActionBan    = "ban"
+	LockActionTBan   = "tban"
+)
+
+// DefaultMaxMentions is the number of mentions a message may have before it breaks the mention lock
+const DefaultMaxMentions = 5
+
 // GetChatLocks retrieves all lock settings for a specific chat ID.
 // Uses optimized queries with caching for better performance.
 // Returns an empty map if no locks are found or an error occurs.
@@ -23,23 +38,82 @@ func GetChatLocks(chatID int64) map[string]bool {
 
 // UpdateLock modifies the value of a specific lock setting and updates it in the database.
 // Creates a new lock record if one doesn't exist for the given chat and permission type.
+// Any expiry of the lock is cleared, so the lock stays until it is changed again.
 func UpdateLock(chatID int64, perm string, val bool) {
-	lockSetting := &LockSettings{
-		ChatId:   chatID,
-		LockType: perm,
-		Locked:   val,
+	err := DB.Where("chat_id = ? AND lock_type = ?", chatID, perm).
+		Assign(map[string]any{"locked": val, "expires_at": nil}).
+		FirstOrCreate(&LockSettings{ChatId: chatID, LockType: perm}).Error
+	if err != nil {
+		log.Errorf("[Database] UpdateLock: %v", err)
+		return
 	}
+	deleteCache(lockCacheKey(chatID, perm))
+}
 
-	// Try to update existing record first
-	err := UpdateRecordWithZeroValues(&LockSettings{}, LockSettings{ChatId: chatID, LockType: perm}, LockSettings{Locked: val})
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		// Create new record if not exists
-		err = CreateRecord(lockSetting)
+// UpdateTimedLock locks a permission type in a chat until the given time.
+// Creates a new lock record if one doesn't exist for the given chat and permission type.
+func UpdateTimedLock(chatID int64, perm string, expiresAt time.Time) error {
+	err := DB.Where("chat_id = ? AND lock_type = ?", chatID, perm).
+		Assign(map[string]any{"locked": true, "expires_at": expiresAt}).
+		FirstOrCreate(&LockSettings{ChatId: chatID, LockType: perm}).Error
+	if err != nil {
+		log.Errorf("[Database] UpdateTimedLock: %v - %d", err, chatID)
+		return err
 	}
+	deleteCache(lockCacheKey(chatID, perm))
+	return nil
+}
 
+// ExpireChatLocks unlocks every timed lock of a chat whose expiry has passed.
+// Returns the lock types that were unlocked.
+func ExpireChatLocks(chatID int64) []string {
+	now := time.Now()
+	var expired []LockSettings
+	err := DB.Where("chat_id = ? AND locked = ? AND expires_at IS NOT NULL AND expires_at <= ?", chatID, true, now).
+		Find(&expired).Error
 	if err != nil {
-		log.Errorf("[Database] UpdateLock: %v", err)
+		log.Errorf("[Database] ExpireChatLocks: %v - %d", err, chatID)
+		return nil
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(expired))
+	lockTypes := make([]string, 0, len(expired))
+	for _, lock := range expired {
+		ids = append(ids, lock.ID)
+		lockTypes = append(lockTypes, lock.LockType)
+	}
+
+	// the expiry is checked again so a lock changed since the lookup is left alone
+	err = DB.Model(&LockSettings{}).
+		Where("id IN ? AND expires_at IS NOT NULL AND expires_at <= ?", ids, now).
+		Updates(map[string]any{"locked": false, "expires_at": nil}).Error
+	if err != nil {
+		log.Errorf("[Database] ExpireChatLocks: %v - %d", err, chatID)
+		return nil
+	}
+	for _, lockType := range lockTypes {
+		deleteCache(lockCacheKey(chatID, lockType))
+	}
+	return lockTypes
+}
+
+// GetChatLockExpiries retrieves the expiry of every timed lock in a chat, keyed by lock type.
+func GetChatLockExpiries(chatID int64) map[string]time.Time {
+	var locks []LockSettings
+	err := DB.Where("chat_id = ? AND locked = ? AND expires_at IS NOT NULL", chatID, true).Find(&locks).Error
+	if err != nil {
+		log.Errorf("[Database] GetChatLockExpiries: %v - %d", err, chatID)
+		return map[string]time.Time{}
 	}
+
+	expiries := make(map[string]time.Time, len(locks))
+	for _, lock := range locks {
+		expiries[lock.LockType] = *lock.ExpiresAt
+	}
+	return expiries
 }
 
 // IsPermLocked checks whether a specific permission type is locked in the given chat.
@@ -55,3 +129,79 @@ func IsPermLocked(chatID int64, perm string) bool {
 
 	return locked
 }
+
+// GetLockAction retrieves the punishment for breaking a lock in a chat, along with its duration in seconds.
+// Returns LockActionDelete if no punishment was set.
+func GetLockAction(chatID int64, perm string) (action string, duration int64) {
+	lock := &LockSettings{}
+	err := GetRecord(lock, LockSettings{ChatId: chatID, LockType: perm})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetLockAction: %v - %d", err, chatID)
+		}
+		return LockActionDelete, 0
+	}
+	if lock.Action == "" {
+		return LockActionDelete, 0
+	}
+	return lock.Action, lock.ActionDuration
+}
+
+// GetChatLockActions retrieves every lock of a chat that has a punishment other than deleting the message.
+// Returns a map of lock types to their settings.
+func GetChatLockActions(chatID int64) map[string]*LockSettings {
+	var locks []*LockSettings
+	err := DB.Where("chat_id = ? AND action <> ?", chatID, LockActionDelete).Find(&locks).Error
+	if err != nil {
+		log.Errorf("[Database] GetChatLockActions: %v - %d", err, chatID)
+		return map[string]*LockSettings{}
+	}
+
+	actions := make(map[string]*LockSettings, len(locks))
+	for _, lock := range locks {
+		actions[lock.LockType] = lock
+	}
+	return actions
+}
+
+// SetLockAction sets the punishment for breaking a lock in a chat.
+// The duration in seconds is only used by the timed actions.
+// Creates an unlocked record if the lock type hasn't been used in the chat yet.
+func SetLockAction(chatID int64, perm, action string, duration int64) error {
+	err := DB.Where("chat_id = ? AND lock_type = ?", chatID, perm).
+		Assign(map[string]any{"action": action, "action_duration": duration}).
+		FirstOrCreate(&LockSettings{ChatId: chatID, LockType: perm}).Error
+	if err != nil {
+		log.Errorf("[Database] SetLockAction: %v - %d", err, chatID)
+	}
+	return err
+}
+
+// GetMaxMentions retrieves the number of mentions a message may have in a chat before it breaks the mention lock.
+// Returns DefaultMaxMentions if no threshold was set.
+func GetMaxMentions(chatID int64) int {
+	lock := &LockSettings{}
+	err := GetRecord(lock, LockSettings{ChatId: chatID, LockType: "mention"})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetMaxMentions: %v - %d", err, chatID)
+		}
+		return DefaultMaxMentions
+	}
+	if lock.Threshold <= 0 {
+		return DefaultMaxMentions
+	}
+	return lock.Threshold
+}
+
+// SetMaxMentions sets the number of mentions a message may have in a chat before it breaks the mention lock.
+// Creates an unlocked record if the mention lock hasn't been used in the chat yet.
+func SetMaxMentions(chatID int64, maxMentions int) error {
+	err := DB.Where("chat_id = ? AND lock_type = ?", chatID, "mention").
+		Assign(map[string]any{"threshold": maxMentions}).
+		FirstOrCreate(&LockSettings{ChatId: chatID, LockType: "mention"}).Error
+	if err != nil {
+		log.Errorf("[Database] SetMaxMentions: %v - %d", err, chatID)
+	}
+	return err
+}
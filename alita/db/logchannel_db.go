@@ -0,0 +1,94 @@
+package db
+
+import (
+	"errors"
+	"slices"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Event categories that can be posted to a log channel
+const (
+	LogCategoryModeration = "moderation"
+	LogCategorySettings   = "settings"
+	LogCategoryCaptcha    = "captcha"
+	LogCategoryReports    = "reports"
+	LogCategoryJoins      = "joins"
+)
+
+// LogCategories lists every event category that can be toggled for a log channel.
+var LogCategories = []string{
+	LogCategoryModeration,
+	LogCategorySettings,
+	LogCategoryCaptcha,
+	LogCategoryReports,
+	LogCategoryJoins,
+}
+
+// ErrInvalidLogCategory is returned when toggling an unknown log category.
+var ErrInvalidLogCategory = errors.New("INVALID_LOG_CATEGORY")
+
+// GetLogChannel retrieves the log channel settings of a chat, with caching support.
+// A ChannelId of 0 means the chat has no log channel.
+func GetLogChannel(chatId int64) *LogChannelSettings {
+	settings, err := getFromCacheOrLoad(logChannelCacheKey(chatId), CacheTTLLogChannel, func() (*LogChannelSettings, error) {
+		settings := &LogChannelSettings{}
+		err := GetRecord(settings, LogChannelSettings{ChatId: chatId})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &LogChannelSettings{ChatId: chatId}, nil
+		} else if err != nil {
+			log.Errorf("[Database] GetLogChannel: %v - %d", err, chatId)
+			return nil, err
+		}
+		return settings, nil
+	})
+	if err != nil || settings == nil {
+		return &LogChannelSettings{ChatId: chatId}
+	}
+	return settings
+}
+
+// SetLogChannel sets the channel events of a chat are posted to.
+// Every log category is enabled when a chat sets its first log channel.
+func SetLogChannel(chatId, channelId int64) error {
+	err := DB.Where("chat_id = ?", chatId).
+		Assign(map[string]any{"channel_id": channelId}).
+		FirstOrCreate(&LogChannelSettings{ChatId: chatId}).Error
+	if err != nil {
+		log.Errorf("[Database] SetLogChannel: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(logChannelCacheKey(chatId))
+	return nil
+}
+
+// UnsetLogChannel removes the log channel of a chat.
+// Returns true if the chat had a log channel.
+func UnsetLogChannel(chatId int64) bool {
+	result := DB.Where("chat_id = ?", chatId).Delete(&LogChannelSettings{})
+	if result.Error != nil {
+		log.Errorf("[Database] UnsetLogChannel: %v - %d", result.Error, chatId)
+		return false
+	}
+
+	deleteCache(logChannelCacheKey(chatId))
+	return result.RowsAffected > 0
+}
+
+// SetLogCategory enables or disables posting a category of events to the log channel of a chat.
+func SetLogCategory(chatId int64, category string, enabled bool) error {
+	if !slices.Contains(LogCategories, category) {
+		return ErrInvalidLogCategory
+	}
+
+	err := DB.Model(&LogChannelSettings{}).Where("chat_id = ?", chatId).Update("log_"+category, enabled).Error
+	if err != nil {
+		log.Errorf("[Database] SetLogCategory: %v - %d", err, chatId)
+		return err
+	}
+
+	deleteCache(logChannelCacheKey(chatId))
+	return nil
+}
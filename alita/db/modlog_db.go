@@ -0,0 +1,90 @@
+package db
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Moderation actions recorded in the audit log
+const (
+	ModActionBan        = "ban"
+	ModActionUnban      = "unban"
+	ModActionKick       = "kick"
+	ModActionMute       = "mute"
+	ModActionUnmute     = "unmute"
+	ModActionWarn       = "warn"
+	ModActionUnwarn     = "unwarn"
+	ModActionResetWarns = "resetwarns"
+	ModActionPurge      = "purge"
+	ModActionLock       = "lock"
+	ModActionUnlock     = "unlock"
+)
+
+// ModActionTypes lists every action type that can be recorded in the audit log.
+var ModActionTypes = []string{
+	ModActionBan,
+	ModActionUnban,
+	ModActionKick,
+	ModActionMute,
+	ModActionUnmute,
+	ModActionWarn,
+	ModActionUnwarn,
+	ModActionResetWarns,
+	ModActionPurge,
+	ModActionLock,
+	ModActionUnlock,
+}
+
+// ModLogFilter narrows down the entries returned from the audit log of a chat.
+// Zero values match every entry.
+type ModLogFilter struct {
+	TargetId int64
+	Action   string
+	Since    time.Time
+	Until    time.Time
+}
+
+// AddModAction stores a moderation action in the audit log.
+func AddModAction(entry *ModAction) error {
+	err := CreateRecord(entry)
+	if err != nil {
+		log.Errorf("[Database] AddModAction: %v - %d", err, entry.ChatId)
+	}
+	return err
+}
+
+// GetModActions retrieves a page of the audit log of a chat, newest first.
+// Returns the entries on the page along with the total number of matching entries.
+func GetModActions(chatId int64, filter ModLogFilter, offset, limit int) (entries []*ModAction, total int64) {
+	scope := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("chat_id = ?", chatId)
+		if filter.TargetId != 0 {
+			tx = tx.Where("target_id = ?", filter.TargetId)
+		}
+		if filter.Action != "" {
+			tx = tx.Where("action = ?", filter.Action)
+		}
+		if !filter.Since.IsZero() {
+			tx = tx.Where("created_at >= ?", filter.Since)
+		}
+		if !filter.Until.IsZero() {
+			tx = tx.Where("created_at < ?", filter.Until)
+		}
+		return tx
+	}
+
+	err := DB.Model(&ModAction{}).Scopes(scope).Count(&total).Error
+	if err != nil {
+		log.Errorf("[Database] GetModActions: %v - %d", err, chatId)
+		return nil, 0
+	}
+
+	err = DB.Scopes(scope).Order("created_at DESC, id DESC").Offset(offset).Limit(limit).Find(&entries).Error
+	if err != nil {
+		log.Errorf("[Database] GetModActions: %v - %d", err, chatId)
+		return nil, 0
+	}
+	return
+}
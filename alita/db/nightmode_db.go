@@ -0,0 +1,70 @@
+package db
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Permission presets applied while night mode is active
+const (
+	NightModePresetReadOnly = "readonly"
+	NightModePresetMedia    = "media"
+)
+
+// Default night mode window, from 23:00 to 07:00 UTC
+const (
+	DefaultNightModeStart = 23 * 60
+	DefaultNightModeEnd   = 7 * 60
+)
+
+// defaultNightMode returns the night mode settings of a chat that hasn't configured it.
+func defaultNightMode(chatId int64) *NightModeSettings {
+	return &NightModeSettings{
+		ChatId:      chatId,
+		StartMinute: DefaultNightModeStart,
+		EndMinute:   DefaultNightModeEnd,
+		Timezone:    "UTC",
+		Preset:      NightModePresetReadOnly,
+	}
+}
+
+// GetNightMode retrieves the night mode settings of a chat.
+// Returns the default settings if the chat hasn't configured night mode.
+func GetNightMode(chatId int64) *NightModeSettings {
+	settings := &NightModeSettings{}
+	err := GetRecord(settings, NightModeSettings{ChatId: chatId})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetNightMode: %v - %d", err, chatId)
+		}
+		return defaultNightMode(chatId)
+	}
+	return settings
+}
+
+// UpdateNightMode changes the given night mode settings of a chat, creating them with defaults if needed.
+// Keys of updates are column names.
+func UpdateNightMode(chatId int64, updates map[string]any) error {
+	err := DB.Where("chat_id = ?", chatId).
+		Assign(updates).
+		FirstOrCreate(defaultNightMode(chatId)).Error
+	if err != nil {
+		log.Errorf("[Database] UpdateNightMode: %v - %d", err, chatId)
+	}
+	return err
+}
+
+// SetNightModeActive records whether night mode currently restricts a chat,
+// along with the permissions to restore when it ends.
+func SetNightModeActive(chatId int64, active bool, savedPermissions string) error {
+	err := DB.Model(&NightModeSettings{}).Where("chat_id = ?", chatId).Updates(map[string]any{
+		"active":            active,
+		"saved_permissions": savedPermissions,
+	}).Error
+	if err != nil {
+		log.Errorf("[Database] SetNightModeActive: %v - %d", err, chatId)
+	}
+	return err
+}
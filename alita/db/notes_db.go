@@ -141,15 +141,16 @@ func AddNote(chatID int64, noteName, replyText, fileID string, buttons ButtonArr
 }
 
 // RemoveNote deletes a note with the specified name from the chat.
-// Does nothing if the note doesn't exist.
-func RemoveNote(chatID int64, noteName string) {
+// Returns true if the note existed and was deleted.
+func RemoveNote(chatID int64, noteName string) bool {
 	// Directly attempt to delete the note without checking existence first
 	result := DB.Where("chat_id = ? AND note_name = ?", chatID, noteName).Delete(&Notes{})
 	if result.Error != nil {
 		log.Errorf("[Database][RemoveNote]: %d - %v", chatID, result.Error)
-		return
+		return false
 	}
-	// result.RowsAffected will be 0 if no note was found, which is fine
+	// result.RowsAffected will be 0 if no note was found
+	return result.RowsAffected > 0
 }
 
 // RemoveAllNotes deletes all notes for the specified chat ID from the database.
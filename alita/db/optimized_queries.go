@@ -178,7 +178,7 @@ func (o *OptimizedAntifloodQueries) GetAntifloodSettings(chatID int64) (*Antiflo
 
 	var settings AntifloodSettings
 	err := o.db.Model(&AntifloodSettings{}).
-		Select("id, chat_id, flood_limit, action, mode, delete_antiflood_message").
+		Select("id, chat_id, flood_limit, action, mode, action_duration, delete_antiflood_message, timer_limit, timer_seconds, timer_action, timer_action_duration").
 		Where("chat_id = ?", chatID).
 		First(&settings).Error
 
@@ -222,7 +222,7 @@ func (o *OptimizedFilterQueries) GetChatFiltersOptimized(chatID int64) ([]*ChatF
 
 	var filters []*ChatFilters
 	err := o.db.Model(&ChatFilters{}).
-		Select("id, keyword, filter_reply, msgtype").
+		Select("id, keyword, match_mode, group_id, filter_reply, msgtype").
 		Where("chat_id = ?", chatID).
 		Find(&filters).Error
 	if err != nil {
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Lifecycle states of a scheduled job
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// DefaultJobMaxAttempts is the number of times a job is tried before it is marked as failed.
+const DefaultJobMaxAttempts = 5
+
+// EnqueueJob stores a job to be run at job.RunAt.
+// If the job has a key, any pending job with the same type and key is replaced.
+// Job times are stored in UTC, since the columns have no time zone and would
+// otherwise keep the wall clock of whatever location the time was in.
+func EnqueueJob(job *ScheduledJob) error {
+	job.RunAt = job.RunAt.UTC()
+	job.Status = JobStatusPending
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultJobMaxAttempts
+	}
+	if job.Payload == "" {
+		job.Payload = "{}"
+	}
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if job.JobKey != "" {
+			if err := tx.Where("job_type = ? AND job_key = ? AND status = ?", job.JobType, job.JobKey, JobStatusPending).
+				Delete(&ScheduledJob{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(job).Error
+	})
+	if err != nil {
+		log.Errorf("[Database] EnqueueJob: %v - %s", err, job.JobType)
+		return err
+	}
+	return nil
+}
+
+// CancelJobs removes the pending jobs with the given type and key.
+// Returns the number of jobs removed.
+func CancelJobs(jobType, jobKey string) (int64, error) {
+	result := DB.Where("job_type = ? AND job_key = ? AND status = ?", jobType, jobKey, JobStatusPending).
+		Delete(&ScheduledJob{})
+	if result.Error != nil {
+		log.Errorf("[Database] CancelJobs: %v - %s", result.Error, jobType)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// ClaimDueJobs marks up to limit due jobs as running and returns them.
+// Jobs left running by a crashed or stopped instance are claimed again once their lease expires.
+// Rows are locked with SKIP LOCKED, so several instances never claim the same job.
+func ClaimDueJobs(limit int, lease time.Duration) ([]*ScheduledJob, error) {
+	now := time.Now().UTC()
+	var jobs []*ScheduledJob
+	err := DB.Raw(`
+		UPDATE scheduled_jobs
+		SET status = ?, attempts = attempts + 1, locked_until = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM scheduled_jobs
+			WHERE run_at <= ? AND (status = ? OR (status = ? AND locked_until < ?))
+			ORDER BY run_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		JobStatusRunning, now.Add(lease), now,
+		now, JobStatusPending, JobStatusRunning, now,
+		limit,
+	).Scan(&jobs).Error
+	if err != nil {
+		log.Errorf("[Database] ClaimDueJobs: %v", err)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// CompleteJob marks a job as successfully run.
+func CompleteJob(id uint) error {
+	err := DB.Model(&ScheduledJob{}).Where("id = ?", id).Updates(map[string]any{
+		"status":       JobStatusDone,
+		"locked_until": nil,
+		"last_error":   "",
+	}).Error
+	if err != nil {
+		log.Errorf("[Database] CompleteJob: %v - %d", err, id)
+		return err
+	}
+	return nil
+}
+
+// RetryJob puts a failed job back in the queue to be run again at runAt.
+func RetryJob(id uint, runAt time.Time, lastError string) error {
+	err := DB.Model(&ScheduledJob{}).Where("id = ?", id).Updates(map[string]any{
+		"status":       JobStatusPending,
+		"run_at":       runAt.UTC(),
+		"locked_until": nil,
+		"last_error":   lastError,
+	}).Error
+	if err != nil {
+		log.Errorf("[Database] RetryJob: %v - %d", err, id)
+		return err
+	}
+	return nil
+}
+
+// FailJob marks a job as permanently failed.
+func FailJob(id uint, lastError string) error {
+	err := DB.Model(&ScheduledJob{}).Where("id = ?", id).Updates(map[string]any{
+		"status":       JobStatusFailed,
+		"locked_until": nil,
+		"last_error":   lastError,
+	}).Error
+	if err != nil {
+		log.Errorf("[Database] FailJob: %v - %d", err, id)
+		return err
+	}
+	return nil
+}
+
+// CountOverdueJobs returns the number of jobs that should already have run,
+// including jobs whose runner died before finishing them.
+func CountOverdueJobs() (int64, error) {
+	now := time.Now().UTC()
+	var count int64
+	err := DB.Model(&ScheduledJob{}).
+		Where("run_at <= ? AND (status = ? OR (status = ? AND locked_until < ?))", now, JobStatusPending, JobStatusRunning, now).
+		Count(&count).Error
+	if err != nil {
+		log.Errorf("[Database] CountOverdueJobs: %v", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// CleanupFinishedJobs removes done and failed jobs last updated before the given age.
+func CleanupFinishedJobs(age time.Duration) (int64, error) {
+	result := DB.Where("status IN ? AND updated_at < ?", []string{JobStatusDone, JobStatusFailed}, time.Now().Add(-age)).
+		Delete(&ScheduledJob{})
+	if result.Error != nil {
+		log.Errorf("[Database] CleanupFinishedJobs: %v", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Kinds of schedule a scheduled message can follow
+const (
+	ScheduleTypeOnce     = "once"
+	ScheduleTypeInterval = "interval"
+	ScheduleTypeCron     = "cron"
+)
+
+// AddScheduledMessage stores a new scheduled message.
+func AddScheduledMessage(message *ScheduledMessage) error {
+	err := CreateRecord(message)
+	if err != nil {
+		log.Errorf("[Database] AddScheduledMessage: %v - %d", err, message.ChatId)
+	}
+	return err
+}
+
+// GetScheduledMessage retrieves a scheduled message by its ID.
+// Returns nil if the message doesn't exist.
+func GetScheduledMessage(id uint) *ScheduledMessage {
+	message := &ScheduledMessage{}
+	err := DB.Where("id = ?", id).First(message).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Errorf("[Database] GetScheduledMessage: %v - %d", err, id)
+		}
+		return nil
+	}
+	return message
+}
+
+// GetChatScheduledMessages retrieves the scheduled messages of a chat, ordered by their next run.
+func GetChatScheduledMessages(chatId int64) (messages []*ScheduledMessage) {
+	err := DB.Where("chat_id = ?", chatId).Order("next_run_at ASC, id ASC").Find(&messages).Error
+	if err != nil {
+		log.Errorf("[Database] GetChatScheduledMessages: %v - %d", err, chatId)
+		return nil
+	}
+	return messages
+}
+
+// CountChatScheduledMessages returns the number of scheduled messages of a chat.
+func CountChatScheduledMessages(chatId int64) (count int64) {
+	err := DB.Model(&ScheduledMessage{}).Where("chat_id = ?", chatId).Count(&count).Error
+	if err != nil {
+		log.Errorf("[Database] CountChatScheduledMessages: %v - %d", err, chatId)
+	}
+	return count
+}
+
+// RemoveScheduledMessage deletes a scheduled message of a chat.
+// Returns true if the message existed.
+func RemoveScheduledMessage(chatId int64, id uint) bool {
+	result := DB.Where("chat_id = ? AND id = ?", chatId, id).Delete(&ScheduledMessage{})
+	if result.Error != nil {
+		log.Errorf("[Database] RemoveScheduledMessage: %v - %d", result.Error, chatId)
+		return false
+	}
+	return result.RowsAffected > 0
+}
+
+// DeleteScheduledMessage deletes a scheduled message by its ID, once it has no runs left.
+func DeleteScheduledMessage(id uint) error {
+	err := DB.Where("id = ?", id).Delete(&ScheduledMessage{}).Error
+	if err != nil {
+		log.Errorf("[Database] DeleteScheduledMessage: %v - %d", err, id)
+	}
+	return err
+}
+
+// UpdateScheduledMessageRun records the message sent for the latest run of a scheduled message
+// and when it runs next.
+func UpdateScheduledMessageRun(id uint, lastMessageId int64, nextRunAt time.Time) error {
+	err := DB.Model(&ScheduledMessage{}).Where("id = ?", id).Updates(map[string]any{
+		"last_message_id": lastMessageId,
+		"next_run_at":     nextRunAt,
+	}).Error
+	if err != nil {
+		log.Errorf("[Database] UpdateScheduledMessageRun: %v - %d", err, id)
+	}
+	return err
+}
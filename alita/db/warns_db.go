@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/divideprojects/Alita_Robot/alita/i18n"
 	log "github.com/sirupsen/logrus"
@@ -64,15 +65,17 @@ func checkWarns(userId, chatId int64) (warnrc *Warns) {
 }
 
 // WarnUser adds a warning to a user in a specific chat with an optional reason.
+// The issuer is stored alongside the warning in its own warn record.
 // Returns the total number of warnings and all warning reasons for the user.
-func WarnUser(userId, chatId int64, reason string) (int, []string) {
-	return WarnUserWithContext(context.Background(), userId, chatId, reason)
+func WarnUser(userId, chatId, issuerId int64, reason string) (int, []string) {
+	return WarnUserWithContext(context.Background(), userId, chatId, issuerId, reason)
 }
 
 // WarnUserWithContext adds a warning to a user with context support for cancellation.
 // Uses database transactions to ensure data consistency and supports context cancellation.
+// If the chat has a warn time set, the new warning expires after that duration.
 // Returns the total number of warnings and all warning reasons for the user.
-func WarnUserWithContext(ctx context.Context, userId, chatId int64, reason string) (int, []string) {
+func WarnUserWithContext(ctx context.Context, userId, chatId, issuerId int64, reason string) (int, []string) {
 	var numWarns int
 	var reasons []string
 
@@ -105,7 +108,6 @@ func WarnUserWithContext(ctx context.Context, userId, chatId int64, reason strin
 			if len(reason) >= 3001 {
 				reason = reason[:3000]
 			}
-			warnrc.Reasons = append(warnrc.Reasons, reason)
 		} else {
 			// Use default language for "No Reason" - this could be improved to use chat language
 			tr := i18n.MustNewTranslator("en")
@@ -113,14 +115,25 @@ func WarnUserWithContext(ctx context.Context, userId, chatId int64, reason strin
 			if noReason == "" {
 				noReason = "No Reason" // fallback
 			}
-			warnrc.Reasons = append(warnrc.Reasons, noReason)
+			reason = noReason
 		}
+		warnrc.Reasons = append(warnrc.Reasons, reason)
 
 		// Save the warn record
 		if err := tx.Save(warnrc).Error; err != nil {
 			return err
 		}
 
+		// Store the individual warning with its issuer and expiry
+		record := &WarnRecord{UserId: userId, ChatId: chatId, IssuedBy: issuerId, Reason: reason}
+		if warnSettings.WarnTime > 0 {
+			expiresAt := time.Now().Add(time.Duration(warnSettings.WarnTime) * time.Second)
+			record.ExpiresAt = &expiresAt
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+
 		numWarns = warnrc.NumWarns
 		reasons = []string(warnrc.Reasons)
 		return nil
@@ -171,6 +184,17 @@ func RemoveWarnWithContext(ctx context.Context, userId, chatId int64) bool {
 			if err := tx.Save(warnrc).Error; err != nil {
 				return err
 			}
+
+			// Drop the matching individual warning, if one was recorded
+			latest := &WarnRecord{}
+			err := tx.Where("user_id = ? AND chat_id = ?", userId, chatId).Order("created_at DESC, id DESC").First(latest).Error
+			if err == nil {
+				if err := tx.Delete(latest).Error; err != nil {
+					return err
+				}
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
 		}
 
 		return nil
@@ -192,7 +216,12 @@ func RemoveWarnWithContext(ctx context.Context, userId, chatId int64) bool {
 // Returns true if the operation was successful, false on error.
 func ResetUserWarns(userId, chatId int64) (removed bool) {
 	removed = true
-	err := DB.Where("user_id = ? AND chat_id = ?", userId, chatId).Delete(&Warns{}).Error
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND chat_id = ?", userId, chatId).Delete(&WarnRecord{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND chat_id = ?", userId, chatId).Delete(&Warns{}).Error
+	})
 	if err != nil {
 		log.Errorf("[Database] ResetUserWarns: %v", err)
 		removed = false
@@ -207,6 +236,17 @@ func GetWarns(userId, chatId int64) (int, []string) {
 	return warnrc.NumWarns, []string(warnrc.Reasons)
 }
 
+// GetWarnRecords retrieves the individual warnings of a user in a specific chat, oldest first.
+// Warnings issued before warn records were introduced are not included.
+func GetWarnRecords(userId, chatId int64) (records []*WarnRecord) {
+	err := DB.Where("user_id = ? AND chat_id = ?", userId, chatId).Order("created_at ASC, id ASC").Find(&records).Error
+	if err != nil {
+		log.Errorf("[Database] GetWarnRecords: %v - %d", err, userId)
+		return nil
+	}
+	return
+}
+
 // SetWarnLimit updates the warning limit for a specific chat.
 // When users reach this limit, the configured warn mode action is applied.
 func SetWarnLimit(chatId int64, warnLimit int) {
@@ -229,6 +269,17 @@ func SetWarnMode(chatId int64, warnMode string) {
 	}
 }
 
+// SetWarnTime updates the number of seconds after which new warnings expire.
+// A value of 0 means warnings never expire.
+func SetWarnTime(chatId, warnTime int64) {
+	warnrc := checkWarnSettings(chatId)
+	warnrc.WarnTime = warnTime
+	err := DB.Save(warnrc).Error
+	if err != nil {
+		log.Errorf("[Database] SetWarnTime: %v", err)
+	}
+}
+
 // GetWarnSetting returns the warning settings for the specified chat.
 // This is the public interface to access warning configuration.
 func GetWarnSetting(chatId int64) *WarnSettings {
@@ -250,10 +301,116 @@ func GetAllChatWarns(chatId int64) int {
 // ResetAllChatWarns removes all warning records for all users in a specific chat.
 // Returns true if the operation was successful, false on error.
 func ResetAllChatWarns(chatId int64) bool {
-	err := DB.Where("chat_id = ?", chatId).Delete(&Warns{}).Error
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("chat_id = ?", chatId).Delete(&WarnRecord{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("chat_id = ?", chatId).Delete(&Warns{}).Error
+	})
 	if err != nil {
 		log.Errorf("[Database] ResetAllChatWarns: %v", err)
 		return false
 	}
 	return true
 }
+
+// GetWarnLadder returns the warn ladder of a chat, ordered by warn count.
+// An empty ladder means the chat uses its warn limit and warn mode instead.
+func GetWarnLadder(chatId int64) (steps []*WarnLadderStep) {
+	err := DB.Where("chat_id = ?", chatId).Order("warn_count ASC").Find(&steps).Error
+	if err != nil {
+		log.Errorf("[Database] GetWarnLadder: %v - %d", err, chatId)
+		return nil
+	}
+	return
+}
+
+// SetWarnLadderStep adds a step to the warn ladder of a chat,
+// replacing the existing step for the same warn count.
+// duration is in seconds and only used by timed actions.
+func SetWarnLadderStep(chatId int64, warnCount int, action string, duration int64) error {
+	err := DB.Where("chat_id = ? AND warn_count = ?", chatId, warnCount).
+		Assign(map[string]any{"action": action, "duration": duration}).
+		FirstOrCreate(&WarnLadderStep{ChatId: chatId, WarnCount: warnCount}).Error
+	if err != nil {
+		log.Errorf("[Database] SetWarnLadderStep: %v - %d", err, chatId)
+	}
+	return err
+}
+
+// RemoveWarnLadderStep removes the step for a warn count from the warn ladder of a chat.
+// Returns true if a step existed and has been removed.
+func RemoveWarnLadderStep(chatId int64, warnCount int) bool {
+	result := DB.Where("chat_id = ? AND warn_count = ?", chatId, warnCount).Delete(&WarnLadderStep{})
+	if result.Error != nil {
+		log.Errorf("[Database] RemoveWarnLadderStep: %v - %d", result.Error, chatId)
+		return false
+	}
+	return result.RowsAffected > 0
+}
+
+// ClearWarnLadder removes all steps from the warn ladder of a chat.
+func ClearWarnLadder(chatId int64) error {
+	err := DB.Where("chat_id = ?", chatId).Delete(&WarnLadderStep{}).Error
+	if err != nil {
+		log.Errorf("[Database] ClearWarnLadder: %v - %d", err, chatId)
+	}
+	return err
+}
+
+// CleanupExpiredWarns removes all expired warnings from the database.
+// The warn count and reasons of every affected user are rebuilt from their remaining warnings.
+// This should be called periodically to let warnings decay.
+func CleanupExpiredWarns() (int64, error) {
+	var removed int64
+
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		var expired []WarnRecord
+		err := tx.Select("DISTINCT user_id, chat_id").Where("expires_at IS NOT NULL AND expires_at < ?", now).Find(&expired).Error
+		if err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		result := tx.Where("expires_at IS NOT NULL AND expires_at < ?", now).Delete(&WarnRecord{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected
+
+		for _, e := range expired {
+			var records []WarnRecord
+			err := tx.Where("user_id = ? AND chat_id = ?", e.UserId, e.ChatId).Order("created_at ASC, id ASC").Find(&records).Error
+			if err != nil {
+				return err
+			}
+
+			reasons := make(StringArray, 0, len(records))
+			for _, r := range records {
+				reasons = append(reasons, r.Reason)
+			}
+
+			err = tx.Model(&Warns{}).
+				Where("user_id = ? AND chat_id = ?", e.UserId, e.ChatId).
+				Updates(map[string]any{"num_warns": len(records), "warns": reasons}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("[Database][CleanupExpiredWarns]: %v", err)
+		return 0, err
+	}
+
+	if removed > 0 {
+		log.Infof("[Database][CleanupExpiredWarns]: Removed %d expired warnings", removed)
+	}
+
+	return removed, nil
+}
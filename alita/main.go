@@ -9,6 +9,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/divideprojects/Alita_Robot/alita/config"
 	"github.com/divideprojects/Alita_Robot/alita/db"
 	"github.com/divideprojects/Alita_Robot/alita/modules"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 
 	"github.com/divideprojects/Alita_Robot/alita/utils/cache"
+	"github.com/divideprojects/Alita_Robot/alita/utils/ratestate"
 	"github.com/divideprojects/Alita_Robot/alita/utils/string_handling"
 )
 
@@ -78,6 +80,11 @@ func InitialChecks(b *gotgbot.Bot) error {
 		return fmt.Errorf("failed to initialize cache: %w", err)
 	}
 
+	// Select where antiflood and antispam keep their counters, needs the cache for redis
+	if err := ratestate.Init(config.RateStateBackend); err != nil {
+		return fmt.Errorf("failed to initialize rate state: %w", err)
+	}
+
 	// Start resource monitoring
 	go ResourceMonitor()
 	return nil
@@ -111,17 +118,25 @@ func LoadModules(dispatcher *ext.Dispatcher) {
 
 	modules.LoadBotUpdates(dispatcher)
 	modules.LoadAntispam(dispatcher)
+	modules.LoadApprovals(dispatcher)
 	modules.LoadLanguage(dispatcher)
 	modules.LoadAdmin(dispatcher)
 	modules.LoadPin(dispatcher)
 	modules.LoadMisc(dispatcher)
 	modules.LoadBans(dispatcher)
+	modules.LoadAppeals(dispatcher)
+	modules.LoadFeds(dispatcher)
+	modules.LoadGbans(dispatcher)
+	modules.LoadModlog(dispatcher)
+	modules.LoadLogs(dispatcher)
+	modules.LoadSchedules(dispatcher)
 	modules.LoadMutes(dispatcher)
 	modules.LoadPurges(dispatcher)
 	modules.LoadUsers(dispatcher)
 	modules.LoadReports(dispatcher)
 	modules.LoadDev(dispatcher)
 	modules.LoadLocks(dispatcher)
+	modules.LoadNightmode(dispatcher)
 	modules.LoadFilters(dispatcher)
 	modules.LoadAntiflood(dispatcher)
 	modules.LoadNotes(dispatcher)
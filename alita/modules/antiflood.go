@@ -3,6 +3,7 @@ package modules
 import (
 	"context"
 	"fmt"
+	"html"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,23 +21,23 @@ import (
 
 	"github.com/divideprojects/Alita_Robot/alita/db"
 	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
+	"github.com/divideprojects/Alita_Robot/alita/utils/ratestate"
 
 	"github.com/divideprojects/Alita_Robot/alita/utils/string_handling"
 )
 
 type antifloodStruct struct {
-	moduleStruct  // inheritance
-	syncHelperMap sync.Map
+	moduleStruct // inheritance
 	// Add semaphore to limit concurrent admin checks
 	adminCheckSemaphore chan struct{}
 }
 
-type floodControl struct {
-	userId       int64
-	messageCount int
-	messageIDs   []int64
-	lastActivity int64 // Unix timestamp for cleanup
-}
+// floodIdleTime is how long a user has to stay quiet for their flood count to start over.
+const floodIdleTime = time.Minute
+
+// maxFloodTimerSeconds is the longest window allowed for the timed mode.
+const maxFloodTimerSeconds = 3600
 
 var _normalAntifloodModule = moduleStruct{
 	moduleName:   "Antiflood",
@@ -45,99 +46,49 @@ var _normalAntifloodModule = moduleStruct{
 
 var antifloodModule = antifloodStruct{
 	moduleStruct:        _normalAntifloodModule,
-	syncHelperMap:       sync.Map{},
 	adminCheckSemaphore: make(chan struct{}, 50), // Limit to 50 concurrent admin checks
 }
 
-// init starts cleanup goroutine for antiflood cache
-func init() {
-	go antifloodModule.cleanupLoop()
+// floodKey is the rate state key counting the messages of a user in a chat.
+func floodKey(chatId, userId int64) string {
+	return fmt.Sprintf("flood:%d:%d", chatId, userId)
 }
 
-// cleanupLoop periodically cleans up old entries from the flood cache
-// cleanupLoop periodically removes old flood control entries from memory.
-// Runs every 5 minutes to clean entries older than 10 minutes.
-func (a *antifloodStruct) cleanupLoop() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		currentTime := time.Now().Unix()
-		a.syncHelperMap.Range(func(key, value any) bool {
-			if floodData, ok := value.(floodControl); ok {
-				// Remove entries older than 10 minutes
-				if currentTime-floodData.lastActivity > 600 {
-					a.syncHelperMap.Delete(key)
-				}
-			}
-			return true
-		})
-	}
+// floodTimerKey is the rate state key holding the sliding window of the timed mode.
+func floodTimerKey(chatId, userId int64) string {
+	return fmt.Sprintf("floodtimer:%d:%d", chatId, userId)
 }
 
 // updateFlood tracks message counts per user and determines if flood limit exceeded.
-// Returns true if user has exceeded flood limit and should be restricted.
-func (*moduleStruct) updateFlood(chatId, userId, msgId int64) (returnVar bool, floodCrc floodControl) {
-	floodSrc := db.GetFlood(chatId)
-
-	if floodSrc.Limit != 0 {
-		currentTime := time.Now().Unix()
-
-		// Key by composite (chatId, userId)
-		key := fmt.Sprintf("%d:%d", chatId, userId)
-		tmpInterface, valExists := antifloodModule.syncHelperMap.Load(key)
-		if valExists && tmpInterface != nil {
-			floodCrc = tmpInterface.(floodControl)
-
-			// Clean up old entries (older than 1 minute)
-			if currentTime-floodCrc.lastActivity > 60 {
-				floodCrc = floodControl{}
-			}
-		}
-
-		// No need to check userId mismatch since key includes userId
-		if floodCrc.userId == 0 {
-			floodCrc.userId = userId
-			floodCrc.messageCount = 0
-			floodCrc.messageIDs = make([]int64, 0, floodSrc.Limit+5) // Pre-allocate with capacity
-		}
+// Returns true along with the counted messages if the user should be restricted.
+func (*moduleStruct) updateFlood(floodSrc *db.AntifloodSettings, chatId, userId, msgId int64) (bool, []int64) {
+	if floodSrc.Limit == 0 {
+		return false, nil
+	}
 
-		floodCrc.messageCount++
-		floodCrc.lastActivity = currentTime
-
-		// Use efficient prepend with pre-allocated slice
-		if len(floodCrc.messageIDs) >= cap(floodCrc.messageIDs) {
-			// Resize if needed, keep only recent messages
-			newIDs := make([]int64, 1, floodSrc.Limit+5)
-			newIDs[0] = msgId
-			if len(floodCrc.messageIDs) > 0 {
-				copyCount := floodSrc.Limit
-				if copyCount > len(floodCrc.messageIDs) {
-					copyCount = len(floodCrc.messageIDs)
-				}
-				newIDs = append(newIDs, floodCrc.messageIDs[:copyCount]...)
-			}
-			floodCrc.messageIDs = newIDs
-		} else {
-			floodCrc.messageIDs = append([]int64{msgId}, floodCrc.messageIDs...)
-		}
+	messageIDs, err := ratestate.Get().Push(floodKey(chatId, userId), msgId, floodSrc.Limit, floodIdleTime)
+	if err != nil {
+		log.Errorf("[Antiflood] updateFlood: %d (%d) - %v", chatId, userId, err)
+		return false, nil
+	}
+	return messageIDs != nil, messageIDs
+}
 
-		if floodCrc.messageCount > floodSrc.Limit {
-			antifloodModule.syncHelperMap.Store(key,
-				floodControl{
-					userId:       0,
-					messageCount: 0,
-					messageIDs:   make([]int64, 0),
-					lastActivity: currentTime,
-				},
-			)
-			returnVar = true
-		} else {
-			antifloodModule.syncHelperMap.Store(key, floodCrc)
-		}
+// updateFloodTimer records a message in the sliding window of the timed mode, which counts
+// the messages of each user on their own regardless of what others send in between.
+// Returns true along with the messages in the window if the user sent more than the limit.
+func (*moduleStruct) updateFloodTimer(floodSrc *db.AntifloodSettings, chatId, userId, msgId int64) (bool, []int64) {
+	if floodSrc.TimerLimit == 0 || floodSrc.TimerSeconds == 0 {
+		return false, nil
 	}
 
-	return
+	window := time.Duration(floodSrc.TimerSeconds) * time.Second
+	messageIDs, err := ratestate.Get().Slide(floodTimerKey(chatId, userId), msgId, floodSrc.TimerLimit, window)
+	if err != nil {
+		log.Errorf("[Antiflood] updateFloodTimer: %d (%d) - %v", chatId, userId, err)
+		return false, nil
+	}
+	return messageIDs != nil, messageIDs
 }
 
 // checkFlood monitors incoming messages for flood violations.
@@ -188,7 +139,7 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 			}()
 
 			select {
-			case isAdmin <- chat_status.IsUserAdmin(b, chat.Id, userId):
+			case isAdmin <- chat_status.IsUserApprovedOrAdmin(b, chat.Id, userId):
 				// Successfully sent result
 			case <-ctx_timeout.Done():
 				// Context cancelled, exit goroutine early
@@ -199,7 +150,12 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 		select {
 		case admin := <-isAdmin:
 			if admin {
-				m.updateFlood(chat.Id, 0, 0) // empty message queue when admin sends a message
+				// empty the message queues of both flood checks when admin sends a message
+				for _, key := range []string{floodKey(chat.Id, userId), floodTimerKey(chat.Id, userId)} {
+					if err := ratestate.Get().Reset(key); err != nil {
+						log.Errorf("[Antiflood] checkFlood: %d (%d) - %v", chat.Id, userId, err)
+					}
+				}
 				return ext.ContinueGroups
 			}
 		case <-ctx_timeout.Done():
@@ -229,18 +185,30 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 		}).Debug("Admin check semaphore full, skipping admin check")
 	}
 
-	// update flood for user
-	flooded, floodCrc := m.updateFlood(chat.Id, userId, msg.MessageId)
+	flood := db.GetFlood(chat.Id)
+
+	// update flood for user, in both the consecutive and the timed mode
+	timerFlooded, timerMessageIDs := m.updateFloodTimer(flood, chat.Id, userId, msg.MessageId)
+	flooded, floodMessageIDs := m.updateFlood(flood, chat.Id, userId, msg.MessageId)
+	floodMode, floodDuration := flood.Mode, flood.ActionDuration
 	if !flooded {
-		return ext.ContinueGroups
+		if !timerFlooded {
+			return ext.ContinueGroups
+		}
+		floodMessageIDs = timerMessageIDs
+		floodMode, floodDuration = flood.TimerAction, flood.TimerActionDuration
 	}
 
-	flood := db.GetFlood(chat.Id)
+	// timed modes are lifted by telegram once the until date has passed
+	var untilDate int64
+	if (floodMode == "tmute" || floodMode == "tban") && floodDuration > 0 {
+		untilDate = time.Now().Unix() + floodDuration
+	}
 
 	if flood.DeleteAntifloodMessage {
 		// For small numbers of messages, delete sequentially
-		if len(floodCrc.messageIDs) <= 3 {
-			for _, i := range floodCrc.messageIDs {
+		if len(floodMessageIDs) <= 3 {
+			for _, i := range floodMessageIDs {
 				_, err := b.DeleteMessage(chat.Id, i, nil)
 				if err != nil && !strings.Contains(err.Error(), "message to delete not found") {
 					log.Error(err)
@@ -254,7 +222,7 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 			var deleteError error
 			var errorMu sync.Mutex
 
-			for _, msgId := range floodCrc.messageIDs {
+			for _, msgId := range floodMessageIDs {
 				wg.Add(1)
 				sem <- struct{}{} // Acquire semaphore
 
@@ -288,13 +256,13 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 		}
 	}
 
-	switch flood.Mode {
-	case "mute":
+	switch floodMode {
+	case "mute", "tmute":
 		// don't work on anonymous channels
 		if user.IsAnonymousChannel() {
 			return ext.ContinueGroups
 		}
-		fmode = "muted"
+		fmode = floodModeText(tr, floodMode, floodDuration)
 		keyboard = [][]gotgbot.InlineKeyboardButton{
 			{
 				{
@@ -321,7 +289,9 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 				CanSendPolls:          false,
 				CanSendOtherMessages:  false,
 			},
-			nil,
+			&gotgbot.RestrictChatMemberOpts{
+				UntilDate: untilDate,
+			},
 		)
 		if err != nil {
 			log.Errorf(" checkFlood: %d (%d) - %v", chat.Id, user.Id(), err)
@@ -332,7 +302,7 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 		if user.IsAnonymousChannel() {
 			return ext.ContinueGroups
 		}
-		fmode = "kicked"
+		fmode = floodModeText(tr, floodMode, floodDuration)
 		keyboard = nil
 		_, err := chat.BanMember(b, userId, nil)
 		if err != nil {
@@ -371,10 +341,10 @@ func (m *moduleStruct) checkFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 				}).Warn("Antiflood unban operation timed out")
 			}
 		}()
-	case "ban":
-		fmode = "banned"
+	case "ban", "tban":
+		fmode = floodModeText(tr, floodMode, floodDuration)
 		if !user.IsAnonymousChannel() {
-			_, err := chat.BanMember(b, userId, nil)
+			_, err := chat.BanMember(b, userId, &gotgbot.BanChatMemberOpts{UntilDate: untilDate})
 			if err != nil {
 				log.Errorf(" checkFlood: %d (%d) - %v", chat.Id, user.Id(), err)
 				return err
@@ -462,6 +432,98 @@ func (m *moduleStruct) setFlood(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// setFloodTimer handles the /setfloodtimer command to configure the timed mode,
+// which takes action on users sending more than a number of messages within some seconds.
+func (m *moduleStruct) setFloodTimer(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	args := ctx.Args()[1:]
+
+	var replyText string
+	switch {
+	case len(args) == 0:
+		replyText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_setfloodtimer_usage")
+	case string_handling.FindInStringSlice([]string{"off", "no", "false", "0"}, strings.ToLower(args[0])):
+		db.SetFloodTimer(chat.Id, 0, 0)
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+		replyText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_setfloodtimer_disabled")
+	case len(args) < 2:
+		replyText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_setfloodtimer_usage")
+	default:
+		count, countErr := strconv.Atoi(args[0])
+		seconds, secondsErr := strconv.Atoi(strings.TrimSuffix(strings.ToLower(args[1]), "s"))
+		if countErr != nil || secondsErr != nil || count < 2 || count > 100 || seconds < 1 || seconds > maxFloodTimerSeconds {
+			replyText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_setfloodtimer_invalid")
+			break
+		}
+		db.SetFloodTimer(chat.Id, count, seconds)
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+		replyText, _ = tr.GetString(strings.ToLower(m.moduleName)+"_setfloodtimer_success", i18n.TranslationParams{
+			"count":   strconv.Itoa(count),
+			"seconds": strconv.Itoa(seconds),
+		})
+	}
+
+	_, err := msg.Reply(b, replyText, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return ext.EndGroups
+}
+
+// setFloodTimerMode handles the /setfloodtimermode command to choose the action
+// taken on users tripping the timed mode, separately from /setfloodmode.
+func (m *moduleStruct) setFloodTimerMode(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	args := ctx.Args()[1:]
+
+	var replyText string
+	switch {
+	case len(args) == 0:
+		replyText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_setfloodtimermode_specify_action")
+	default:
+		var selectedMode string
+		var duration int64
+		selectedMode, duration, replyText = m.extractFloodMode(b, ctx, tr, args)
+		if selectedMode == "" {
+			if replyText == "" {
+				return ext.EndGroups
+			}
+			break
+		}
+		db.SetFloodTimerMode(chat.Id, selectedMode, duration)
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+		replyText, _ = tr.GetString(strings.ToLower(m.moduleName)+"_setfloodtimermode_success", i18n.TranslationParams{
+			"mode": punishmentDisplay(tr, selectedMode, duration),
+		})
+	}
+
+	_, err := msg.Reply(b, replyText, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return ext.EndGroups
+}
+
 // flood handles the /flood command to display current flood protection settings.
 // Shows the flood limit and action (mute/kick/ban) for the chat.
 func (m *moduleStruct) flood(b *gotgbot.Bot, ctx *ext.Context) error {
@@ -483,20 +545,21 @@ func (m *moduleStruct) flood(b *gotgbot.Bot, ctx *ext.Context) error {
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 
 	flood := db.GetFlood(chat.Id)
-	if flood.Limit == 0 {
+	if flood.Limit == 0 && flood.TimerLimit == 0 {
 		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_flood_disabled")
 	} else {
-		var mode string
-		switch flood.Mode {
-		case "mute":
-			mode = "muted"
-		case "ban":
-			mode = "banned"
-		case "kick":
-			mode = "kicked"
+		if flood.Limit != 0 {
+			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_flood_show_settings")
+			text = fmt.Sprintf(temp, flood.Limit, floodModeText(tr, flood.Mode, flood.ActionDuration))
+		}
+		if flood.TimerLimit != 0 {
+			timerText, _ := tr.GetString(strings.ToLower(m.moduleName)+"_flood_show_timer_settings", i18n.TranslationParams{
+				"count":   strconv.Itoa(flood.TimerLimit),
+				"seconds": strconv.Itoa(flood.TimerSeconds),
+				"mode":    floodModeText(tr, flood.TimerAction, flood.TimerActionDuration),
+			})
+			text = strings.TrimPrefix(text+"\n\n"+timerText, "\n\n")
 		}
-		temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_flood_show_settings")
-		text = fmt.Sprintf(temp, flood.Limit, mode)
 	}
 	_, err := msg.Reply(b, text, helpers.Shtml())
 	if err != nil {
@@ -520,18 +583,17 @@ func (m *moduleStruct) setFloodMode(b *gotgbot.Bot, ctx *ext.Context) error {
 	args := ctx.Args()[1:]
 
 	if len(args) > 0 {
-		selectedMode := strings.ToLower(args[0])
-		if string_handling.FindInStringSlice([]string{"ban", "kick", "mute"}, selectedMode) {
+		selectedMode, duration, replyText := m.extractFloodMode(b, ctx, tr, args)
+		if selectedMode != "" {
 			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_setfloodmode_success")
-			_, err := msg.Reply(b, fmt.Sprintf(temp, selectedMode), helpers.Shtml())
+			_, err := msg.Reply(b, fmt.Sprintf(temp, punishmentDisplay(tr, selectedMode, duration)), helpers.Shtml())
 			if err != nil {
 				log.Error(err)
 			}
-			go db.SetFloodMode(chat.Id, selectedMode)
+			go db.SetFloodMode(chat.Id, selectedMode, duration)
 			return ext.EndGroups
-		} else {
-			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_setfloodmode_unknown_type")
-			_, err := msg.Reply(b, fmt.Sprintf(temp, args[0]), helpers.Shtml())
+		} else if replyText != "" {
+			_, err := msg.Reply(b, replyText, helpers.Shtml())
 			if err != nil {
 				return err
 			}
@@ -546,6 +608,50 @@ func (m *moduleStruct) setFloodMode(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// extractFloodMode reads a flood mode from the command arguments, along with the
+// duration of the timed tmute and tban modes, which uses the same syntax as /tban.
+// If the mode can't be used, an empty mode is returned along with the text to reply with,
+// which is also empty when a reply has already been sent.
+func (m *moduleStruct) extractFloodMode(b *gotgbot.Bot, ctx *ext.Context, tr *i18n.Translator, args []string) (mode string, duration int64, replyText string) {
+	selectedMode := strings.ToLower(args[0])
+	switch selectedMode {
+	case "ban", "kick", "mute":
+		return selectedMode, 0, ""
+	case "tban", "tmute":
+		if len(args) < 2 {
+			replyText, _ = tr.GetString(strings.ToLower(m.moduleName)+"_setfloodmode_time_required", i18n.TranslationParams{"mode": selectedMode})
+			return "", 0, replyText
+		}
+		untilDate, _, _ := extraction.ExtractTime(b, ctx, args[1])
+		if untilDate == -1 {
+			return "", 0, ""
+		}
+		return selectedMode, durationUntil(untilDate), ""
+	default:
+		temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_setfloodmode_unknown_type")
+		return "", 0, fmt.Sprintf(temp, html.EscapeString(args[0]))
+	}
+}
+
+// floodModeText describes what happens to flooding users under a flood mode,
+// including the duration of the timed modes.
+func floodModeText(tr *i18n.Translator, mode string, duration int64) string {
+	var text string
+	switch mode {
+	case "ban":
+		text, _ = tr.GetString("antiflood_mode_banned")
+	case "tban":
+		text, _ = tr.GetString("antiflood_mode_banned_for", i18n.TranslationParams{"duration": formatWarnTime(tr, duration)})
+	case "kick":
+		text, _ = tr.GetString("antiflood_mode_kicked")
+	case "tmute":
+		text, _ = tr.GetString("antiflood_mode_muted_for", i18n.TranslationParams{"duration": formatWarnTime(tr, duration)})
+	default:
+		text, _ = tr.GetString("antiflood_mode_muted")
+	}
+	return text
+}
+
 // setFloodDeleter handles the /delflood command to toggle message deletion on flood.
 // Configures whether to delete all flood messages or just the triggering message.
 func (m *moduleStruct) setFloodDeleter(b *gotgbot.Bot, ctx *ext.Context) error {
@@ -596,6 +702,8 @@ func LoadAntiflood(dispatcher *ext.Dispatcher) {
 
 	dispatcher.AddHandler(handlers.NewCommand("setflood", antifloodModule.setFlood))
 	dispatcher.AddHandler(handlers.NewCommand("setfloodmode", antifloodModule.setFloodMode))
+	dispatcher.AddHandler(handlers.NewCommand("setfloodtimer", antifloodModule.setFloodTimer))
+	dispatcher.AddHandler(handlers.NewCommand("setfloodtimermode", antifloodModule.setFloodTimerMode))
 	dispatcher.AddHandler(handlers.NewCommand("delflood", antifloodModule.setFloodDeleter))
 	dispatcher.AddHandler(handlers.NewCommand("flood", antifloodModule.flood))
 	misc.AddCmdToDisableable("flood")
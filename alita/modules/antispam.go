@@ -1,83 +1,560 @@
 package modules
 
 import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/i18n"
+	"github.com/divideprojects/Alita_Robot/alita/utils/cache"
+	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
+	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+	"github.com/divideprojects/Alita_Robot/alita/utils/ratestate"
+	"github.com/divideprojects/Alita_Robot/alita/utils/scheduler"
 )
 
 var antispamModule = moduleStruct{
-	moduleName: "antispam",
-	antiSpam:   map[int64]*antiSpamInfo{},
+	moduleName: "Antispam",
+}
+
+// antispamRestoreJob is the scheduler job type that lifts the restrict action of a chat.
+const antispamRestoreJob = "antispam_restore"
+
+// antispamSlowModeDelay is how often each member may send a message while the slowmode action runs.
+const antispamSlowModeDelay = 10 * time.Second
+
+// Bounds of the antispam levels a chat can configure
+const (
+	maxAntispamLimit         = 1000
+	maxAntispamWindowSeconds = 3600
+)
+
+// defaultAntispamLevel applies to the whole chat unless it configures levels of its own.
+var defaultAntispamLevel = antiSpamLevel{
+	Limit:  18,
+	Expiry: time.Second,
+}
+
+// antispamPayload identifies the chat an antispam job belongs to.
+type antispamPayload struct {
+	ChatID int64 `json:"chat_id"`
+}
+
+// antispamLevels returns the levels enforced in a chat, falling back to the default
+// level for the whole chat when it has no chat levels configured.
+func antispamLevels(chatId int64) []antiSpamLevel {
+	var levels []antiSpamLevel
+	hasChatLevel := false
+	for _, level := range db.GetAntispamLevels(chatId) {
+		levels = append(levels, antiSpamLevel{
+			Limit:   level.MessageLimit,
+			Expiry:  time.Duration(level.Seconds) * time.Second,
+			PerUser: level.Scope == db.AntispamScopeUser,
+		})
+		if level.Scope == db.AntispamScopeChat {
+			hasChatLevel = true
+		}
+	}
+	if !hasChatLevel {
+		levels = append(levels, defaultAntispamLevel)
+	}
+	return levels
 }
 
 // checkSpammed evaluates if a chat has exceeded spam detection levels.
-// Returns true if any configured spam threshold has been violated.
-func (moduleStruct) checkSpammed(chatId int64, levels []antiSpamLevel) bool {
-	_asInfo, ok := antispamModule.antiSpam[chatId]
-	if !ok {
-		// Assign a new AntiSpamInfo to the chatId because not found
-		antispamModule.antiSpam[chatId] = &antiSpamInfo{
-			Levels: levels,
+// Returns true if any configured spam threshold has been violated, and whether this
+// message is the one that went over a chat level or a level of its sender.
+func (moduleStruct) checkSpammed(chatId, userId int64, levels []antiSpamLevel) (spammed, chatTripped, userTripped bool) {
+	for _, level := range levels {
+		// every level counts the messages within its own fixed window
+		key := fmt.Sprintf("antispam:%d:%d", chatId, level.Expiry.Milliseconds())
+		if level.PerUser {
+			key = fmt.Sprintf("antispam:%d:%d:%d", chatId, userId, level.Expiry.Milliseconds())
+		}
+		count, err := ratestate.Get().Incr(key, level.Expiry)
+		if err != nil {
+			log.Errorf("[Antispam] checkSpammed: %d - %v", chatId, err)
+			continue
+		}
+		if count > int64(level.Limit) {
+			spammed = true
+			if count == int64(level.Limit)+1 {
+				if level.PerUser {
+					userTripped = true
+				} else {
+					chatTripped = true
+				}
+			}
 		}
+	}
+	return spammed, chatTripped, userTripped
+}
+
+// antispamExempt reports whether the sender of an update is exempt from the per-user levels
+// and the slow mode, like anonymous admins, admins and approved users.
+func antispamExempt(b *gotgbot.Bot, ctx *ext.Context) bool {
+	sender := ctx.EffectiveSender
+	return sender == nil || sender.IsAnonymousAdmin() || chat_status.IsUserApprovedOrAdmin(b, ctx.EffectiveChat.Id, sender.Id())
+}
+
+// spamCheck performs spam detection for the chat of an update.
+// Returns true if the update should not be processed any further.
+func (m moduleStruct) spamCheck(b *gotgbot.Bot, ctx *ext.Context) bool {
+	chat := ctx.EffectiveChat
+	sender := ctx.EffectiveSender
+	var userId int64
+	if sender != nil {
+		userId = sender.Id()
+	}
+
+	// private chats only get the default level, settings belong to groups
+	if chat.Type == gotgbot.ChatTypePrivate {
+		spammed, _, _ := m.checkSpammed(chat.Id, userId, []antiSpamLevel{defaultAntispamLevel})
+		return spammed
+	}
+
+	levels := antispamLevels(chat.Id)
+	if slices.ContainsFunc(levels, func(level antiSpamLevel) bool { return level.PerUser }) && antispamExempt(b, ctx) {
+		levels = slices.DeleteFunc(levels, func(level antiSpamLevel) bool { return level.PerUser })
+	}
+
+	spammed, chatTripped, userTripped := m.checkSpammed(chat.Id, userId, levels)
+	settings := db.GetAntispamSettings(chat.Id)
+	if chatTripped {
+		m.antispamTripped(b, chat, settings)
+	} else if userTripped {
+		m.antispamUserTripped(b, ctx, settings)
+	}
+	if spammed {
+		return true
+	}
+
+	if settings.Action == db.AntispamActionSlowmode && settings.ActiveUntil != nil && time.Now().Before(*settings.ActiveUntil) {
+		return m.enforceAntispamSlowMode(b, ctx)
+	}
+	return false
+}
+
+// enforceAntispamSlowMode deletes the message if its sender already wrote within the slow mode delay.
+// Returns true if the message was deleted.
+func (moduleStruct) enforceAntispamSlowMode(b *gotgbot.Bot, ctx *ext.Context) bool {
+	chat := ctx.EffectiveChat
+	if antispamExempt(b, ctx) {
 		return false
 	}
-	newLevels := make([]antiSpamLevel, len(_asInfo.Levels))
-	var spammed bool
-	for n, level := range _asInfo.Levels {
-		// Expire the _asInfo if current time becomes greater than expiration time
-		if level.CurrTime+level.Expiry <= time.Duration(time.Now().UnixNano()) {
-			// Allocate a new 'current time' with count reset to 0 if expired
-			level.CurrTime = time.Duration(time.Now().UnixNano())
-			level.Count = 0
-			level.Spammed = false
-		}
-		level.Count += 1
-		if (level.Count + 1) > level.Limit {
-			// fmt.Println("level", n, "has been spammed with count", level.Count, "while the limit was", level.Limit)
-			level.Spammed = true
-		}
-		newLevels[n] = level
-		if !spammed && level.Spammed {
-			spammed = true
+
+	key := fmt.Sprintf("antispam_slow:%d:%d", chat.Id, ctx.EffectiveSender.Id())
+	count, err := ratestate.Get().Incr(key, antispamSlowModeDelay)
+	if err != nil {
+		log.Errorf("[Antispam] enforceAntispamSlowMode: %d - %v", chat.Id, err)
+		return false
+	}
+	if count == 1 {
+		return false
+	}
+
+	if _, err = ctx.EffectiveMessage.Delete(b, nil); err != nil {
+		log.Debugf("[Antispam] Failed to delete slow mode message in %d: %v", chat.Id, err)
+	}
+	return true
+}
+
+// antispamTripped counts a trip of the antispam levels of a chat and starts its configured action.
+// Only one instance starts the action at a time, and a running action isn't started again.
+func (m moduleStruct) antispamTripped(b *gotgbot.Bot, chat *gotgbot.Chat, settings *db.AntispamSettings) {
+	_ = db.RecordAntispamTrip(chat.Id)
+	if settings.Action == db.AntispamActionDrop {
+		return
+	}
+
+	lockKey := fmt.Sprintf("alita:antispam:trip:%d", chat.Id)
+	token := uuid.NewString()
+	if locked, err := cache.AcquireLock(lockKey, token, 30*time.Second); err != nil || !locked {
+		return
+	}
+	defer func() {
+		if err := cache.ReleaseLock(lockKey, token); err != nil {
+			log.Debugf("[Antispam] Failed to release trip lock of %d: %v", chat.Id, err)
 		}
+	}()
+
+	// the settings may have changed while another instance held the lock
+	settings = db.GetAntispamSettings(chat.Id)
+	if settings.ActiveUntil != nil && time.Now().Before(*settings.ActiveUntil) {
+		return
 	}
-	_asInfo.Levels = newLevels
-	antispamModule.antiSpam[chatId] = _asInfo
-	return spammed
-}
-
-// spamCheck performs spam detection for a specific chat.
-// Checks against a default threshold of 18 messages per second.
-func (moduleStruct) spamCheck(chatId int64) bool {
-	// if sql.IsUserSudo(chatId) {
-	//	return false
-	// }
-	curr := time.Duration(time.Now().UnixNano())
-	return antispamModule.checkSpammed(chatId, []antiSpamLevel{
-		{
-			CurrTime: curr,
-			Limit:    18,
-			Expiry:   time.Second,
-		},
+
+	tr := i18n.MustNewTranslator(db.GetLanguage(&ext.Context{EffectiveChat: chat}))
+	until := time.Now().Add(time.Duration(settings.ActionDuration) * time.Second)
+	var text string
+	switch settings.Action {
+	case db.AntispamActionSlowmode:
+		if err := db.SetAntispamActive(chat.Id, &until, ""); err != nil {
+			return
+		}
+		text, _ = tr.GetString("antispam_slowmode_started", i18n.TranslationParams{
+			"duration": formatWarnTime(tr, settings.ActionDuration),
+			"delay":    strconv.Itoa(int(antispamSlowModeDelay.Seconds())),
+		})
+	case db.AntispamActionRestrict:
+		// night mode holds the permissions to restore in the morning, so a second
+		// lockdown on top of it would restore the night permissions instead
+		if db.GetNightMode(chat.Id).Active {
+			log.Debugf("[Antispam] Night mode is active in %d, not restricting", chat.Id)
+			return
+		}
+		if err := m.restrictAntispamChat(b, chat.Id, until); err != nil {
+			log.Warnf("[Antispam] Failed to restrict chat %d: %v", chat.Id, err)
+			return
+		}
+		text, _ = tr.GetString("antispam_restrict_started", i18n.TranslationParams{
+			"duration": formatWarnTime(tr, settings.ActionDuration),
+		})
+	default:
+		return
+	}
+
+	if _, err := b.SendMessage(chat.Id, text, helpers.Shtml()); err != nil {
+		log.Debugf("[Antispam] Failed to announce action in %d: %v", chat.Id, err)
+	}
+}
+
+// antispamUserTripped handles a member going over a per-user level. Unlike the chat levels,
+// the slowmode and restrict actions only mute that member for the action duration.
+func (moduleStruct) antispamUserTripped(b *gotgbot.Bot, ctx *ext.Context, settings *db.AntispamSettings) {
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	// channels posting in the chat can't be muted
+	if settings.Action == db.AntispamActionDrop || user == nil {
+		return
+	}
+	if !chat_status.CanBotRestrict(b, ctx, chat, true) {
+		return
+	}
+
+	if err := applyPunishment(b, chat, user.Id, "tmute", settings.ActionDuration); err != nil {
+		log.Debugf("[Antispam] Failed to mute %d in %d: %v", user.Id, chat.Id, err)
+		return
+	}
+
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	text, _ := tr.GetString("antispam_user_muted", i18n.TranslationParams{
+		"user":     helpers.MentionHtml(user.Id, user.FirstName),
+		"duration": formatWarnTime(tr, settings.ActionDuration),
 	})
+	if _, err := b.SendMessage(chat.Id, text, helpers.Shtml()); err != nil {
+		log.Debugf("[Antispam] Failed to announce mute in %d: %v", chat.Id, err)
+	}
+}
+
+// restrictAntispamChat saves the current permissions of a chat, takes all of them away
+// and schedules their restoration at until.
+func (moduleStruct) restrictAntispamChat(b *gotgbot.Bot, chatId int64, until time.Time) error {
+	chat, err := b.GetChat(chatId, nil)
+	if err != nil {
+		return err
+	}
+	saved := gotgbot.ChatPermissions{}
+	if chat.Permissions != nil {
+		saved = *chat.Permissions
+	}
+	savedJSON, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.SetChatPermissions(chatId, gotgbot.ChatPermissions{},
+		&gotgbot.SetChatPermissionsOpts{UseIndependentChatPermissions: true})
+	if err != nil {
+		return err
+	}
+
+	if err = db.SetAntispamActive(chatId, &until, string(savedJSON)); err != nil {
+		return err
+	}
+	return scheduler.Enqueue(antispamRestoreJob, fmt.Sprint(chatId), antispamPayload{ChatID: chatId}, until)
+}
+
+// antispamRestrictPending reports whether the restrict action holds the permissions of a chat,
+// and until when. The time is in the past when the restore job hasn't succeeded yet.
+func antispamRestrictPending(chatId int64) (time.Time, bool) {
+	settings := db.GetAntispamSettings(chatId)
+	if settings.ActiveUntil == nil || settings.SavedPermissions == "" {
+		return time.Time{}, false
+	}
+	return *settings.ActiveUntil, true
+}
+
+// runAntispamRestoreJob restores the permissions a chat had before the restrict action started.
+// Failures are retried, since the chat stays restricted until this succeeds.
+func runAntispamRestoreJob(b *gotgbot.Bot, job *db.ScheduledJob) error {
+	var payload antispamPayload
+	if err := scheduler.DecodePayload(job, &payload); err != nil {
+		// a malformed payload can't succeed on retry
+		log.Errorf("[Antispam] Invalid payload for job %d: %v", job.ID, err)
+		return nil
+	}
+
+	settings := db.GetAntispamSettings(payload.ChatID)
+	if settings.ActiveUntil == nil || settings.SavedPermissions == "" {
+		return nil
+	}
+
+	var saved gotgbot.ChatPermissions
+	if err := json.Unmarshal([]byte(settings.SavedPermissions), &saved); err != nil {
+		log.Errorf("[Antispam] Invalid saved permissions of chat %d: %v", payload.ChatID, err)
+		return db.SetAntispamActive(payload.ChatID, nil, "")
+	}
+
+	_, err := b.SetChatPermissions(payload.ChatID, saved,
+		&gotgbot.SetChatPermissionsOpts{UseIndependentChatPermissions: true})
+	if err != nil {
+		return err
+	}
+	if err = db.SetAntispamActive(payload.ChatID, nil, ""); err != nil {
+		return err
+	}
+
+	tr := i18n.MustNewTranslator(db.GetLanguage(&ext.Context{EffectiveChat: &gotgbot.Chat{Id: payload.ChatID}}))
+	text, _ := tr.GetString("antispam_restrict_lifted")
+	if _, err = b.SendMessage(payload.ChatID, text, helpers.Shtml()); err != nil {
+		log.Debugf("[Antispam] Failed to announce lifted restriction in %d: %v", payload.ChatID, err)
+	}
+	return nil
+}
+
+// parseAntispamWindow parses the window of an antispam level, written as seconds
+// or with an s, m or h unit.
+func parseAntispamWindow(s string) (int, bool) {
+	unit := 1
+	switch {
+	case strings.HasSuffix(s, "s"):
+		s = strings.TrimSuffix(s, "s")
+	case strings.HasSuffix(s, "m"):
+		s, unit = strings.TrimSuffix(s, "m"), 60
+	case strings.HasSuffix(s, "h"):
+		s, unit = strings.TrimSuffix(s, "h"), 60*60
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 || n*unit > maxAntispamWindowSeconds {
+		return 0, false
+	}
+	return n * unit, true
+}
+
+// formatAntispamWindow formats the window of an antispam level using the largest unit that divides it evenly.
+func formatAntispamWindow(seconds int) string {
+	switch {
+	case seconds%(60*60) == 0:
+		return fmt.Sprintf("%dh", seconds/(60*60))
+	case seconds%60 == 0:
+		return fmt.Sprintf("%dm", seconds/60)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+// antispamStatus renders the antispam levels, action and trip count of a chat.
+func antispamStatus(tr *i18n.Translator, chatId int64) string {
+	var sb strings.Builder
+	header, _ := tr.GetString("antispam_status_levels_header")
+	sb.WriteString(header)
+
+	levels := db.GetAntispamLevels(chatId)
+	hasChatLevel := false
+	for _, level := range levels {
+		if level.Scope == db.AntispamScopeChat {
+			hasChatLevel = true
+		}
+	}
+	if !hasChatLevel {
+		line, _ := tr.GetString("antispam_level_default", i18n.TranslationParams{
+			"limit":  strconv.Itoa(defaultAntispamLevel.Limit),
+			"window": formatAntispamWindow(int(defaultAntispamLevel.Expiry.Seconds())),
+		})
+		sb.WriteString("\n - " + line)
+	}
+	for _, level := range levels {
+		line, _ := tr.GetString("antispam_level_"+level.Scope, i18n.TranslationParams{
+			"limit":  strconv.Itoa(level.MessageLimit),
+			"window": formatAntispamWindow(level.Seconds),
+		})
+		sb.WriteString("\n - " + line)
+	}
+
+	settings := db.GetAntispamSettings(chatId)
+	duration := settings.ActionDuration
+	if settings.Action == db.AntispamActionDrop {
+		duration = 0
+	}
+	action, _ := tr.GetString("antispam_status_action", i18n.TranslationParams{
+		"action": punishmentDisplay(tr, settings.Action, duration),
+	})
+	sb.WriteString("\n\n" + action)
+
+	var trips string
+	if settings.LastTrippedAt == nil {
+		trips, _ = tr.GetString("antispam_status_never_tripped")
+	} else {
+		trips, _ = tr.GetString("antispam_status_trips", i18n.TranslationParams{
+			"count": strconv.FormatInt(settings.Trips, 10),
+			"time":  settings.LastTrippedAt.UTC().Format(displayTimeLayout),
+		})
+	}
+	sb.WriteString("\n" + trips)
+
+	if settings.ActiveUntil != nil && time.Now().Before(*settings.ActiveUntil) {
+		active, _ := tr.GetString("antispam_status_active_"+settings.Action, i18n.TranslationParams{
+			"time": settings.ActiveUntil.UTC().Format(displayTimeLayout),
+		})
+		sb.WriteString("\n\n" + active)
+	}
+	return sb.String()
+}
+
+// antispam handles the /antispam command to view and change the antispam settings of a chat.
+//
+//	/antispam                              show the current settings
+//	/antispam <chat|user> <count> <window> allow at most count messages per window
+//	/antispam rm <chat|user> <window>      remove a level
+//	/antispam reset                        go back to the default level
+//	/antispam action <drop|slowmode|restrict> [time]
+func (m moduleStruct) antispam(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	var (
+		text    string
+		err     error
+		updated bool
+	)
+	option := ""
+	if len(args) > 0 {
+		option = strings.ToLower(args[0])
+	}
+
+	switch option {
+	case "":
+		text = antispamStatus(tr, chat.Id)
+	case db.AntispamScopeChat, db.AntispamScopeUser:
+		if len(args) != 3 {
+			text, _ = tr.GetString("antispam_usage")
+			break
+		}
+		limit, convErr := strconv.Atoi(args[1])
+		seconds, ok := parseAntispamWindow(strings.ToLower(args[2]))
+		if convErr != nil || limit < 1 || limit > maxAntispamLimit || !ok {
+			text, _ = tr.GetString("antispam_invalid_level")
+			break
+		}
+		err = db.SetAntispamLevel(chat.Id, option, seconds, limit)
+		updated = true
+	case "rm", "remove":
+		if len(args) != 3 {
+			text, _ = tr.GetString("antispam_usage")
+			break
+		}
+		scope := strings.ToLower(args[1])
+		if scope != db.AntispamScopeChat && scope != db.AntispamScopeUser {
+			text, _ = tr.GetString("antispam_usage")
+			break
+		}
+		seconds, ok := parseAntispamWindow(strings.ToLower(args[2]))
+		if !ok || !db.RemoveAntispamLevel(chat.Id, scope, seconds) {
+			text, _ = tr.GetString("antispam_level_not_found")
+			break
+		}
+		updated = true
+	case "reset":
+		err = db.ResetAntispamLevels(chat.Id)
+		updated = true
+	case "action":
+		if len(args) < 2 {
+			text, _ = tr.GetString("antispam_invalid_action")
+			break
+		}
+		action := strings.ToLower(args[1])
+		switch action {
+		case db.AntispamActionDrop:
+			err = db.UpdateAntispamSettings(chat.Id, map[string]any{"action": action})
+			updated = true
+		case db.AntispamActionSlowmode, db.AntispamActionRestrict:
+			if !chat_status.CanBotRestrict(b, ctx, nil, false) || !chat_status.CanBotDelete(b, ctx, nil, false) {
+				return ext.EndGroups
+			}
+			duration := int64(db.DefaultAntispamActionDuration)
+			if len(args) > 2 {
+				untilDate, _, _ := extraction.ExtractTime(b, ctx, args[2])
+				if untilDate == -1 {
+					return ext.EndGroups
+				}
+				duration = durationUntil(untilDate)
+			}
+			err = db.UpdateAntispamSettings(chat.Id, map[string]any{"action": action, "action_duration": duration})
+			updated = true
+		default:
+			text, _ = tr.GetString("antispam_invalid_action")
+		}
+	default:
+		text, _ = tr.GetString("antispam_usage")
+	}
+
+	if updated {
+		if err != nil {
+			text, _ = tr.GetString("antispam_action_failed")
+		} else {
+			logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+			text, _ = tr.GetString("antispam_updated")
+			text += "\n\n" + antispamStatus(tr, chat.Id)
+		}
+	}
+
+	_, err = msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
 }
 
 // LoadAntispam registers the antispam message handler with the dispatcher.
-// Sets up spam detection monitoring for all incoming messages.
+// Sets up spam detection monitoring for all incoming messages, along with the
+// command and job that manage the antispam settings of a chat.
 func LoadAntispam(dispatcher *ext.Dispatcher) {
+	HelpModule.AbleMap.Store(antispamModule.moduleName, true)
+
 	dispatcher.AddHandlerToGroup(
 		handlers.NewMessage(
 			message.All,
 			func(bot *gotgbot.Bot, ctx *ext.Context) error {
-				if antispamModule.spamCheck(ctx.EffectiveChat.Id) {
+				if antispamModule.spamCheck(bot, ctx) {
 					return ext.EndGroups
 				}
 				return ext.ContinueGroups
 			},
 		), -2,
 	)
+	dispatcher.AddHandler(handlers.NewCommand("antispam", antispamModule.antispam))
+
+	scheduler.RegisterHandler(antispamRestoreJob, runAntispamRestoreJob)
 }
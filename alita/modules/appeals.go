@@ -0,0 +1,459 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+	"github.com/eko/gocache/lib/v4/store"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/i18n"
+	"github.com/divideprojects/Alita_Robot/alita/utils/cache"
+	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+)
+
+var appealsModule = moduleStruct{
+	moduleName:   "Appeals",
+	handlerGroup: 11,
+}
+
+const (
+	// appealCooldown is how long a user must wait to appeal again after an appeal was denied.
+	appealCooldown = 24 * time.Hour
+	// appealStatementTimeout is how long the bot waits for the statement after /appeal.
+	appealStatementTimeout = 10 * time.Minute
+	// appealInfoTimeout is how long the bot waits for extra information an admin asked for.
+	appealInfoTimeout = 24 * time.Hour
+	// maxAppealStatementLength caps the length of a single appeal statement.
+	maxAppealStatementLength = 1000
+)
+
+// appealDraft is what the bot is waiting for in the PM of a banned user.
+// AppealID is set when admins asked for more information on an existing appeal.
+type appealDraft struct {
+	ChatID   int64 `json:"chat_id"`
+	AppealID uint  `json:"appeal_id"`
+}
+
+// appealDraftCacheKey returns the cache key of the appeal a user is writing in PM.
+func appealDraftCacheKey(userId int64) string {
+	return fmt.Sprintf("alita:appeal_draft:%d", userId)
+}
+
+// setAppealDraft remembers that the next PM message of a user belongs to an appeal.
+func setAppealDraft(userId int64, draft appealDraft, ttl time.Duration) {
+	err := cache.Marshal.Set(cache.Context, appealDraftCacheKey(userId), draft, store.WithExpiration(ttl))
+	if err != nil {
+		log.Errorf("[Appeals] Failed to store appeal draft of %d: %v", userId, err)
+	}
+}
+
+// popAppealDraft returns and forgets the appeal a user is writing, if any.
+func popAppealDraft(userId int64) (appealDraft, bool) {
+	var draft appealDraft
+	if _, err := cache.Marshal.Get(cache.Context, appealDraftCacheKey(userId), &draft); err != nil || draft.ChatID == 0 {
+		return draft, false
+	}
+	_ = cache.Marshal.Delete(cache.Context, appealDraftCacheKey(userId))
+	return draft, true
+}
+
+// truncateAppealStatement caps a statement at maxAppealStatementLength runes.
+func truncateAppealStatement(statement string) string {
+	runes := []rune(strings.TrimSpace(statement))
+	if len(runes) > maxAppealStatementLength {
+		runes = append(runes[:maxAppealStatementLength], '…')
+	}
+	return string(runes)
+}
+
+// appealsToggle handles the /appeals command to view or toggle whether banned users can appeal.
+func (m moduleStruct) appealsToggle(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	if len(args) == 0 {
+		if db.AreAppealsEnabled(chat.Id) {
+			text, _ = tr.GetString("appeals_currently_enabled")
+		} else {
+			text, _ = tr.GetString("appeals_currently_disabled")
+		}
+	} else {
+		var enabled bool
+		switch strings.ToLower(args[0]) {
+		case "on", "yes", "true":
+			enabled = true
+		case "off", "no", "false":
+			enabled = false
+		default:
+			text, _ = tr.GetString("appeals_invalid_option")
+		}
+		if text == "" && enabled && db.GetLogChannel(chat.Id).ChannelId == 0 {
+			// appeals are reviewed in the log channel, never in the chat itself
+			text, _ = tr.GetString("appeals_need_log_channel")
+		}
+		if text == "" {
+			if err := db.SetAppealsEnabled(chat.Id, enabled); err != nil {
+				text, _ = tr.GetString("appeals_action_failed")
+			} else {
+				logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+				if enabled {
+					text, _ = tr.GetString("appeals_enabled")
+				} else {
+					text, _ = tr.GetString("appeals_disabled")
+				}
+			}
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// appeal handles the /appeal command, used in PM by a banned user to appeal their ban in a chat.
+// The statement can follow the chat, otherwise the bot waits for it in the next message.
+func (moduleStruct) appeal(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequirePrivate(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	if len(args) == 0 {
+		text, _ = tr.GetString("appeals_usage")
+	} else if chat, err := chat_status.GetChat(b, args[0]); err != nil || (chat.Type != "group" && chat.Type != "supergroup") {
+		text, _ = tr.GetString("appeals_chat_not_found")
+	} else {
+		text = startAppeal(b, tr, user, chat, strings.Join(args[1:], " "))
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// startAppeal checks whether a user can appeal their ban in a chat and submits the statement,
+// or waits for it if none was given. Returns the reply for the user.
+func startAppeal(b *gotgbot.Bot, tr *i18n.Translator, user *gotgbot.User, chat *gotgbot.Chat, statement string) string {
+	chatName := html.EscapeString(chat.Title)
+
+	if !db.AreAppealsEnabled(chat.Id) {
+		text, _ := tr.GetString("appeals_not_enabled", i18n.TranslationParams{"chat": chatName})
+		return text
+	}
+
+	if db.GetLogChannel(chat.Id).ChannelId == 0 {
+		text, _ := tr.GetString("appeals_no_log_channel", i18n.TranslationParams{"chat": chatName})
+		return text
+	}
+
+	// temporary bans are lifted on their own, only permanent ones can be appealed
+	member, err := b.GetChatMember(chat.Id, user.Id, nil)
+	if err != nil || member.GetStatus() != "kicked" {
+		text, _ := tr.GetString("appeals_not_banned", i18n.TranslationParams{"chat": chatName})
+		return text
+	}
+	if untilDate := member.MergeChatMember().UntilDate; untilDate != 0 {
+		text, _ := tr.GetString("appeals_temporary_ban", i18n.TranslationParams{
+			"chat": chatName,
+			"wait": formatWarnTime(tr, untilDate-time.Now().Unix()),
+		})
+		return text
+	}
+
+	if open := db.GetOpenAppeal(chat.Id, user.Id); open != nil {
+		if open.Status == db.AppealStatusInfoRequested {
+			setAppealDraft(user.Id, appealDraft{ChatID: chat.Id, AppealID: open.ID}, appealInfoTimeout)
+			text, _ := tr.GetString("appeals_send_info", i18n.TranslationParams{"chat": chatName})
+			return text
+		}
+		text, _ := tr.GetString("appeals_already_pending", i18n.TranslationParams{"chat": chatName})
+		return text
+	}
+
+	if denied := db.GetLastDeniedAppeal(chat.Id, user.Id); denied != nil && denied.ReviewedAt != nil {
+		if wait := time.Until(denied.ReviewedAt.Add(appealCooldown)); wait > 0 {
+			text, _ := tr.GetString("appeals_cooldown", i18n.TranslationParams{
+				"chat": chatName,
+				"wait": formatWarnTime(tr, int64((wait+time.Hour-1)/time.Hour)*60*60),
+			})
+			return text
+		}
+	}
+
+	if strings.TrimSpace(statement) == "" {
+		setAppealDraft(user.Id, appealDraft{ChatID: chat.Id}, appealStatementTimeout)
+		text, _ := tr.GetString("appeals_send_statement", i18n.TranslationParams{"chat": chatName})
+		return text
+	}
+
+	return submitAppeal(b, tr, user, chat, statement)
+}
+
+// submitAppeal stores a new appeal and sends it to the admins of the chat for review.
+func submitAppeal(b *gotgbot.Bot, tr *i18n.Translator, user *gotgbot.User, chat *gotgbot.Chat, statement string) string {
+	appeal := &db.BanAppeal{
+		ChatId:    chat.Id,
+		UserId:    user.Id,
+		Statement: truncateAppealStatement(statement),
+	}
+	if err := db.CreateAppeal(appeal); err != nil {
+		text, _ := tr.GetString("appeals_action_failed")
+		return text
+	}
+
+	if err := sendAppealForReview(b, appeal, chat); err != nil {
+		text, _ := tr.GetString("appeals_delivery_failed")
+		return text
+	}
+
+	text, _ := tr.GetString("appeals_submitted", i18n.TranslationParams{"chat": html.EscapeString(chat.Title)})
+	return text
+}
+
+// appealStatementWatcher picks up the statement, or the extra information admins asked for,
+// that a banned user sends in PM after /appeal.
+func (moduleStruct) appealStatementWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	if user == nil || msg.Text == "" || strings.HasPrefix(msg.Text, "/") {
+		return ext.ContinueGroups
+	}
+
+	draft, ok := popAppealDraft(user.Id)
+	if !ok {
+		return ext.ContinueGroups
+	}
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	chatInfo, err := b.GetChat(draft.ChatID, nil)
+	if err != nil {
+		log.Debugf("[Appeals] Failed to get chat %d: %v", draft.ChatID, err)
+		return ext.ContinueGroups
+	}
+	chat := chatInfo.ToChat()
+
+	var text string
+	if draft.AppealID == 0 {
+		text = submitAppeal(b, tr, user, &chat, msg.Text)
+	} else if !db.AddAppealInfo(draft.AppealID, truncateAppealStatement(msg.Text)) {
+		text, _ = tr.GetString("appeals_action_failed")
+	} else if appeal := db.GetAppeal(draft.AppealID); appeal == nil || sendAppealForReview(b, appeal, &chat) != nil {
+		text, _ = tr.GetString("appeals_delivery_failed")
+	} else {
+		text, _ = tr.GetString("appeals_info_submitted", i18n.TranslationParams{"chat": html.EscapeString(chat.Title)})
+	}
+
+	_, err = msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// errAppealNoLogChannel is returned when an appeal can't be reviewed because its chat has no log channel.
+var errAppealNoLogChannel = errors.New("chat has no log channel")
+
+// sendAppealForReview posts an appeal with review buttons to the log channel of the chat.
+// Appeals are never posted in the chat itself, where every member could read them.
+func sendAppealForReview(b *gotgbot.Bot, appeal *db.BanAppeal, chat *gotgbot.Chat) error {
+	logChannel := db.GetLogChannel(chat.Id)
+	if logChannel.ChannelId == 0 {
+		return errAppealNoLogChannel
+	}
+
+	lang := "en"
+	if settings := db.GetChatSettings(chat.Id); settings != nil && settings.Language != "" {
+		lang = settings.Language
+	}
+	tr := i18n.MustNewTranslator(lang)
+
+	text, _ := tr.GetString("appeals_review", i18n.TranslationParams{
+		"id":        fmt.Sprint(appeal.ID),
+		"chat":      html.EscapeString(chat.Title),
+		"user":      mentionUserById(appeal.UserId),
+		"userid":    fmt.Sprint(appeal.UserId),
+		"statement": html.EscapeString(appeal.Statement),
+	})
+	unbanText, _ := tr.GetString("appeals_button_unban")
+	denyText, _ := tr.GetString("appeals_button_deny")
+	infoText, _ := tr.GetString("appeals_button_info")
+
+	_, err := b.SendMessage(logChannel.ChannelId, text, &gotgbot.SendMessageOpts{
+		ParseMode: helpers.HTML,
+		LinkPreviewOptions: &gotgbot.LinkPreviewOptions{
+			IsDisabled: true,
+		},
+		ReplyMarkup: gotgbot.InlineKeyboardMarkup{
+			InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
+				{
+					{Text: unbanText, CallbackData: fmt.Sprintf("appeal.unban.%d", appeal.ID)},
+					{Text: denyText, CallbackData: fmt.Sprintf("appeal.deny.%d", appeal.ID)},
+				},
+				{
+					{Text: infoText, CallbackData: fmt.Sprintf("appeal.info.%d", appeal.ID)},
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("[Appeals] Failed to send appeal %d for review: %v", appeal.ID, err)
+	}
+	return err
+}
+
+// appealButtonHandler handles the Unban, Deny and Ask-more buttons of an appeal.
+// Only admins of the appealed chat who can restrict members may review appeals.
+func (moduleStruct) appealButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
+	query := ctx.CallbackQuery
+	user := query.From
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	args := strings.Split(query.Data, ".")
+	if len(args) != 3 {
+		return ext.EndGroups
+	}
+	action := args[1]
+	id, err := strconv.ParseUint(args[2], 10, 32)
+	if err != nil {
+		return ext.EndGroups
+	}
+
+	appeal := db.GetAppeal(uint(id))
+	if appeal == nil {
+		text, _ := tr.GetString("appeals_not_found")
+		_, err := query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+		return err
+	}
+
+	chatInfo, err := b.GetChat(appeal.ChatId, nil)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	chat := chatInfo.ToChat()
+
+	if !chat_status.CanUserRestrict(b, ctx, &chat, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var status, resultKey, userKey string
+	switch action {
+	case "unban":
+		status, resultKey, userKey = db.AppealStatusApproved, "appeals_result_unbanned", "appeals_user_unbanned"
+	case "deny":
+		status, resultKey, userKey = db.AppealStatusDenied, "appeals_result_denied", "appeals_user_denied"
+	case "info":
+		status, resultKey, userKey = db.AppealStatusInfoRequested, "appeals_result_info_requested", "appeals_user_info_requested"
+	default:
+		return ext.EndGroups
+	}
+
+	if appeal.Status != db.AppealStatusPending {
+		text, _ := tr.GetString("appeals_already_reviewed")
+		_, err := query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+		return err
+	}
+
+	// claim the appeal first, so only one admin acts on it when several press a button at once
+	if !db.ReviewAppeal(appeal.ID, status, user.Id) {
+		text, _ := tr.GetString("appeals_already_reviewed")
+		_, err := query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+		return err
+	}
+
+	if action == "unban" {
+		// record the unban in the appealed chat rather than where the button was pressed
+		ctx.EffectiveChat = &chat
+		err = unbanMember(b, ctx, &chat, appeal.UserId, fmt.Sprintf("appeal #%d", appeal.ID))
+		if err != nil {
+			log.Errorf("[Appeals] Failed to unban %d in %d: %v", appeal.UserId, chat.Id, err)
+			db.ReopenAppeal(appeal.ID, status)
+			text, _ := tr.GetString("appeals_unban_failed")
+			_, err := query.Answer(b, &gotgbot.AnswerCallbackQueryOpts{Text: text})
+			return err
+		}
+	}
+
+	// tell the user in their own language
+	userTr := i18n.MustNewTranslator(db.GetLanguage(&ext.Context{
+		EffectiveChat:   &gotgbot.Chat{Id: appeal.UserId, Type: "private"},
+		EffectiveSender: &gotgbot.Sender{User: &gotgbot.User{Id: appeal.UserId}},
+	}))
+	userText, _ := userTr.GetString(userKey, i18n.TranslationParams{
+		"chat": html.EscapeString(chat.Title),
+		"wait": formatWarnTime(userTr, int64(appealCooldown/time.Second)),
+	})
+	if _, dmErr := b.SendMessage(appeal.UserId, userText, helpers.Shtml()); dmErr != nil {
+		log.Debugf("[Appeals] Failed to message %d about appeal %d: %v", appeal.UserId, appeal.ID, dmErr)
+	}
+	if action == "info" {
+		setAppealDraft(appeal.UserId, appealDraft{ChatID: chat.Id, AppealID: appeal.ID}, appealInfoTimeout)
+	}
+
+	result, _ := tr.GetString(resultKey, i18n.TranslationParams{
+		"admin": helpers.MentionHtml(user.Id, user.FirstName),
+	})
+	if reviewMsg := ctx.EffectiveMessage; reviewMsg != nil {
+		_, _, editErr := reviewMsg.EditText(b, reviewMsg.OriginalHTML()+result, &gotgbot.EditMessageTextOpts{
+			ParseMode: helpers.HTML,
+			LinkPreviewOptions: &gotgbot.LinkPreviewOptions{
+				IsDisabled: true,
+			},
+		})
+		if editErr != nil {
+			log.Debugf("[Appeals] Failed to update review message of appeal %d: %v", appeal.ID, editErr)
+		}
+	}
+
+	_, err = query.Answer(b, nil)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// LoadAppeals registers the ban appeal commands, buttons and PM statement watcher.
+func LoadAppeals(dispatcher *ext.Dispatcher) {
+	HelpModule.AbleMap.Store(appealsModule.moduleName, true)
+
+	dispatcher.AddHandler(handlers.NewCommand("appeals", appealsModule.appealsToggle))
+	dispatcher.AddHandler(handlers.NewCommand("appeal", appealsModule.appeal))
+	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("appeal."), appealsModule.appealButtonHandler))
+	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.Private, appealsModule.appealStatementWatcher), appealsModule.handlerGroup)
+}
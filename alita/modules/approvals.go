@@ -0,0 +1,238 @@
+package modules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/i18n"
+	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
+	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+)
+
+var approvalsModule = moduleStruct{moduleName: "Approvals"}
+
+// approve handles the /approve command to exempt a member from blacklists, antiflood, locks and captcha.
+func (m moduleStruct) approve(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	userId := extraction.ExtractUser(b, ctx)
+	if userId == -1 {
+		return ext.EndGroups
+	}
+
+	var text string
+	switch {
+	case userId == 0:
+		text, _ = tr.GetString("approvals_no_user_specified")
+	case helpers.IsChannelID(userId):
+		text, _ = tr.GetString("approvals_user_is_channel")
+	case chat_status.IsUserAdmin(b, chat.Id, userId):
+		text, _ = tr.GetString("approvals_user_is_admin")
+	default:
+		alreadyApproved, err := db.ApproveUser(chat.Id, userId, user.Id)
+		switch {
+		case err != nil:
+			text, _ = tr.GetString("approvals_action_failed")
+		case alreadyApproved:
+			text, _ = tr.GetString("approvals_already_approved", i18n.TranslationParams{"user": mentionUserById(userId)})
+		default:
+			logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+			text, _ = tr.GetString("approvals_approved", i18n.TranslationParams{"user": mentionUserById(userId)})
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// unapprove handles the /unapprove command to make automated moderation act on a member again.
+func (m moduleStruct) unapprove(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	userId := extraction.ExtractUser(b, ctx)
+	if userId == -1 {
+		return ext.EndGroups
+	}
+
+	var text string
+	switch {
+	case userId == 0:
+		text, _ = tr.GetString("approvals_no_user_specified")
+	case !db.UnapproveUser(chat.Id, userId):
+		text, _ = tr.GetString("approvals_not_approved", i18n.TranslationParams{"user": mentionUserById(userId)})
+	default:
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+		text, _ = tr.GetString("approvals_unapproved", i18n.TranslationParams{"user": mentionUserById(userId)})
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// approved handles the /approved command to list the approved members of a chat.
+func (moduleStruct) approved(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	approvedUsers := db.GetApprovedUsers(chat.Id)
+	if len(approvedUsers) == 0 {
+		text, _ = tr.GetString("approvals_none")
+	} else {
+		text, _ = tr.GetString("approvals_list_header")
+		var sb strings.Builder
+		for _, approvedUser := range approvedUsers {
+			line, _ := tr.GetString("approvals_list_entry", i18n.TranslationParams{
+				"user": mentionUserById(approvedUser.UserId),
+				"id":   fmt.Sprint(approvedUser.UserId),
+			})
+			sb.WriteString(line)
+		}
+		text += sb.String()
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// unapproveAll handles the /unapproveall command, asking the chat owner to confirm
+// removing every approved member.
+func (moduleStruct) unapproveAll(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserOwner(b, ctx, chat, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	text, _ := tr.GetString("approvals_unapprove_all_ask")
+	yesText, _ := tr.GetString("button_yes")
+	noText, _ := tr.GetString("button_no")
+	_, err := msg.Reply(b, text,
+		&gotgbot.SendMessageOpts{
+			ParseMode: helpers.HTML,
+			ReplyMarkup: gotgbot.InlineKeyboardMarkup{
+				InlineKeyboard: [][]gotgbot.InlineKeyboardButton{
+					{
+						{Text: yesText, CallbackData: "unapproveAll.yes"},
+						{Text: noText, CallbackData: "unapproveAll.no"},
+					},
+				},
+			},
+		},
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// unapproveAllButtonHandler processes the confirmation of /unapproveall.
+func (m moduleStruct) unapproveAllButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
+	query := ctx.CallbackQuery
+	user := query.From
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	// permission checks
+	if !chat_status.RequireUserOwner(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var helpText string
+	switch strings.TrimPrefix(query.Data, "unapproveAll.") {
+	case "yes":
+		count := db.UnapproveAllUsers(query.Message.GetChat().Id)
+		logSettingsChange(b, ctx, m.moduleName, "/unapproveall")
+		helpText, _ = tr.GetString("approvals_unapprove_all_done", i18n.TranslationParams{"count": fmt.Sprint(count)})
+	default:
+		helpText, _ = tr.GetString("approvals_unapprove_all_cancelled")
+	}
+
+	_, _, err := query.Message.EditText(b,
+		helpText,
+		&gotgbot.EditMessageTextOpts{
+			ParseMode: helpers.HTML,
+		},
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	_, err = query.Answer(b,
+		&gotgbot.AnswerCallbackQueryOpts{
+			Text: helpText,
+		},
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// LoadApprovals registers the approval commands with the dispatcher.
+func LoadApprovals(dispatcher *ext.Dispatcher) {
+	HelpModule.AbleMap.Store(approvalsModule.moduleName, true)
+
+	dispatcher.AddHandler(handlers.NewCommand("approve", approvalsModule.approve))
+	dispatcher.AddHandler(handlers.NewCommand("unapprove", approvalsModule.unapprove))
+	dispatcher.AddHandler(handlers.NewCommand("approved", approvalsModule.approved))
+	dispatcher.AddHandler(handlers.NewCommand("unapproveall", approvalsModule.unapproveAll))
+	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("unapproveAll."), approvalsModule.unapproveAllButtonHandler))
+}
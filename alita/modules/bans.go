@@ -1,11 +1,9 @@
 package modules
 
 import (
-	"context"
 	"fmt"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
@@ -118,46 +116,13 @@ func (m moduleStruct) dkick(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
-	_, err := chat.BanMember(b, userId, nil)
+	err := applyPunishment(b, chat, userId, "kick", 0)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
 
-	// Use non-blocking approach with goroutine for delayed unban with timeout
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.WithField("panic", r).Error("Panic in delayed unban goroutine")
-			}
-		}()
-
-		// Create context with timeout
-		timeoutCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		timer := time.NewTimer(2 * time.Second)
-		defer timer.Stop()
-
-		select {
-		case <-timer.C:
-			_, unbanErr := chat.UnbanMember(b, userId, nil)
-			if unbanErr != nil {
-				log.WithFields(log.Fields{
-					"chatId": chat.Id,
-					"userId": userId,
-					"error":  unbanErr,
-				}).Error("Failed to unban user after dkick")
-			}
-		case <-timeoutCtx.Done():
-			log.WithFields(log.Fields{
-				"chatId": chat.Id,
-				"userId": userId,
-			}).Warn("Dkick unban operation timed out")
-		}
-	}()
-
-	// Continue immediately without blocking
+	logModAction(b, ctx, db.ModActionKick, userId, reason, 0)
 
 	kickuser, err := b.GetChat(userId, nil)
 	if err != nil {
@@ -259,46 +224,14 @@ func (m moduleStruct) kick(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
-	_, err := chat.BanMember(b, userId, nil)
+	err := applyPunishment(b, chat, userId, "kick", 0)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
 
-	// Use non-blocking approach with goroutine for delayed unban with timeout
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.WithField("panic", r).Error("Panic in delayed unban goroutine")
-			}
-		}()
-
-		// Create context with timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		timer := time.NewTimer(2 * time.Second)
-		defer timer.Stop()
-
-		select {
-		case <-timer.C:
-			_, unbanErr := chat.UnbanMember(b, userId, nil)
-			if unbanErr != nil {
-				log.WithFields(log.Fields{
-					"chatId": chat.Id,
-					"userId": userId,
-					"error":  unbanErr,
-				}).Error("Failed to unban user after kick")
-			}
-		case <-ctx.Done():
-			log.WithFields(log.Fields{
-				"chatId": chat.Id,
-				"userId": userId,
-			}).Warn("Kick unban operation timed out")
-		}
-	}()
+	logModAction(b, ctx, db.ModActionKick, userId, reason, 0)
 
-	// Continue immediately without blocking
 	kickuser, err := b.GetChat(userId, nil)
 	if err != nil {
 		log.Error(err)
@@ -459,6 +392,8 @@ func (m moduleStruct) tBan(b *gotgbot.Bot, ctx *ext.Context) error {
 		return err
 	}
 
+	logModAction(b, ctx, db.ModActionBan, userId, reason, durationUntil(_time))
+
 	banUser, err := b.GetChat(userId, nil)
 	if err != nil {
 		log.Error(err)
@@ -571,6 +506,8 @@ func (m moduleStruct) ban(b *gotgbot.Bot, ctx *ext.Context) error {
 			return err
 		}
 
+		logModAction(b, ctx, db.ModActionBan, userId, reason, 0)
+
 		_, name, _ := extraction.GetUserInfo(userId)
 
 		baseStr, _ := tr.GetString(strings.ToLower(m.moduleName) + "_ban_normal_ban")
@@ -676,6 +613,8 @@ func (m moduleStruct) sBan(b *gotgbot.Bot, ctx *ext.Context) error {
 		return err
 	}
 
+	logModAction(b, ctx, db.ModActionBan, userId, "", 0)
+
 	_, err = msg.Delete(b, nil)
 	if err != nil {
 		log.Error(err)
@@ -775,6 +714,8 @@ func (m moduleStruct) dBan(b *gotgbot.Bot, ctx *ext.Context) error {
 		return err
 	}
 
+	logModAction(b, ctx, db.ModActionBan, userId, reason, 0)
+
 	banUser, err := b.GetChat(userId, nil)
 	if err != nil {
 		log.Error(err)
@@ -879,7 +820,7 @@ func (m moduleStruct) unban(b *gotgbot.Bot, ctx *ext.Context) error {
 			text, _ = tr.GetString("bans_anonymous_unban_reply_required")
 		}
 	} else {
-		_, err := chat.UnbanMember(b, userId, nil)
+		err := unbanMember(b, ctx, chat, userId, "")
 		if err != nil {
 			log.Error(err)
 			return err
@@ -904,6 +845,19 @@ func (m moduleStruct) unban(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// unbanMember lifts the ban of a user in a chat and records it in the audit log.
+// The sender of ctx is recorded as the admin who lifted the ban, and the entry is filed
+// under ctx.EffectiveChat, so callers acting from outside the chat must point it at chat.
+func unbanMember(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, userId int64, reason string) error {
+	_, err := chat.UnbanMember(b, userId, nil)
+	if err != nil {
+		return err
+	}
+
+	logModAction(b, ctx, db.ModActionUnban, userId, reason, 0)
+	return nil
+}
+
 /* Used to Restrict members from a chat
 Shows an inline keyboard menu which shows options to kick, ban and mute */
 
@@ -1021,48 +975,17 @@ func (moduleStruct) restrictButtonHandler(b *gotgbot.Bot, ctx *ext.Context) erro
 
 	switch action {
 	case "kick":
-		_, err := chat.BanMember(b, int64(userId), nil)
+		err := applyPunishment(b, chat, int64(userId), "kick", 0)
 		if err != nil {
 			log.Error(err)
 			return err
 		}
+		logModAction(b, ctx, db.ModActionKick, int64(userId), "", 0)
 		temp, _ := tr.GetString("bans_restrict_action_kick")
 		helpText = fmt.Sprintf(temp,
 			helpers.MentionHtml(user.Id, user.FirstName),
 			helpers.MentionHtml(int64(userId), actionUser.FirstName),
 		)
-		// Use non-blocking delayed unban for restrict kick action with timeout
-		go func() {
-			defer func() {
-				if r := recover(); r != nil {
-					log.WithField("panic", r).Error("Panic in restrict delayed unban goroutine")
-				}
-			}()
-
-			// Create context with timeout
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-
-			timer := time.NewTimer(3 * time.Second)
-			defer timer.Stop()
-
-			select {
-			case <-timer.C:
-				_, unbanErr := chat.UnbanMember(b, int64(userId), nil)
-				if unbanErr != nil {
-					log.WithFields(log.Fields{
-						"chatId": chat.Id,
-						"userId": userId,
-						"error":  unbanErr,
-					}).Error("Failed to unban user after restrict kick")
-				}
-			case <-ctx.Done():
-				log.WithFields(log.Fields{
-					"chatId": chat.Id,
-					"userId": userId,
-				}).Warn("Restrict kick unban operation timed out")
-			}
-		}()
 	case "mute":
 		_, err := chat.RestrictMember(b, int64(userId),
 			gotgbot.ChatPermissions{
@@ -1087,6 +1010,7 @@ func (moduleStruct) restrictButtonHandler(b *gotgbot.Bot, ctx *ext.Context) erro
 			log.Error(err)
 			return err
 		}
+		logModAction(b, ctx, db.ModActionMute, int64(userId), "", 0)
 		temp, _ := tr.GetString("bans_restrict_action_mute")
 		helpText = fmt.Sprintf(temp,
 			helpers.MentionHtml(user.Id, user.FirstName),
@@ -1098,6 +1022,7 @@ func (moduleStruct) restrictButtonHandler(b *gotgbot.Bot, ctx *ext.Context) erro
 			log.Error(err)
 			return err
 		}
+		logModAction(b, ctx, db.ModActionBan, int64(userId), "", 0)
 		temp, _ := tr.GetString("bans_restrict_action_ban")
 		helpText = fmt.Sprintf(temp,
 			helpers.MentionHtml(user.Id, user.FirstName),
@@ -1251,6 +1176,7 @@ func (moduleStruct) unrestrictButtonHandler(b *gotgbot.Bot, ctx *ext.Context) er
 			log.Error(err)
 			return err
 		}
+		logModAction(b, ctx, db.ModActionUnmute, int64(userId), "", 0)
 
 		temp, _ := tr.GetString("bans_unrestrict_action_unmute")
 		helpText = fmt.Sprintf(temp, helpers.MentionHtml(user.Id, user.FirstName))
@@ -1265,6 +1191,7 @@ func (moduleStruct) unrestrictButtonHandler(b *gotgbot.Bot, ctx *ext.Context) er
 			log.Error(err)
 			return err
 		}
+		logModAction(b, ctx, db.ModActionUnban, int64(userId), "", 0)
 
 		temp, _ := tr.GetString("bans_unrestrict_action_unban")
 		helpText = fmt.Sprintf(temp, helpers.MentionHtml(user.Id, user.FirstName))
@@ -1,17 +1,24 @@
 package modules
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
 	"slices"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
-	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/divideprojects/Alita_Robot/alita/db"
@@ -19,9 +26,9 @@ import (
 	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
 	"github.com/divideprojects/Alita_Robot/alita/utils/decorators/cmdDecorator"
 	"github.com/divideprojects/Alita_Robot/alita/utils/decorators/misc"
+	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
 	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
 	"github.com/divideprojects/Alita_Robot/alita/utils/keyword_matcher"
-	"github.com/divideprojects/Alita_Robot/alita/utils/string_handling"
 )
 
 var blacklistsModule = moduleStruct{
@@ -38,7 +45,8 @@ Connection - true, true
 Admin can add a blacklist to the chat
 */
 // addBlacklist handles the /addblacklist command to add blacklisted words to a group.
-// Admins can add words that will trigger automatic moderation actions.
+// Admins can add words that will trigger automatic moderation actions, either several
+// words using the chat default action or a single quoted trigger with its own action and reason.
 func (m moduleStruct) addBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
 	msg := ctx.EffectiveMessage
 	// connection status
@@ -53,8 +61,8 @@ func (m moduleStruct) addBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 
 	var (
-		alreadyBlacklisted, newBlacklist []string
-		text                             string
+		alreadyBlacklisted, newBlacklist, invalidTriggers, failedTriggers []string
+		text                                                              string
 	)
 
 	// Permission Checks
@@ -79,17 +87,30 @@ func (m moduleStruct) addBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
 			return err
 		}
 		return ext.EndGroups
+	} else if strings.HasPrefix(args[0], `"`) {
+		return m.addBlacklistTrigger(b, ctx, tr, args)
 	} else if len(args) >= 1 {
-		allBlWords := db.GetBlacklistSettings(chat.Id).Triggers()
+		blSettings := db.GetBlacklistSettings(chat.Id)
+
+		var triggers []keyword_matcher.Trigger
+		for _, blWord := range args {
+			trigger, err := keyword_matcher.ParseTrigger(blWord)
+			if err != nil {
+				invalidTriggers = append(invalidTriggers, fmt.Sprintf("<code>%s</code>: %s", html.EscapeString(blWord), html.EscapeString(err.Error())))
+				continue
+			}
+			triggers = append(triggers, trigger)
+		}
 
 		// For small lists, process sequentially
-		if len(args) <= 3 {
-			for _, blWord := range args {
-				if string_handling.FindInStringSlice(allBlWords, blWord) {
-					alreadyBlacklisted = append(alreadyBlacklisted, blWord)
+		if len(triggers) <= 3 {
+			for _, trigger := range triggers {
+				if blSettings.Get(trigger.Pattern, trigger.Mode) != nil {
+					alreadyBlacklisted = append(alreadyBlacklisted, html.EscapeString(trigger.String()))
+				} else if err := db.AddBlacklist(chat.Id, trigger.Pattern, trigger.Mode, "", 0, ""); err != nil {
+					failedTriggers = append(failedTriggers, fmt.Sprintf("<code>%s</code>", html.EscapeString(trigger.String())))
 				} else {
-					go db.AddBlacklist(chat.Id, blWord)
-					newBlacklist = append(newBlacklist, fmt.Sprintf("<code>%s</code>", blWord))
+					newBlacklist = append(newBlacklist, fmt.Sprintf("<code>%s</code>", html.EscapeString(trigger.String())))
 				}
 			}
 		} else {
@@ -97,22 +118,23 @@ func (m moduleStruct) addBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
 			type result struct {
 				word            string
 				isAlreadyListed bool
+				err             error
 			}
 
-			resultChan := make(chan result, len(args))
+			resultChan := make(chan result, len(triggers))
 			var wg sync.WaitGroup
 
-			for _, blWord := range args {
+			for _, trigger := range triggers {
 				wg.Add(1)
-				go func(word string) {
+				go func(trigger keyword_matcher.Trigger) {
 					defer wg.Done()
-					isListed := string_handling.FindInStringSlice(allBlWords, word)
-					resultChan <- result{word: word, isAlreadyListed: isListed}
-
-					if !isListed {
-						db.AddBlacklist(chat.Id, word)
+					res := result{word: html.EscapeString(trigger.String())}
+					res.isAlreadyListed = blSettings.Get(trigger.Pattern, trigger.Mode) != nil
+					if !res.isAlreadyListed {
+						res.err = db.AddBlacklist(chat.Id, trigger.Pattern, trigger.Mode, "", 0, "")
 					}
-				}(blWord)
+					resultChan <- res
+				}(trigger)
 			}
 
 			// Close channel after all goroutines complete
@@ -125,18 +147,29 @@ func (m moduleStruct) addBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
 			for res := range resultChan {
 				if res.isAlreadyListed {
 					alreadyBlacklisted = append(alreadyBlacklisted, res.word)
+				} else if res.err != nil {
+					failedTriggers = append(failedTriggers, fmt.Sprintf("<code>%s</code>", res.word))
 				} else {
 					newBlacklist = append(newBlacklist, fmt.Sprintf("<code>%s</code>", res.word))
 				}
 			}
 		}
 
+		if len(invalidTriggers) >= 1 {
+			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_blacklist_invalid_triggers")
+			text += temp + fmt.Sprintf("\n - %s\n\n", strings.Join(invalidTriggers, "\n - "))
+		}
 		if len(alreadyBlacklisted) >= 1 {
 			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_blacklist_already_blacklisted")
 			text += temp + fmt.Sprintf("\n - %s\n\n", strings.Join(alreadyBlacklisted, "\n - "))
 		}
+		if len(failedTriggers) >= 1 {
+			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_blacklist_add_failed")
+			text += temp + fmt.Sprintf("\n - %s\n\n", strings.Join(failedTriggers, "\n - "))
+		}
 		if len(newBlacklist) >= 1 {
 			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_blacklist_added_bl")
+			logSettingsChange(b, ctx, m.moduleName, msg.GetText())
 			text += temp + fmt.Sprintf("\n - %s\n\n", strings.Join(newBlacklist, "\n - "))
 		}
 
@@ -150,6 +183,194 @@ func (m moduleStruct) addBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// addBlacklistTrigger adds a single quoted trigger with its own action and reason,
+// as in /addblacklist "word" ban spam-link.
+// Without an action or reason, the trigger uses the chat default set with /blaction.
+func (m moduleStruct) addBlacklistTrigger(b *gotgbot.Bot, ctx *ext.Context, tr *i18n.Translator, args []string) error {
+	msg := ctx.EffectiveMessage
+
+	quoted, rest := extraction.ExtractQuotes(strings.Join(args, " "), true, false)
+	trigger, parseErr := keyword_matcher.ParseTrigger(quoted)
+
+	var text string
+	if strings.TrimSpace(quoted) == "" {
+		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_blacklist_give_bl_word")
+	} else if parseErr != nil {
+		temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_blacklist_invalid_triggers")
+		text = temp + fmt.Sprintf("\n - <code>%s</code>: %s", html.EscapeString(quoted), html.EscapeString(parseErr.Error()))
+	} else {
+		return m.addTriggerWithAction(b, ctx, tr, trigger, strings.Fields(rest))
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// addTriggerWithAction adds a trigger along with the action and reason read from args,
+// replying with the result.
+func (m moduleStruct) addTriggerWithAction(b *gotgbot.Bot, ctx *ext.Context, tr *i18n.Translator, trigger keyword_matcher.Trigger, args []string) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+	var text string
+
+	if db.GetBlacklistSettings(chat.Id).Get(trigger.Pattern, trigger.Mode) != nil {
+		temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_blacklist_already_blacklisted")
+		text = temp + fmt.Sprintf("\n - <code>%s</code>", html.EscapeString(trigger.String()))
+	} else {
+		action, duration, reason, replyText, ok := m.extractBlacklistAction(b, ctx, tr, args)
+		if !ok {
+			if replyText == "" {
+				return ext.EndGroups
+			}
+			text = replyText
+		} else if err := db.AddBlacklist(chat.Id, trigger.Pattern, trigger.Mode, action, duration, reason); err != nil {
+			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_blacklist_add_failed")
+			text = temp + fmt.Sprintf("\n - <code>%s</code>", html.EscapeString(trigger.String()))
+		} else {
+			logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_blacklist_added_trigger", i18n.TranslationParams{
+				"trigger": html.EscapeString(trigger.String()),
+				"action":  m.blacklistActionText(tr, action, duration),
+				"reason":  m.blacklistReasonText(tr, reason),
+			})
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+/*
+	Used to blacklist a sticker or a whole sticker pack in group!
+
+Connection - true, true
+Admin can blacklist stickers in the chat
+*/
+// blacklistSticker handles the /blsticker command to blacklist sticker packs or single stickers.
+// Replying to a sticker blacklists its pack, or only that sticker with the sticker option;
+// without a reply, a pack is given by its name or link. The action and reason follow like in /addblacklist.
+func (m moduleStruct) blacklistSticker(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	// Permission Checks
+	if !chat_status.IsUserAdmin(b, chat.Id, user.Id) {
+		return ext.EndGroups
+	}
+	if !chat_status.IsBotAdmin(b, ctx, chat) {
+		return ext.EndGroups
+	}
+	if !chat_status.CanUserRestrict(b, ctx, chat, user.Id, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.CanBotRestrict(b, ctx, chat, false) {
+		return ext.EndGroups
+	}
+
+	var trigger keyword_matcher.Trigger
+	if reply := msg.ReplyToMessage; reply != nil && reply.Sticker != nil {
+		kind := "pack"
+		if len(args) > 0 && (strings.EqualFold(args[0], "pack") || strings.EqualFold(args[0], "sticker")) {
+			kind = strings.ToLower(args[0])
+			args = args[1:]
+		}
+		if kind == "sticker" || reply.Sticker.SetName == "" {
+			trigger = keyword_matcher.Trigger{Pattern: reply.Sticker.FileUniqueId, Mode: keyword_matcher.ModeSticker}
+		} else {
+			trigger = keyword_matcher.Trigger{Pattern: strings.ToLower(reply.Sticker.SetName), Mode: keyword_matcher.ModeStickerSet}
+		}
+	} else if setName := stickerSetName(args); setName != "" {
+		trigger = keyword_matcher.Trigger{Pattern: strings.ToLower(setName), Mode: keyword_matcher.ModeStickerSet}
+		args = args[1:]
+	} else {
+		text, _ := tr.GetString(strings.ToLower(m.moduleName) + "_blsticker_give_sticker")
+		_, err := msg.Reply(b, text, helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	return m.addTriggerWithAction(b, ctx, tr, trigger, args)
+}
+
+// stickerSetName reads the name of a sticker pack from the first argument,
+// given either as is or as a t.me/addstickers link.
+func stickerSetName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	setName := strings.TrimPrefix(strings.TrimPrefix(args[0], "https://"), "t.me/addstickers/")
+	return strings.Trim(setName, "/")
+}
+
+// extractBlacklistAction reads an optional blacklist action from the command arguments,
+// along with the duration of the timed tmute and tban actions, which uses the same syntax as /tban.
+// The arguments after the action are joined into the reason; without an action, all of them are.
+// If the action can't be used, ok is false and replyText holds the text to reply with,
+// which is empty when a reply has already been sent.
+func (m moduleStruct) extractBlacklistAction(b *gotgbot.Bot, ctx *ext.Context, tr *i18n.Translator, args []string) (action string, duration int64, reason, replyText string, ok bool) {
+	if len(args) == 0 {
+		return "", 0, "", "", true
+	}
+
+	selectedAction := strings.ToLower(args[0])
+	switch selectedAction {
+	case "mute", "kick", "warn", "ban", "none":
+		return selectedAction, 0, strings.Join(args[1:], " "), "", true
+	case "tmute", "tban":
+		if len(args) < 2 {
+			replyText, _ = tr.GetString(strings.ToLower(m.moduleName)+"_set_bl_action_time_required", i18n.TranslationParams{"action": selectedAction})
+			return "", 0, "", replyText, false
+		}
+		untilDate, _, _ := extraction.ExtractTime(b, ctx, args[1])
+		if untilDate == -1 {
+			return "", 0, "", "", false
+		}
+		return selectedAction, durationUntil(untilDate), strings.Join(args[2:], " "), "", true
+	default:
+		return "", 0, strings.Join(args, " "), "", true
+	}
+}
+
+// blacklistActionText describes the action of a trigger for HTML replies,
+// naming the chat default when the trigger has no action of its own.
+func (m moduleStruct) blacklistActionText(tr *i18n.Translator, action string, duration int64) string {
+	if action == "" {
+		text, _ := tr.GetString(strings.ToLower(m.moduleName) + "_chat_default")
+		return text
+	}
+	return punishmentDisplay(tr, action, duration)
+}
+
+// blacklistReasonText describes the reason of a trigger for HTML replies,
+// naming the chat default when the trigger has no reason of its own.
+func (m moduleStruct) blacklistReasonText(tr *i18n.Translator, reason string) string {
+	if reason == "" {
+		text, _ := tr.GetString(strings.ToLower(m.moduleName) + "_chat_default")
+		return text
+	}
+	return html.EscapeString(reason)
+}
+
 /*
 	Used to remove a blacklist from group!
 
@@ -196,11 +417,21 @@ func (m moduleStruct) removeBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
 		}
 		return ext.EndGroups
 	} else {
-		allBlWords := db.GetBlacklistSettings(chat.Id).Triggers()
-		for _, blWord := range args {
-			if string_handling.FindInStringSlice(allBlWords, blWord) {
-				removedBlacklists = append(removedBlacklists, blWord)
-				go db.RemoveBlacklist(chat.Id, blWord)
+		blSettings := db.GetBlacklistSettings(chat.Id)
+		// a quoted trigger can contain spaces, like the ones added with their own action
+		blWords := args
+		if strings.HasPrefix(args[0], `"`) {
+			quoted, _ := extraction.ExtractQuotes(strings.Join(args, " "), true, false)
+			blWords = []string{quoted}
+		}
+		for _, blWord := range blWords {
+			trigger, err := keyword_matcher.ParseTrigger(blWord)
+			if err != nil {
+				continue
+			}
+			for _, match := range matchingBlacklists(blSettings, trigger) {
+				removedBlacklists = append(removedBlacklists, match.String())
+				go db.RemoveBlacklist(chat.Id, match.Pattern, match.Mode)
 			}
 		}
 		if len(removedBlacklists) <= 0 {
@@ -212,6 +443,7 @@ func (m moduleStruct) removeBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
 			}
 		} else {
 			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_unblacklist_removed_bl")
+			logSettingsChange(b, ctx, m.moduleName, msg.GetText())
 			_, err := msg.Reply(b, fmt.Sprintf(temp, strings.Join(removedBlacklists, ", ")), nil)
 			if err != nil {
 				log.Error(err)
@@ -222,6 +454,27 @@ func (m moduleStruct) removeBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// matchingBlacklists returns the blacklisted triggers removed by /rmblacklist for the given trigger.
+// A trigger that isn't blacklisted in its own mode falls back to the same pattern in any text
+// mode, so a plain word also removes triggers migrated to the substring mode.
+func matchingBlacklists(blSettings db.BlacklistSettingsSlice, trigger keyword_matcher.Trigger) []keyword_matcher.Trigger {
+	if blSettings.Get(trigger.Pattern, trigger.Mode) != nil {
+		return []keyword_matcher.Trigger{trigger}
+	}
+	if trigger.Mode != keyword_matcher.ModeWord {
+		return nil
+	}
+
+	var matches []keyword_matcher.Trigger
+	for _, bs := range blSettings {
+		match := keyword_matcher.Trigger{Pattern: bs.Word, Mode: bs.MatchMode}
+		if !match.IsSticker() && strings.EqualFold(bs.Word, trigger.Pattern) {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
 /*
 	Used to list all blacklists of a group!
 
@@ -256,21 +509,39 @@ func (m moduleStruct) listBlacklists(b *gotgbot.Bot, ctx *ext.Context) error {
 		replyMsgId = msg.MessageId
 	}
 
-	blSrc := db.GetBlacklistSettings(chat.Id)
-	slices.Sort(blSrc.Triggers())
+	blSrc := slices.Clone(db.GetBlacklistSettings(chat.Id))
+	slices.SortFunc(blSrc, func(a, b *db.BlacklistSettings) int {
+		return strings.Compare(a.Word, b.Word)
+	})
 	var sb strings.Builder
-	for _, i := range blSrc.Triggers() {
-		sb.WriteString(fmt.Sprintf("\n - <code>%s</code>", i))
+	for _, bs := range blSrc {
+		sb.WriteString(fmt.Sprintf("\n - <code>%s</code>: <b>%s</b>", html.EscapeString(blacklistTrigger(bs).String()), m.blacklistActionText(tr, bs.Action, bs.ActionDuration)))
+		if bs.Reason != "" {
+			sb.WriteString(" - " + html.EscapeString(bs.Reason))
+		}
 	}
 	blacklistsText += sb.String()
 
 	if blacklistsText != "" {
+		chatSettings := db.GetBlacklistChatSettings(chat.Id)
 		temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_ls_bl_list_bl")
-		blacklistsText = temp + blacklistsText
+		actionText, _ := tr.GetString(strings.ToLower(m.moduleName)+"_ls_bl_action", i18n.TranslationParams{
+			"action": punishmentDisplay(tr, chatSettings.Action, chatSettings.ActionDuration),
+		})
+		blacklistsText = temp + blacklistsText + "\n\n" + actionText
 	} else {
 		blacklistsText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_ls_bl_no_blacklisted")
 	}
 
+	if packs := db.GetBlacklistPackSubscriptions(chat.Id); len(packs) > 0 {
+		names := make([]string, 0, len(packs))
+		for _, pack := range packs {
+			names = append(names, fmt.Sprintf("<code>%s</code>", pack.Name))
+		}
+		packsText, _ := tr.GetString(strings.ToLower(m.moduleName)+"_ls_bl_packs", i18n.TranslationParams{"packs": strings.Join(names, ", ")})
+		blacklistsText += "\n" + packsText
+	}
+
 	_, err := msg.Reply(b,
 		blacklistsText,
 		&gotgbot.SendMessageOpts{
@@ -294,10 +565,11 @@ func (m moduleStruct) listBlacklists(b *gotgbot.Bot, ctx *ext.Context) error {
 
 # Connection - true, true
 
-Admin with restriction permission can set blacklist action in group out of - ick, ban, mute
+Admin with restriction permission can set blacklist action in group out of - kick, ban, mute, tban, tmute
 */
-// setBlacklistAction handles the /blaction command to configure blacklist punishment.
-// Sets the action (mute/kick/warn/ban/none) taken when blacklisted words are detected.
+// setBlacklistAction handles the /blaction command to configure the default blacklist punishment.
+// Sets the action (mute/tmute/kick/warn/ban/tban/none) and optional reason used for blacklisted words
+// without their own, with the timed actions taking a duration in the same format as /tban.
 func (m moduleStruct) setBlacklistAction(b *gotgbot.Bot, ctx *ext.Context) error {
 	msg := ctx.EffectiveMessage
 	// connection status
@@ -322,20 +594,24 @@ func (m moduleStruct) setBlacklistAction(b *gotgbot.Bot, ctx *ext.Context) error
 	}
 
 	if len(args) == 0 {
-		currAction := db.GetBlacklistSettings(chat.Id).Action()
+		chatSettings := db.GetBlacklistChatSettings(chat.Id)
 		temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_set_bl_action_current_mode")
-		rMsg = fmt.Sprintf(temp, currAction)
-	} else if len(args) == 1 {
-		action := strings.ToLower(args[0])
-		if string_handling.FindInStringSlice([]string{"mute", "kick", "warn", "ban", "none"}, action) {
-			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_set_bl_action_changed_mode")
-			rMsg = fmt.Sprintf(temp, action)
-			go db.SetBlacklistAction(chat.Id, action)
-		} else {
+		rMsg = fmt.Sprintf(temp, punishmentDisplay(tr, chatSettings.Action, chatSettings.ActionDuration))
+	} else {
+		action, duration, reason, replyText, ok := m.extractBlacklistAction(b, ctx, tr, args)
+		if !ok {
+			if replyText == "" {
+				return ext.EndGroups
+			}
+			rMsg = replyText
+		} else if action == "" {
 			rMsg, _ = tr.GetString(strings.ToLower(m.moduleName) + "_set_bl_action_choose_correct_option")
+		} else {
+			temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_set_bl_action_changed_mode")
+			rMsg = fmt.Sprintf(temp, punishmentDisplay(tr, action, duration))
+			go db.SetBlacklistAction(chat.Id, action, duration, reason)
+			logSettingsChange(b, ctx, m.moduleName, msg.GetText())
 		}
-	} else {
-		rMsg, _ = tr.GetString(strings.ToLower(m.moduleName) + "_set_bl_action_choose_correct_option")
 	}
 	_, err := msg.Reply(b, rMsg, helpers.Smarkdown())
 	if err != nil {
@@ -345,6 +621,59 @@ func (m moduleStruct) setBlacklistAction(b *gotgbot.Bot, ctx *ext.Context) error
 	return ext.EndGroups
 }
 
+/*
+	Used to set the text normalization of blacklists and filters in chat
+
+# Connection - true, true
+
+Admin with restriction permission can set the normalization out of - strict, basic, off
+*/
+// setBlacklistNormalization handles the /blnormalize command to configure text normalization.
+// Sets how far messages and triggers of blacklists and filters are normalized before matching,
+// to catch homoglyphs, zero-width characters, diacritics, leetspeak and split up words.
+func (m moduleStruct) setBlacklistNormalization(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	var rMsg string
+
+	// Permission Checks
+	if !chat_status.CanUserRestrict(b, ctx, chat, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	if len(args) == 0 {
+		rMsg, _ = tr.GetString(strings.ToLower(m.moduleName)+"_normalize_current", i18n.TranslationParams{
+			"level": db.GetBlacklistChatSettings(chat.Id).Normalization,
+		})
+	} else {
+		switch level := strings.ToLower(args[0]); level {
+		case keyword_matcher.NormalizeStrict, keyword_matcher.NormalizeBasic, keyword_matcher.NormalizeOff:
+			go db.SetBlacklistNormalization(chat.Id, level)
+			logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+			rMsg, _ = tr.GetString(strings.ToLower(m.moduleName)+"_normalize_changed", i18n.TranslationParams{"level": level})
+		default:
+			rMsg, _ = tr.GetString(strings.ToLower(m.moduleName) + "_normalize_choose_correct_option")
+		}
+	}
+
+	_, err := msg.Reply(b, rMsg, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
 /*
 	Used to remove all blacklists from a group
 
@@ -409,6 +738,7 @@ func (m moduleStruct) buttonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	switch creatorAction {
 	case "yes":
 		go db.RemoveAllBlacklist(query.Message.GetChat().Id)
+		logSettingsChange(b, ctx, m.moduleName, "/remallbl")
 		helpText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_rm_all_bl_button_handler_yes")
 	case "no":
 		helpText, _ = tr.GetString(strings.ToLower(m.moduleName) + "_rm_all_bl_button_handler_no")
@@ -438,13 +768,612 @@ func (m moduleStruct) buttonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// Blacklist export formats, shared through /exportblacklist and /importblacklist
+const (
+	blacklistExportVersion = 1
+	blacklistTextHeader    = "# alita blacklist v%d"
+	blacklistImportMaxSize = 1 << 20
+)
+
+var (
+	errBlacklistFileTooBig       = errors.New("blacklist file is too big")
+	errBlacklistFileInvalid      = errors.New("not a blacklist export")
+	errBlacklistVersionTooRecent = errors.New("blacklist export version is not supported")
+
+	// blacklistPackNameRegex matches the names a blacklist pack can be published under
+	blacklistPackNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{2,31}$`)
+)
+
+// blacklistExport is the JSON form of an exported blacklist.
+type blacklistExport struct {
+	Version  int                      `json:"version"`
+	Triggers []blacklistExportTrigger `json:"triggers"`
+}
+
+// blacklistExportTrigger is an exported trigger, written the way /addblacklist reads it.
+// An empty action or reason means the chat default of the chat importing it.
+type blacklistExportTrigger struct {
+	Trigger  string `json:"trigger"`
+	Action   string `json:"action,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// encodeBlacklist writes the triggers of a chat as JSON, or with the text format as a header
+// followed by one tab-separated line per trigger: trigger, action, duration and reason.
+// Triggers containing tabs or line breaks only survive the JSON format.
+func encodeBlacklist(tr *i18n.Translator, blSettings db.BlacklistSettingsSlice, format string) ([]byte, error) {
+	sorted := slices.Clone(blSettings)
+	slices.SortFunc(sorted, func(a, b *db.BlacklistSettings) int {
+		return strings.Compare(blacklistTrigger(a).String(), blacklistTrigger(b).String())
+	})
+
+	triggers := make([]blacklistExportTrigger, 0, len(sorted))
+	for _, bs := range sorted {
+		t := blacklistExportTrigger{Trigger: blacklistTrigger(bs).String(), Action: bs.Action, Reason: bs.Reason}
+		if bs.ActionDuration > 0 {
+			t.Duration = formatWarnTime(tr, bs.ActionDuration)
+		}
+		triggers = append(triggers, t)
+	}
+
+	if format == "text" {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf(blacklistTextHeader+"\n", blacklistExportVersion))
+		sb.WriteString("# trigger\taction\tduration\treason\n")
+		for _, t := range triggers {
+			line := strings.Join([]string{t.Trigger, t.Action, t.Duration, strings.Join(strings.Fields(t.Reason), " ")}, "\t")
+			sb.WriteString(strings.TrimRight(line, "\t") + "\n")
+		}
+		return []byte(sb.String()), nil
+	}
+	return json.MarshalIndent(blacklistExport{Version: blacklistExportVersion, Triggers: triggers}, "", "  ")
+}
+
+// decodeBlacklist reads a blacklist written by encodeBlacklist, telling the JSON and text formats
+// apart by their first character. Plain text without the header is read as one trigger per line.
+// Entries that can't be imported are returned separately as descriptions of what is wrong with them,
+// and later entries replace earlier ones with the same trigger.
+func decodeBlacklist(data []byte) (entries db.BlacklistSettingsSlice, invalid []string, err error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\ufeff")))
+	if !utf8.Valid(data) {
+		return nil, nil, errBlacklistFileInvalid
+	}
+
+	add := func(label string, t blacklistExportTrigger) {
+		bs, err := t.settings()
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %s", label, err))
+			return
+		}
+		if i := slices.IndexFunc(entries, func(e *db.BlacklistSettings) bool {
+			return e.Word == bs.Word && e.MatchMode == bs.MatchMode
+		}); i >= 0 {
+			entries[i] = bs
+			return
+		}
+		entries = append(entries, bs)
+	}
+
+	if bytes.HasPrefix(data, []byte("{")) {
+		var export blacklistExport
+		if err := json.Unmarshal(data, &export); err != nil || export.Version < 1 {
+			return nil, nil, errBlacklistFileInvalid
+		}
+		if export.Version > blacklistExportVersion {
+			return nil, nil, errBlacklistVersionTooRecent
+		}
+		for i, t := range export.Triggers {
+			add(fmt.Sprintf("#%d", i+1), t)
+		}
+		return entries, invalid, nil
+	}
+
+	for n, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		var version int
+		if n == 0 {
+			if _, err := fmt.Sscanf(line, blacklistTextHeader, &version); err == nil && version > blacklistExportVersion {
+				return nil, nil, errBlacklistVersionTooRecent
+			}
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := append(strings.SplitN(line, "\t", 4), "", "", "")
+		add(fmt.Sprintf("line %d", n+1), blacklistExportTrigger{Trigger: fields[0], Action: fields[1], Duration: fields[2], Reason: fields[3]})
+	}
+	return entries, invalid, nil
+}
+
+// settings validates an exported trigger and converts it into a blacklist setting.
+// Durations use the m/h/d/w units and, like /tban, must be shorter than a year.
+func (t blacklistExportTrigger) settings() (*db.BlacklistSettings, error) {
+	trigger, err := keyword_matcher.ParseTrigger(t.Trigger)
+	if err != nil {
+		return nil, err
+	}
+
+	action := strings.ToLower(strings.TrimSpace(t.Action))
+	var duration int64
+	switch action {
+	case "", "mute", "kick", "warn", "ban", "none":
+	case "tmute", "tban":
+		d, ok := parseScheduleDuration(strings.TrimSpace(t.Duration))
+		if !ok || d >= 365*24*time.Hour {
+			return nil, fmt.Errorf("invalid duration %q", t.Duration)
+		}
+		duration = int64(d.Seconds())
+	default:
+		return nil, fmt.Errorf("unknown action %q", t.Action)
+	}
+
+	return &db.BlacklistSettings{
+		Word:           trigger.Pattern,
+		MatchMode:      trigger.Mode,
+		Action:         action,
+		ActionDuration: duration,
+		Reason:         strings.TrimSpace(t.Reason),
+	}, nil
+}
+
+// blacklistImportSummary counts what importing a blacklist changes in a chat.
+type blacklistImportSummary struct {
+	added, updated, unchanged, removed int
+}
+
+// diffBlacklistImport compares the triggers to import with the current blacklist of a chat.
+// With replace, the current triggers missing from the import are counted as removed.
+func diffBlacklistImport(current, entries db.BlacklistSettingsSlice, replace bool) (s blacklistImportSummary) {
+	for _, e := range entries {
+		switch c := current.Get(e.Word, e.MatchMode); {
+		case c == nil:
+			s.added++
+		case c.Action == e.Action && c.ActionDuration == e.ActionDuration && c.Reason == e.Reason:
+			s.unchanged++
+		default:
+			s.updated++
+		}
+	}
+	if replace {
+		for _, c := range current {
+			if entries.Get(c.Word, c.MatchMode) == nil {
+				s.removed++
+			}
+		}
+	}
+	return
+}
+
+// downloadBlacklistFile downloads a document sent to import a blacklist, refusing files over blacklistImportMaxSize.
+func downloadBlacklistFile(b *gotgbot.Bot, doc *gotgbot.Document) ([]byte, error) {
+	if doc.FileSize > blacklistImportMaxSize {
+		return nil, errBlacklistFileTooBig
+	}
+	file, err := b.GetFile(doc.FileId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := httpClient.Get(file.URL(b, nil))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, blacklistImportMaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > blacklistImportMaxSize {
+		return nil, errBlacklistFileTooBig
+	}
+	return data, nil
+}
+
+/*
+	Used to export the blacklist of a group!
+
+Connection - true, true
+Admin can export the blacklist of the chat as a file
+*/
+// exportBlacklist handles the /exportblacklist command to export the blacklist as a file.
+// The file is JSON by default or plain text with the text option, and can be imported
+// into other chats with /importblacklist.
+func (m moduleStruct) exportBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	// Permission Checks
+	if !chat_status.IsUserAdmin(b, chat.Id, user.Id) {
+		return ext.EndGroups
+	}
+
+	format := "json"
+	if len(args) > 0 {
+		format = strings.ToLower(args[0])
+	}
+
+	var text string
+	blSettings := db.GetBlacklistSettings(chat.Id)
+	if format != "json" && format != "text" {
+		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_export_choose_correct_option")
+	} else if len(blSettings) == 0 {
+		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_ls_bl_no_blacklisted")
+	}
+	if text != "" {
+		_, err := msg.Reply(b, text, helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	data, err := encodeBlacklist(tr, blSettings, format)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	fileName := fmt.Sprintf("blacklist_%d.json", chat.Id)
+	if format == "text" {
+		fileName = fmt.Sprintf("blacklist_%d.txt", chat.Id)
+	}
+	caption, _ := tr.GetString(strings.ToLower(m.moduleName)+"_export_caption", i18n.TranslationParams{
+		"chat":  html.EscapeString(chat.Title),
+		"count": len(blSettings),
+	})
+
+	_, err = b.SendDocument(
+		msg.Chat.Id,
+		gotgbot.InputFileByReader(fileName, bytes.NewReader(data)),
+		&gotgbot.SendDocumentOpts{
+			Caption:   caption,
+			ParseMode: helpers.HTML,
+			ReplyParameters: &gotgbot.ReplyParameters{
+				MessageId:                msg.MessageId,
+				AllowSendingWithoutReply: true,
+			},
+		},
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+/*
+	Used to import a blacklist into a group!
+
+Connection - true, true
+Admin can import a blacklist file exported with /exportblacklist
+*/
+// importBlacklist handles the /importblacklist command, used in reply to an exported blacklist.
+// Triggers are merged into the blacklist, or replace it with the replace option. Only a summary
+// of the changes is shown until the command is repeated with the apply option.
+func (m moduleStruct) importBlacklist(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	// Permission Checks
+	if !chat_status.IsUserAdmin(b, chat.Id, user.Id) {
+		return ext.EndGroups
+	}
+	if !chat_status.IsBotAdmin(b, ctx, chat) {
+		return ext.EndGroups
+	}
+	if !chat_status.CanUserRestrict(b, ctx, chat, user.Id, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.CanBotRestrict(b, ctx, chat, false) {
+		return ext.EndGroups
+	}
+
+	mode, apply, validArgs := "merge", false, true
+	for _, arg := range args {
+		switch arg = strings.ToLower(arg); arg {
+		case "merge", "replace":
+			mode = arg
+		case "apply":
+			apply = true
+		default:
+			validArgs = false
+		}
+	}
+
+	var (
+		text    string
+		entries db.BlacklistSettingsSlice
+		invalid []string
+	)
+	reply := msg.ReplyToMessage
+	if !validArgs {
+		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_import_usage")
+	} else if reply == nil || reply.Document == nil {
+		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_import_reply_to_file")
+	} else {
+		data, err := downloadBlacklistFile(b, reply.Document)
+		if err == nil {
+			entries, invalid, err = decodeBlacklist(data)
+		}
+		switch {
+		case errors.Is(err, errBlacklistFileTooBig):
+			text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_import_file_too_big")
+		case errors.Is(err, errBlacklistFileInvalid):
+			text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_import_invalid_file")
+		case errors.Is(err, errBlacklistVersionTooRecent):
+			text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_import_unsupported_version")
+		case err != nil:
+			log.Error(err)
+			text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_import_download_failed")
+		case len(entries) == 0:
+			text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_import_no_triggers")
+		default:
+			summary := diffBlacklistImport(db.GetBlacklistSettings(chat.Id), entries, mode == "replace")
+			summaryText, _ := tr.GetString(strings.ToLower(m.moduleName)+"_import_summary", i18n.TranslationParams{
+				"added":     summary.added,
+				"updated":   summary.updated,
+				"unchanged": summary.unchanged,
+				"removed":   summary.removed,
+				"invalid":   len(invalid),
+			})
+			if !apply {
+				temp, _ := tr.GetString(strings.ToLower(m.moduleName)+"_import_preview", i18n.TranslationParams{"mode": mode})
+				hint, _ := tr.GetString(strings.ToLower(m.moduleName)+"_import_dry_run", i18n.TranslationParams{"command": "/importblacklist " + mode + " apply"})
+				text = temp + "\n" + summaryText + "\n\n" + hint
+			} else if err := db.ImportBlacklist(chat.Id, entries, mode == "replace"); err != nil {
+				text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_import_failed")
+			} else {
+				temp, _ := tr.GetString(strings.ToLower(m.moduleName)+"_import_applied", i18n.TranslationParams{"mode": mode})
+				text = temp + "\n" + summaryText
+				logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+			}
+		}
+	}
+
+	// list the entries that were skipped, the first few are enough to fix the file
+	if len(invalid) > 0 {
+		temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_blacklist_invalid_triggers")
+		var sb strings.Builder
+		for _, entry := range invalid[:min(len(invalid), 10)] {
+			sb.WriteString("\n - " + html.EscapeString(entry))
+		}
+		if len(invalid) > 10 {
+			sb.WriteString("\n - …")
+		}
+		text += "\n\n" + temp + sb.String()
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+/*
+	Used to share blacklists between groups as packs!
+
+Connection - true, true
+Chat owner can publish the blacklist of the chat as a pack, admins can subscribe the chat to packs
+*/
+// blacklistPack handles the /blpack command to share blacklists between chats.
+// Owners publish the blacklist of their chat as a named pack, publishing again to update it,
+// and admins subscribe their chat to packs, whose latest triggers are enforced along with its own.
+func (m moduleStruct) blacklistPack(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	// Permission Checks
+	if !chat_status.IsUserAdmin(b, chat.Id, user.Id) {
+		return ext.EndGroups
+	}
+
+	if len(args) == 0 {
+		return m.listBlacklistPacks(b, ctx, tr)
+	}
+
+	var (
+		text    string
+		changed bool
+	)
+	action := strings.ToLower(args[0])
+	name := ""
+	if len(args) > 1 {
+		name = strings.ToLower(args[1])
+	}
+
+	switch {
+	case action != "publish" && action != "unpublish" && action != "subscribe" && action != "unsubscribe":
+		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_pack_usage")
+	case !blacklistPackNameRegex.MatchString(name):
+		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_pack_invalid_name")
+	case action == "publish":
+		if !chat_status.RequireUserOwner(b, ctx, chat, user.Id, false) {
+			return ext.EndGroups
+		}
+		if len(db.GetBlacklistSettings(chat.Id)) == 0 {
+			text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_ls_bl_no_blacklisted")
+			break
+		}
+		pack, err := db.PublishBlacklistPack(name, chat.Id)
+		switch {
+		case errors.Is(err, db.ErrBlacklistPackNotOwned):
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_pack_not_owned", i18n.TranslationParams{"name": name})
+		case err != nil:
+			text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_pack_failed")
+		default:
+			changed = true
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_pack_published", i18n.TranslationParams{
+				"name":    name,
+				"version": pack.Version,
+				"count":   len(db.GetBlacklistPackTriggers(pack.ID)),
+			})
+		}
+	case action == "unpublish":
+		if !chat_status.RequireUserOwner(b, ctx, chat, user.Id, false) {
+			return ext.EndGroups
+		}
+		err := db.UnpublishBlacklistPack(name, chat.Id)
+		switch {
+		case errors.Is(err, db.ErrBlacklistPackNotFound):
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_pack_not_found", i18n.TranslationParams{"name": name})
+		case errors.Is(err, db.ErrBlacklistPackNotOwned):
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_pack_not_owned", i18n.TranslationParams{"name": name})
+		case err != nil:
+			text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_pack_failed")
+		default:
+			changed = true
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_pack_unpublished", i18n.TranslationParams{"name": name})
+		}
+	default:
+		if !chat_status.CanUserRestrict(b, ctx, chat, user.Id, false) {
+			return ext.EndGroups
+		}
+		if !chat_status.CanBotRestrict(b, ctx, chat, false) {
+			return ext.EndGroups
+		}
+		pack := db.GetBlacklistPack(name)
+		switch {
+		case pack == nil:
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_pack_not_found", i18n.TranslationParams{"name": name})
+		case action == "subscribe":
+			db.SubscribeBlacklistPack(chat.Id, pack.ID)
+			changed = true
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_pack_subscribed", i18n.TranslationParams{
+				"name":  name,
+				"count": len(db.GetBlacklistPackTriggers(pack.ID)),
+			})
+		case db.UnsubscribeBlacklistPack(chat.Id, pack.ID):
+			changed = true
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_pack_unsubscribed", i18n.TranslationParams{"name": name})
+		default:
+			text, _ = tr.GetString(strings.ToLower(m.moduleName)+"_pack_not_subscribed", i18n.TranslationParams{"name": name})
+		}
+	}
+
+	if changed {
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+	}
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// listBlacklistPacks replies with every published blacklist pack, marking the ones the chat is subscribed to.
+func (m moduleStruct) listBlacklistPacks(b *gotgbot.Bot, ctx *ext.Context, tr *i18n.Translator) error {
+	msg := ctx.EffectiveMessage
+	chat := ctx.EffectiveChat
+
+	packs := db.GetBlacklistPacks()
+	subscriptions := db.GetBlacklistPackSubscriptions(chat.Id)
+
+	var text string
+	if len(packs) == 0 {
+		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_pack_none")
+	} else {
+		text, _ = tr.GetString(strings.ToLower(m.moduleName) + "_pack_list")
+		subscribedMark, _ := tr.GetString(strings.ToLower(m.moduleName) + "_pack_subscribed_mark")
+		var sb strings.Builder
+		for _, pack := range packs {
+			entry, _ := tr.GetString(strings.ToLower(m.moduleName)+"_pack_list_entry", i18n.TranslationParams{
+				"name":    pack.Name,
+				"version": pack.Version,
+				"count":   len(db.GetBlacklistPackTriggers(pack.ID)),
+			})
+			sb.WriteString("\n - " + entry)
+			if slices.ContainsFunc(subscriptions, func(sub *db.BlacklistPack) bool { return sub.ID == pack.ID }) {
+				sb.WriteString(" " + subscribedMark)
+			}
+		}
+		text += sb.String()
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// blacklistTrigger returns the trigger of a blacklist setting along with its match mode.
+func blacklistTrigger(bs *db.BlacklistSettings) keyword_matcher.Trigger {
+	return keyword_matcher.Trigger{Pattern: bs.Word, Mode: bs.MatchMode}
+}
+
+// matchBlacklistedSticker returns the blacklist setting banning a sticker, either
+// the sticker itself or its whole pack, or nil if the sticker is allowed.
+func matchBlacklistedSticker(blSettings db.BlacklistSettingsSlice, sticker *gotgbot.Sticker) *db.BlacklistSettings {
+	if bs := blSettings.Get(sticker.FileUniqueId, keyword_matcher.ModeSticker); bs != nil {
+		return bs
+	}
+	if sticker.SetName == "" {
+		return nil
+	}
+	return blSettings.Get(strings.ToLower(sticker.SetName), keyword_matcher.ModeStickerSet)
+}
+
+// blacklistWatched reports whether a message has content the blacklist watcher checks:
+// text, a media caption or a sticker. Forwarded messages carry the forwarded text or caption.
+func blacklistWatched(msg *gotgbot.Message) bool {
+	return msg.Text != "" || msg.Caption != "" || msg.Sticker != nil
+}
+
+// blacklistTriggers returns the triggers of all blacklist settings of a chat.
+func blacklistTriggers(blSettings db.BlacklistSettingsSlice) []keyword_matcher.Trigger {
+	triggers := make([]keyword_matcher.Trigger, 0, len(blSettings))
+	for _, bs := range blSettings {
+		triggers = append(triggers, blacklistTrigger(bs))
+	}
+	return triggers
+}
+
 /*
 	Blacklist watcher
 
 Watcher for blacklisted words, if any of the sentence contains the word, it will remove and use the appropriate action
 */
-// blacklistWatcher monitors all messages for blacklisted words.
-// Automatically applies configured punishment when blacklisted content is detected.
+// blacklistWatcher monitors all messages for blacklisted words and stickers.
+// Checks text, media captions and edits, and automatically applies the configured
+// punishment when blacklisted content is detected.
 func (m moduleStruct) blacklistWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 	chat := ctx.EffectiveChat
 	user := ctx.EffectiveSender
@@ -454,50 +1383,83 @@ func (m moduleStruct) blacklistWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 
 	// skip admins and creator + approved users and anonymous channel
 	// Only check admin status for actual users, not anonymous channels
-	if !user.IsAnonymousChannel() && user.IsUser() && user.Id() > 0 && chat_status.IsUserAdmin(b, chat.Id, user.Id()) {
+	if !user.IsAnonymousChannel() && user.IsUser() && user.Id() > 0 && chat_status.IsUserApprovedOrAdmin(b, chat.Id, user.Id()) {
 		return ext.ContinueGroups
 	}
 
 	msg := ctx.EffectiveMessage
-	blSettings := db.GetBlacklistSettings(chat.Id)
+	// the chat's own triggers followed by the ones of the packs it is subscribed to
+	blSettings := db.GetEffectiveBlacklist(chat.Id)
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 
-	triggers := blSettings.Triggers()
+	triggers := blacklistTriggers(blSettings)
 	if len(triggers) == 0 {
 		return ext.ContinueGroups
 	}
+	chatSettings := db.GetBlacklistChatSettings(chat.Id)
+
+	var trigger *db.BlacklistSettings
+	if msg.Sticker != nil {
+		// stickers are checked against their pack and their own id
+		trigger = matchBlacklistedSticker(blSettings, msg.Sticker)
+	} else {
+		// Use Aho-Corasick prefiltering with per-mode verification for efficient multi-pattern matching
+		cache := keyword_matcher.GetCache("blacklists")
+		matcher := cache.GetOrCreateTriggerMatcher(chat.Id, triggers, chatSettings.Normalization)
 
-	// Use Aho-Corasick for efficient multi-pattern matching
-	cache := keyword_matcher.GetGlobalCache()
-	matcher := cache.GetOrCreateMatcher(chat.Id, triggers)
+		// text of the message, or caption of media
+		text := msg.GetText()
 
-	// Check for any blacklist match first
-	if !matcher.HasMatch(msg.Text) {
-		return ext.ContinueGroups
-	}
+		// Check for any blacklist match first
+		if !matcher.HasMatch(text) {
+			return ext.ContinueGroups
+		}
 
-	// Get first match to process
-	matches := matcher.FindMatches(msg.Text)
-	if len(matches) == 0 {
+		// Get first match to process
+		matches := matcher.FindMatches(text)
+		if len(matches) == 0 {
+			return ext.ContinueGroups
+		}
+
+		trigger = blSettings.Get(matches[0].Pattern, matches[0].Mode)
+		if trigger == nil {
+			trigger = &db.BlacklistSettings{ChatId: chat.Id, Word: matches[0].Pattern, MatchMode: matches[0].Mode}
+		}
+	}
+	if trigger == nil {
 		return ext.ContinueGroups
 	}
 
-	// Process first matched trigger
-	i := matches[0].Pattern
+	// Process the matched trigger, with its own action and reason or the chat default
+	i := blacklistTrigger(trigger)
+	reason := trigger.ReasonFor(chatSettings)
+	if reason == "" {
+		reason, _ = tr.GetString(strings.ToLower(m.moduleName)+"_default_reason", i18n.TranslationParams{"trigger": i.String()})
+	}
+	htmlReason := html.EscapeString(reason)
 
 	_, err := msg.Delete(b, nil)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
-	switch blSettings.Action() {
-	case "mute":
+
+	// timed actions are lifted by telegram once the until date has passed
+	action, duration := trigger.ActionFor(chatSettings)
+	var untilDate int64
+	if (action == "tmute" || action == "tban") && duration > 0 {
+		untilDate = time.Now().Unix() + duration
+	}
+
+	switch action {
+	case "mute", "tmute":
 		// don't work on anonymous channels
 		if user.IsAnonymousChannel() {
 			return ext.ContinueGroups
 		}
 
-		_, err = b.RestrictChatMember(chat.Id, user.Id(), gotgbot.ChatPermissions{CanSendMessages: false}, nil)
+		_, err = b.RestrictChatMember(chat.Id, user.Id(), gotgbot.ChatPermissions{CanSendMessages: false},
+			&gotgbot.RestrictChatMemberOpts{UntilDate: untilDate})
 		if err != nil {
 			log.Error(err)
 			return err
@@ -505,20 +1467,28 @@ func (m moduleStruct) blacklistWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 
 		_, err = msg.Reply(b,
 			func() string {
+				if untilDate > 0 {
+					text, _ := tr.GetString(strings.ToLower(m.moduleName)+"_bl_watcher_tmuted_user", i18n.TranslationParams{
+						"user":     helpers.MentionHtml(user.Id(), user.Name()),
+						"duration": formatWarnTime(tr, duration),
+						"reason":   htmlReason,
+					})
+					return text
+				}
 				temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_bl_watcher_muted_user")
-				return fmt.Sprintf(temp, helpers.MentionHtml(user.Id(), user.Name()), fmt.Sprintf(blSettings.Reason(), i))
+				return fmt.Sprintf(temp, helpers.MentionHtml(user.Id(), user.Name()), htmlReason)
 			}(),
 			helpers.Shtml())
 		if err != nil {
 			log.Error(err)
 			return err
 		}
-	case "ban":
+	case "ban", "tban":
 		// ban anonymous channels as well
 		if user.IsAnonymousChannel() {
 			_, err = b.BanChatSenderChat(chat.Id, user.Id(), nil)
 		} else {
-			_, err = b.BanChatMember(chat.Id, user.Id(), nil)
+			_, err = b.BanChatMember(chat.Id, user.Id(), &gotgbot.BanChatMemberOpts{UntilDate: untilDate})
 		}
 		if err != nil {
 			log.Error(err)
@@ -527,8 +1497,16 @@ func (m moduleStruct) blacklistWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 
 		_, err = msg.Reply(b,
 			func() string {
+				if untilDate > 0 && !user.IsAnonymousChannel() {
+					text, _ := tr.GetString(strings.ToLower(m.moduleName)+"_bl_watcher_tbanned_user", i18n.TranslationParams{
+						"user":     helpers.MentionHtml(user.Id(), user.Name()),
+						"duration": formatWarnTime(tr, duration),
+						"reason":   htmlReason,
+					})
+					return text
+				}
 				temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_bl_watcher_banned_user")
-				return fmt.Sprintf(temp, helpers.MentionHtml(user.Id(), user.Name()), fmt.Sprintf(blSettings.Reason(), i))
+				return fmt.Sprintf(temp, helpers.MentionHtml(user.Id(), user.Name()), htmlReason)
 			}(),
 			helpers.Shtml())
 		if err != nil {
@@ -550,7 +1528,7 @@ func (m moduleStruct) blacklistWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 		_, err = msg.Reply(b,
 			func() string {
 				temp, _ := tr.GetString(strings.ToLower(m.moduleName) + "_bl_watcher_kicked_user")
-				return fmt.Sprintf(temp, helpers.MentionHtml(user.Id(), user.Name()), fmt.Sprintf(blSettings.Reason(), i))
+				return fmt.Sprintf(temp, helpers.MentionHtml(user.Id(), user.Name()), htmlReason)
 			}(),
 			helpers.Shtml())
 		if err != nil {
@@ -582,7 +1560,7 @@ func (m moduleStruct) blacklistWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 			return ext.ContinueGroups
 		}
 
-		err = warnsModule.warnThisUser(b, ctx, user.Id(), fmt.Sprintf(blSettings.Reason(), i), "warn")
+		err = warnsModule.warnThisUser(b, ctx, user.Id(), b.Id, reason, "warn")
 		if err != nil {
 			log.Error(err)
 			return err
@@ -604,7 +1582,12 @@ func LoadBlacklists(dispatcher *ext.Dispatcher) {
 	dispatcher.AddHandler(handlers.NewCommand("rmblacklist", blacklistsModule.removeBlacklist))
 	dispatcher.AddHandler(handlers.NewCommand("blaction", blacklistsModule.setBlacklistAction))
 	dispatcher.AddHandler(handlers.NewCommand("blacklistaction", blacklistsModule.setBlacklistAction))
+	dispatcher.AddHandler(handlers.NewCommand("blnormalize", blacklistsModule.setBlacklistNormalization))
 	cmdDecorator.MultiCommand(dispatcher, []string{"remallbl", "rmallbl"}, blacklistsModule.rmAllBlacklists)
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("rmAllBlacklist"), blacklistsModule.buttonHandler))
-	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.Text, blacklistsModule.blacklistWatcher), blacklistsModule.handlerGroup)
+	dispatcher.AddHandler(handlers.NewCommand("blsticker", blacklistsModule.blacklistSticker))
+	dispatcher.AddHandler(handlers.NewCommand("exportblacklist", blacklistsModule.exportBlacklist))
+	dispatcher.AddHandler(handlers.NewCommand("importblacklist", blacklistsModule.importBlacklist))
+	dispatcher.AddHandler(handlers.NewCommand("blpack", blacklistsModule.blacklistPack))
+	dispatcher.AddHandlerToGroup(handlers.NewMessage(blacklistWatched, blacklistsModule.blacklistWatcher).SetAllowEdited(true), blacklistsModule.handlerGroup)
 }
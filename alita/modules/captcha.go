@@ -22,6 +22,7 @@ import (
 	"github.com/divideprojects/Alita_Robot/alita/utils/cache"
 	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
 	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+	"github.com/divideprojects/Alita_Robot/alita/utils/scheduler"
 	"github.com/eko/gocache/lib/v4/store"
 	"github.com/mojocn/base64Captcha"
 	log "github.com/sirupsen/logrus"
@@ -638,29 +639,51 @@ func SendCaptcha(bot *gotgbot.Bot, ctx *ext.Context, userID int64, userName stri
 		return err
 	}
 
-	// Schedule cleanup after timeout with context
-	go func(originalMessageID int64) {
-		// Create a context with the timeout duration plus a small buffer
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(settings.Timeout)*time.Minute+30*time.Second)
-		defer cancel()
+	// Schedule the timeout as a persistent job so it still fires after a restart
+	err = scheduler.Enqueue(captchaTimeoutJob, fmt.Sprint(preAttempt.ID), captchaTimeoutPayload{
+		ChatID:    chat.Id,
+		UserID:    userID,
+		AttemptID: preAttempt.ID,
+		Action:    settings.FailureAction,
+	}, time.Now().Add(time.Duration(settings.Timeout)*time.Minute))
+	if err != nil {
+		log.Errorf("Failed to schedule captcha timeout for user %d in chat %d: %v", userID, chat.Id, err)
+	}
 
-		// Use a timer instead of Sleep for better control
-		timer := time.NewTimer(time.Duration(settings.Timeout) * time.Minute)
-		defer timer.Stop()
+	return nil
+}
 
-		select {
-		case <-timer.C:
-			// Check if attempt still exists (not completed)
-			attempt, _ := db.GetCaptchaAttempt(userID, chat.Id)
-			if attempt != nil {
-				// Use the latest message ID from the attempt to avoid leaving a stale message after refresh
-				handleCaptchaTimeout(bot, chat.Id, userID, attempt.MessageID, settings.FailureAction)
-			}
-		case <-ctx.Done():
-			log.Warnf("Captcha timeout handler cancelled for user %d in chat %d", userID, chat.Id)
-		}
-	}(sent.MessageId)
+// captchaTimeoutJob is the scheduler job type that punishes users who didn't solve their captcha in time.
+const captchaTimeoutJob = "captcha_timeout"
+
+// captchaTimeoutPayload identifies the captcha attempt a timeout job belongs to.
+type captchaTimeoutPayload struct {
+	ChatID    int64  `json:"chat_id"`
+	UserID    int64  `json:"user_id"`
+	AttemptID uint   `json:"attempt_id"`
+	Action    string `json:"action"`
+}
+
+// runCaptchaTimeoutJob applies the failure action if the captcha attempt is still pending.
+// Attempts that were solved, failed or replaced by a newer captcha are skipped.
+func runCaptchaTimeoutJob(bot *gotgbot.Bot, job *db.ScheduledJob) error {
+	var payload captchaTimeoutPayload
+	if err := scheduler.DecodePayload(job, &payload); err != nil {
+		// a malformed payload can't succeed on retry
+		log.Errorf("Invalid captcha timeout payload for job %d: %v", job.ID, err)
+		return nil
+	}
+
+	attempt, err := db.GetCaptchaAttemptByID(payload.AttemptID)
+	if err != nil {
+		return err
+	}
+	if attempt == nil {
+		return nil
+	}
 
+	// Use the latest message ID from the attempt to avoid leaving a stale message after refresh
+	handleCaptchaTimeout(bot, payload.ChatID, payload.UserID, attempt.MessageID, payload.Action)
 	return nil
 }
 
@@ -742,6 +765,21 @@ func handleCaptchaTimeout(bot *gotgbot.Bot, chatID, userID int64, messageID int6
 		}()
 	}
 
+	var actionText string
+	switch action {
+	case "ban":
+		actionText, _ = tr.GetString("captcha_action_banned")
+	case "mute":
+		actionText, _ = tr.GetString("captcha_action_muted")
+	default:
+		actionText, _ = tr.GetString("captcha_action_kicked")
+	}
+	logText, _ := tr.GetString("logs_captcha_failed", i18n.TranslationParams{
+		"user":   helpers.MentionHtml(userID, userName),
+		"action": actionText,
+	})
+	sendChatLog(bot, chatID, db.LogCategoryCaptcha, logText)
+
 	// Delete the attempt from database
 	_ = db.DeleteCaptchaAttempt(userID, chatID)
 
@@ -1118,8 +1156,8 @@ func (moduleStruct) handlePendingCaptchaMessage(bot *gotgbot.Bot, ctx *ext.Conte
 		return ext.ContinueGroups
 	}
 
-	// Skip if user is an admin
-	if chat_status.IsUserAdmin(bot, chat.Id, user.Id) {
+	// Skip if user is an admin or approved
+	if chat_status.IsUserApprovedOrAdmin(bot, chat.Id, user.Id) {
 		return ext.ContinueGroups
 	}
 
@@ -1207,6 +1245,8 @@ func LoadCaptcha(dispatcher *ext.Dispatcher) {
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("captcha_verify."), captchaModule.captchaVerifyCallback))
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("captcha_refresh."), captchaModule.captchaRefreshCallback))
 
+	scheduler.RegisterHandler(captchaTimeoutJob, runCaptchaTimeoutJob)
+
 	// Start periodic cleanup of expired attempts with context
 	go func() {
 		ticker := time.NewTicker(5 * time.Minute)
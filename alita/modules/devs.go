@@ -218,7 +218,7 @@ func (moduleStruct) addSudo(b *gotgbot.Bot, ctx *ext.Context) error {
 	} else {
 		textTemplate, _ := tr.GetString("devs_added_to_sudo")
 		txt = fmt.Sprintf(textTemplate, helpers.MentionHtml(reqUser.Id, reqUser.FirstName))
-		go db.AddDev(userId)
+		go db.AddSudo(userId)
 	}
 	_, err = msg.Reply(b, txt, &gotgbot.SendMessageOpts{ParseMode: helpers.HTML})
 	if err != nil {
@@ -306,7 +306,7 @@ func (moduleStruct) remSudo(b *gotgbot.Bot, ctx *ext.Context) error {
 	} else {
 		textTemplate, _ := tr.GetString("devs_removed_from_sudo")
 		txt = fmt.Sprintf(textTemplate, helpers.MentionHtml(reqUser.Id, reqUser.FirstName))
-		go db.RemDev(userId)
+		go db.RemSudo(userId)
 	}
 	_, err = msg.Reply(b, txt, &gotgbot.SendMessageOpts{ParseMode: helpers.HTML})
 	if err != nil {
@@ -0,0 +1,520 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/i18n"
+	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
+	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+)
+
+var fedsModule = moduleStruct{moduleName: "Federations"}
+
+// getFedFromArgs resolves the federation a command refers to.
+// Uses the fed ID given as argument, else the federation of the current group,
+// else the federation owned by the user when used in PM.
+func getFedFromArgs(ctx *ext.Context) *db.Federation {
+	args := ctx.Args()
+	if len(args) >= 2 {
+		return db.GetFederation(args[1])
+	}
+
+	if ctx.EffectiveChat.Type == "private" {
+		return db.GetFederationByOwner(ctx.EffectiveSender.User.Id)
+	}
+	return db.GetChatFederation(ctx.EffectiveChat.Id)
+}
+
+// getGroupFedAsAdmin returns the federation of the current group if the user is a fed admin.
+// Replies with the appropriate error and returns nil otherwise.
+func getGroupFedAsAdmin(b *gotgbot.Bot, ctx *ext.Context, ownerOnly bool) *db.Federation {
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return nil
+	}
+
+	fed := db.GetChatFederation(ctx.EffectiveChat.Id)
+	if fed == nil {
+		text, _ := tr.GetString("feds_chat_not_in_fed")
+		_, err := msg.Reply(b, text, helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	if ownerOnly && fed.OwnerId != user.Id {
+		temp, _ := tr.GetString("feds_not_fed_owner")
+		_, err := msg.Reply(b, fmt.Sprintf(temp, html.EscapeString(fed.FedName)), helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	if !db.IsFedAdmin(fed, user.Id) {
+		temp, _ := tr.GetString("feds_not_fed_admin")
+		_, err := msg.Reply(b, fmt.Sprintf(temp, html.EscapeString(fed.FedName)), helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+		}
+		return nil
+	}
+
+	return fed
+}
+
+// extractFedTarget extracts the user a federation command is aimed at.
+// Replies with the appropriate error and returns 0 if no valid user was given.
+func extractFedTarget(b *gotgbot.Bot, ctx *ext.Context) (int64, string) {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	userId, reason := extraction.ExtractUserAndText(b, ctx)
+	if userId == -1 {
+		return 0, ""
+	}
+
+	var text string
+	switch {
+	case userId == 0:
+		text, _ = tr.GetString("feds_no_user_specified")
+	case helpers.IsChannelID(userId):
+		text, _ = tr.GetString("feds_user_is_channel")
+	case userId == b.Id:
+		text, _ = tr.GetString("feds_user_is_bot_itself")
+	default:
+		return userId, reason
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+	}
+	return 0, ""
+}
+
+// newFed handles the /newfed command to create a federation owned by the user.
+// Each user can own a single federation.
+func (moduleStruct) newFed(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequirePrivate(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	splitText := strings.SplitN(msg.Text, " ", 2)
+	if len(splitText) < 2 || strings.TrimSpace(splitText[1]) == "" {
+		text, _ = tr.GetString("feds_newfed_no_name")
+	} else {
+		fedName := strings.TrimSpace(splitText[1])
+		fed, err := db.CreateFederation(user.Id, fedName)
+		switch {
+		case errors.Is(err, db.ErrFedAlreadyOwned):
+			temp, _ := tr.GetString("feds_newfed_already_owned")
+			text = fmt.Sprintf(temp, html.EscapeString(db.GetFederationByOwner(user.Id).FedName))
+		case err != nil:
+			text, _ = tr.GetString("feds_action_failed")
+		default:
+			temp, _ := tr.GetString("feds_newfed_created")
+			text = fmt.Sprintf(temp, html.EscapeString(fed.FedName), fed.FedId, fed.FedId)
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// joinFed handles the /joinfed command to add the current chat to a federation.
+// Only the chat owner can join a federation.
+func (moduleStruct) joinFed(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserOwner(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	if len(args) < 2 {
+		text, _ = tr.GetString("feds_joinfed_no_id")
+	} else {
+		fed := db.GetFederation(args[1])
+		if fed == nil {
+			text, _ = tr.GetString("feds_fed_not_found")
+		} else {
+			err := db.JoinFederation(chat.Id, fed.FedId)
+			switch {
+			case errors.Is(err, db.ErrChatAlreadyInFed):
+				text, _ = tr.GetString("feds_joinfed_already_in_fed")
+			case err != nil:
+				text, _ = tr.GetString("feds_action_failed")
+			default:
+				temp, _ := tr.GetString("feds_joinfed_joined")
+				text = fmt.Sprintf(temp, html.EscapeString(fed.FedName))
+			}
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// leaveFed handles the /leavefed command to remove the current chat from its federation.
+// Only the chat owner can leave a federation.
+func (moduleStruct) leaveFed(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserOwner(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	fed := db.GetChatFederation(chat.Id)
+	if fed == nil {
+		text, _ = tr.GetString("feds_chat_not_in_fed")
+	} else if err := db.LeaveFederation(chat.Id); err != nil {
+		text, _ = tr.GetString("feds_action_failed")
+	} else {
+		temp, _ := tr.GetString("feds_leavefed_left")
+		text = fmt.Sprintf(temp, html.EscapeString(fed.FedName))
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// fban handles the /fban command to ban a user in every chat of the federation.
+// Only federation admins can fban, and the reason is stored with the ban.
+func (moduleStruct) fban(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	fed := getGroupFedAsAdmin(b, ctx, false)
+	if fed == nil {
+		return ext.EndGroups
+	}
+
+	userId, reason := extractFedTarget(b, ctx)
+	if userId == 0 {
+		return ext.EndGroups
+	}
+
+	var text string
+	if db.IsFedAdmin(fed, userId) {
+		text, _ = tr.GetString("feds_fban_is_fed_admin")
+	} else {
+		alreadyBanned, err := db.FedBanUser(fed.FedId, userId, user.Id, reason)
+		if err != nil {
+			text, _ = tr.GetString("feds_action_failed")
+		} else {
+			var temp string
+			if alreadyBanned {
+				temp, _ = tr.GetString("feds_fban_updated")
+			} else {
+				temp, _ = tr.GetString("feds_fban_banned")
+			}
+			text = fmt.Sprintf(temp, mentionUserById(userId), html.EscapeString(fed.FedName))
+			if reason != "" {
+				temp, _ := tr.GetString("feds_reason")
+				text += fmt.Sprintf(temp, html.EscapeString(reason))
+			}
+
+			fedBanInChat(b, ctx, chat, userId, user.Id, reason)
+			go banInFedChats(b, ctx, fed.FedId, chat.Id, userId, user.Id, reason)
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// banInFedChats bans a user in every chat of a federation except the given one.
+func banInFedChats(b *gotgbot.Bot, ctx *ext.Context, fedId string, skipChatId, userId, actorId int64, reason string) {
+	for _, chatId := range db.GetFedChats(fedId) {
+		if chatId == skipChatId {
+			continue
+		}
+		fedBanInChat(b, ctx, &gotgbot.Chat{Id: chatId}, userId, actorId, reason)
+	}
+}
+
+// fedBanInChat bans a fedbanned user in a chat of the federation and records the ban
+// in the audit log of that chat. Chats where the bot can't ban are skipped silently.
+// Returns true if the user was banned.
+func fedBanInChat(b *gotgbot.Bot, ctx *ext.Context, chat *gotgbot.Chat, userId, actorId int64, reason string) bool {
+	if !chat_status.CanBotRestrict(b, ctx, chat, true) {
+		return false
+	}
+	if err := applyPunishment(b, chat, userId, "ban", 0); err != nil {
+		log.Debugf("[Feds] Failed to ban %d in %d: %v", userId, chat.Id, err)
+		return false
+	}
+
+	entry := newModAction(ctx, db.ModActionBan, userId, reason, 0)
+	entry.ActorId = actorId
+	if chat.Id != entry.ChatId {
+		// the ban wasn't issued in this chat, so there is no message to link
+		entry.ChatId = chat.Id
+		entry.MessageLink = ""
+	}
+	recordModAction(b, ctx, entry)
+	return true
+}
+
+// unfban handles the /unfban command to lift a federation ban.
+// The user is unbanned in every chat of the federation.
+func (moduleStruct) unfban(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	fed := getGroupFedAsAdmin(b, ctx, false)
+	if fed == nil {
+		return ext.EndGroups
+	}
+
+	userId, _ := extractFedTarget(b, ctx)
+	if userId == 0 {
+		return ext.EndGroups
+	}
+
+	var text string
+	if !db.FedUnbanUser(fed.FedId, userId) {
+		temp, _ := tr.GetString("feds_unfban_not_banned")
+		text = fmt.Sprintf(temp, mentionUserById(userId), html.EscapeString(fed.FedName))
+	} else {
+		temp, _ := tr.GetString("feds_unfban_unbanned")
+		text = fmt.Sprintf(temp, mentionUserById(userId), html.EscapeString(fed.FedName))
+
+		go func(chatIds []int64) {
+			for _, chatId := range chatIds {
+				_, err := b.UnbanChatMember(chatId, userId, &gotgbot.UnbanChatMemberOpts{OnlyIfBanned: true})
+				if err != nil {
+					log.Debugf("[Feds] Failed to unban %d in %d: %v", userId, chatId, err)
+				}
+			}
+		}(db.GetFedChats(fed.FedId))
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// fpromote handles the /fpromote command to make a user an admin of the federation.
+// Only the federation owner can promote admins.
+func (moduleStruct) fpromote(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	fed := getGroupFedAsAdmin(b, ctx, true)
+	if fed == nil {
+		return ext.EndGroups
+	}
+
+	userId, _ := extractFedTarget(b, ctx)
+	if userId == 0 {
+		return ext.EndGroups
+	}
+
+	var text string
+	if userId == fed.OwnerId {
+		text, _ = tr.GetString("feds_fpromote_is_owner")
+	} else if err := db.AddFedAdmin(fed.FedId, userId); err != nil {
+		text, _ = tr.GetString("feds_action_failed")
+	} else {
+		temp, _ := tr.GetString("feds_fpromote_promoted")
+		text = fmt.Sprintf(temp, mentionUserById(userId), html.EscapeString(fed.FedName))
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// fdemote handles the /fdemote command to remove a user from the federation admins.
+// Only the federation owner can demote admins.
+func (moduleStruct) fdemote(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	fed := getGroupFedAsAdmin(b, ctx, true)
+	if fed == nil {
+		return ext.EndGroups
+	}
+
+	userId, _ := extractFedTarget(b, ctx)
+	if userId == 0 {
+		return ext.EndGroups
+	}
+
+	var temp string
+	if db.RemoveFedAdmin(fed.FedId, userId) {
+		temp, _ = tr.GetString("feds_fdemote_demoted")
+	} else {
+		temp, _ = tr.GetString("feds_fdemote_not_admin")
+	}
+	text := fmt.Sprintf(temp, mentionUserById(userId), html.EscapeString(fed.FedName))
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// fedInfo handles the /fedinfo command to show details about a federation.
+func (moduleStruct) fedInfo(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	var text string
+	fed := getFedFromArgs(ctx)
+	if fed == nil {
+		text, _ = tr.GetString("feds_no_fed_found")
+	} else {
+		chats, bans := db.GetFedStats(fed.FedId)
+		temp, _ := tr.GetString("feds_fedinfo")
+		text = fmt.Sprintf(
+			temp,
+			html.EscapeString(fed.FedName),
+			fed.FedId,
+			mentionUserById(fed.OwnerId),
+			len(db.GetFedAdmins(fed.FedId)),
+			chats,
+			bans,
+		)
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// fedAdmins handles the /fedadmins command to list the owner and admins of a federation.
+func (moduleStruct) fedAdmins(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	var text string
+	fed := getFedFromArgs(ctx)
+	if fed == nil {
+		text, _ = tr.GetString("feds_no_fed_found")
+	} else {
+		temp, _ := tr.GetString("feds_fedadmins_header")
+		text = fmt.Sprintf(temp, html.EscapeString(fed.FedName))
+		temp, _ = tr.GetString("feds_fedadmins_owner")
+		text += fmt.Sprintf(temp, mentionUserById(fed.OwnerId))
+		temp, _ = tr.GetString("feds_fedadmins_admin")
+		for _, adminId := range db.GetFedAdmins(fed.FedId) {
+			text += fmt.Sprintf(temp, mentionUserById(adminId))
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// enforceFedBan bans a joining user if they are banned in the federation of the chat.
+// Returns true if the user was fbanned and removed, in which case no greeting should be sent.
+func enforceFedBan(b *gotgbot.Bot, ctx *ext.Context, user gotgbot.User) bool {
+	chat := ctx.EffectiveChat
+
+	fed, fban := db.GetChatFedBan(chat.Id, user.Id)
+	if fban == nil {
+		return false
+	}
+
+	if !fedBanInChat(b, ctx, chat, user.Id, fban.BannedBy, fban.Reason) {
+		return false
+	}
+
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	temp, _ := tr.GetString("feds_join_fbanned")
+	text := fmt.Sprintf(temp, helpers.MentionHtml(user.Id, user.FirstName), html.EscapeString(fed.FedName))
+	if fban.Reason != "" {
+		temp, _ := tr.GetString("feds_reason")
+		text += fmt.Sprintf(temp, html.EscapeString(fban.Reason))
+	}
+	_, err := b.SendMessage(chat.Id, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+// LoadFeds registers all federation command handlers with the dispatcher.
+// Fedbans are enforced on join from the greetings module.
+func LoadFeds(dispatcher *ext.Dispatcher) {
+	HelpModule.AbleMap.Store(fedsModule.moduleName, true)
+
+	dispatcher.AddHandler(handlers.NewCommand("newfed", fedsModule.newFed))
+	dispatcher.AddHandler(handlers.NewCommand("joinfed", fedsModule.joinFed))
+	dispatcher.AddHandler(handlers.NewCommand("leavefed", fedsModule.leaveFed))
+	dispatcher.AddHandler(handlers.NewCommand("fban", fedsModule.fban))
+	dispatcher.AddHandler(handlers.NewCommand("unfban", fedsModule.unfban))
+	dispatcher.AddHandler(handlers.NewCommand("fpromote", fedsModule.fpromote))
+	dispatcher.AddHandler(handlers.NewCommand("fdemote", fedsModule.fdemote))
+	dispatcher.AddHandler(handlers.NewCommand("fedinfo", fedsModule.fedInfo))
+	dispatcher.AddHandler(handlers.NewCommand("fedadmins", fedsModule.fedAdmins))
+}
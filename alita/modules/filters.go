@@ -3,6 +3,7 @@ package modules
 import (
 	"fmt"
 	"html"
+	"slices"
 	"strings"
 
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
@@ -19,11 +20,9 @@ import (
 	"github.com/divideprojects/Alita_Robot/alita/db"
 	"github.com/divideprojects/Alita_Robot/alita/i18n"
 
-	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
 	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
 
 	"github.com/divideprojects/Alita_Robot/alita/utils/keyword_matcher"
-	"github.com/divideprojects/Alita_Robot/alita/utils/string_handling"
 )
 
 var filtersModule = moduleStruct{
@@ -39,8 +38,8 @@ var filtersModule = moduleStruct{
 
 Only admin can add new filters in the chat
 */
-// addFilter creates a new filter with a keyword trigger and response content.
-// Only admins can add filters. Supports text, media, and buttons with a limit of 150 filters per chat.
+// addFilter creates a new filter with one or more triggers sharing its response content.
+// Only admins can add filters. Supports text, media, and buttons with a limit of 150 triggers per chat.
 func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 	msg := ctx.EffectiveMessage
 	// connection status
@@ -101,17 +100,48 @@ func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
-	filterWord = strings.ToLower(filterWord) // convert string to it's lower form
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	triggers, invalid := filterTriggers(filterWord)
+	if len(invalid) > 0 || len(triggers) == 0 {
+		text, _ := tr.GetString("filters_keyword_required")
+		if len(invalid) > 0 {
+			text, _ = tr.GetString("filters_invalid_triggers")
+			text += "\n - " + strings.Join(invalid, "\n - ")
+		}
+		_, err := msg.Reply(b, text, helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+	if filtersNum+int64(len(triggers)) > 150 {
+		text, _ := tr.GetString("filters_limit_exceeded")
+		_, err := msg.Reply(b, text, helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	filterTriggerList := make([]db.FilterTrigger, len(triggers))
+	exists := false
+	for i, trigger := range triggers {
+		filterTriggerList[i] = db.FilterTrigger{KeyWord: trigger.Pattern, MatchMode: trigger.Mode}
+		exists = exists || db.DoesFilterExists(chat.Id, trigger.Pattern, trigger.Mode)
+	}
 
-	if db.DoesFilterExists(chat.Id, filterWord) {
-		m.overwriteFiltersMap[fmt.Sprint(filterWord, "_", chat.Id)] = overwriteFilter{
-			filterWord: filterWord,
-			text:       text,
-			fileid:     fileid,
-			buttons:    buttons,
-			dataType:   dataType,
+	if exists {
+		// the command message identifies the filter waiting for confirmation
+		overwriteKey := fmt.Sprint(msg.MessageId)
+		m.overwriteFiltersMap[fmt.Sprint(overwriteKey, "_", chat.Id)] = overwriteFilter{
+			triggers: filterTriggerList,
+			text:     text,
+			fileid:   fileid,
+			buttons:  buttons,
+			dataType: dataType,
 		}
-		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		confirmText, _ := tr.GetString("filters_overwrite_confirm")
 		yesText, _ := tr.GetString("common_yes")
 		noText, _ := tr.GetString("common_no")
@@ -124,7 +154,7 @@ func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 						{
 							{
 								Text:         yesText,
-								CallbackData: "filters_overwrite." + filterWord,
+								CallbackData: "filters_overwrite." + overwriteKey,
 							},
 							{
 								Text:         noText,
@@ -142,11 +172,15 @@ func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
-	go db.AddFilter(chat.Id, filterWord, text, fileid, buttons, dataType)
-
-	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
-	successText, _ := tr.GetString("filters_added_success")
-	_, err := msg.Reply(b, fmt.Sprintf(successText, filterWord), helpers.Shtml())
+	var replyText string
+	if err := db.AddFilter(chat.Id, filterTriggerList, text, fileid, buttons, dataType); err != nil {
+		replyText, _ = tr.GetString("filters_add_failed")
+	} else {
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+		successText, _ := tr.GetString("filters_added_success")
+		replyText = fmt.Sprintf(successText, filterTriggersText(triggers))
+	}
+	_, err := msg.Reply(b, replyText, helpers.Shtml())
 	if err != nil {
 		log.Error(err)
 		return err
@@ -155,6 +189,74 @@ func (m moduleStruct) addFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// filterTriggers reads the triggers of a filter from its keyword: a single trigger, quoted or not,
+// or a list of them in parentheses separated by commas, as in (hi, hello, "good morning").
+// Returns the triggers along with descriptions of the invalid ones for HTML replies.
+func filterTriggers(keyWord string) (triggers []keyword_matcher.Trigger, invalid []string) {
+	keyWord = strings.TrimSpace(keyWord)
+	parts := []string{keyWord}
+	if strings.HasPrefix(keyWord, "(") && strings.HasSuffix(keyWord, ")") {
+		parts = splitFilterList(keyWord[1 : len(keyWord)-1])
+	}
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && strings.HasPrefix(part, `"`) && strings.HasSuffix(part, `"`) {
+			part = part[1 : len(part)-1]
+		}
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		trigger, err := keyword_matcher.ParseFilterTrigger(part)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("<code>%s</code>: %s", html.EscapeString(part), html.EscapeString(err.Error())))
+			continue
+		}
+		if !slices.Contains(triggers, trigger) {
+			triggers = append(triggers, trigger)
+		}
+	}
+	return
+}
+
+// splitFilterList splits a list of filter triggers on the commas outside quotes.
+func splitFilterList(s string) []string {
+	var (
+		parts    []string
+		start    int
+		inQuotes bool
+	)
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ',' && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// filterTriggerText returns a filter trigger the way /filter and /stop read it,
+// quoted when it contains spaces, commas or parentheses.
+func filterTriggerText(trigger keyword_matcher.Trigger) string {
+	text := trigger.FilterString()
+	if strings.ContainsAny(text, " ,()") {
+		return `"` + text + `"`
+	}
+	return text
+}
+
+// filterTriggersText lists filter triggers for HTML replies.
+func filterTriggersText(triggers []keyword_matcher.Trigger) string {
+	texts := make([]string, len(triggers))
+	for i, trigger := range triggers {
+		texts[i] = fmt.Sprintf("<code>%s</code>", html.EscapeString(filterTriggerText(trigger)))
+	}
+	return strings.Join(texts, ", ")
+}
+
 /*
 	Used to remove a filter to a specific keyword in chat!
 
@@ -190,26 +292,40 @@ func (moduleStruct) rmFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 			return err
 		}
 	} else {
-
-		filterWord, _ := extraction.ExtractQuotes(strings.Join(args, " "), true, true)
-
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
-		if !string_handling.FindInStringSlice(db.GetFiltersList(chat.Id), strings.ToLower(filterWord)) {
-			text, _ := tr.GetString("filters_not_exists")
-			_, err := msg.Reply(b, text, helpers.Shtml())
-			if err != nil {
-				log.Error(err)
-				return err
+		filterWord, _ := helpers.SplitFilterKeyword(strings.Join(args, " "))
+		triggers, _ := filterTriggers(filterWord)
+
+		var removed, missing []keyword_matcher.Trigger
+		for _, trigger := range triggers {
+			if db.DoesFilterExists(chat.Id, trigger.Pattern, trigger.Mode) {
+				removed = append(removed, trigger)
+			} else {
+				missing = append(missing, trigger)
 			}
+		}
+
+		var text string
+		if len(removed) == 0 {
+			text, _ = tr.GetString("filters_not_exists")
 		} else {
-			go db.RemoveFilter(chat.Id, strings.ToLower(filterWord))
+			for _, trigger := range removed {
+				db.RemoveFilter(chat.Id, trigger.Pattern, trigger.Mode)
+			}
+			logSettingsChange(b, ctx, filtersModule.moduleName, msg.GetText())
 			successText, _ := tr.GetString("filters_removed_success")
-			_, err := msg.Reply(b, fmt.Sprintf(successText, filterWord), helpers.Shtml())
-			if err != nil {
-				log.Error(err)
-				return err
+			text = fmt.Sprintf(successText, filterTriggersText(removed))
+			if len(missing) > 0 {
+				missingText, _ := tr.GetString("filters_not_exists_triggers")
+				text += "\n\n" + fmt.Sprintf(missingText, filterTriggersText(missing))
 			}
 		}
+
+		_, err := msg.Reply(b, text, helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
 	}
 	return ext.EndGroups
 }
@@ -222,7 +338,7 @@ func (moduleStruct) rmFilter(b *gotgbot.Bot, ctx *ext.Context) error {
 Any user can view users in a chat
 */
 // filtersList displays all active filter keywords in the current chat.
-// Any user can view the list of available filters with their trigger keywords.
+// Any user can view the list of available filters, with the triggers sharing a reply on the same line.
 func (moduleStruct) filtersList(b *gotgbot.Bot, ctx *ext.Context) error {
 	msg := ctx.EffectiveMessage
 	// if command is disabled, return
@@ -246,12 +362,25 @@ func (moduleStruct) filtersList(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
-	filterKeys := db.GetFiltersList(chat.Id)
 	info, _ := tr.GetString("filters_none_in_chat")
 	newFilterKeys := make([]string, 0)
 
-	for _, fkey := range filterKeys {
-		newFilterKeys = append(newFilterKeys, fmt.Sprintf("<code>%s</code>", html.EscapeString(fkey)))
+	// group the triggers of each filter in the order they were added
+	var groupIds []int64
+	groups := make(map[int64][]keyword_matcher.Trigger)
+	for _, filter := range db.GetFilters(chat.Id) {
+		groupId := filter.GroupId
+		if groupId == 0 {
+			groupId = int64(filter.ID)
+		}
+		if _, ok := groups[groupId]; !ok {
+			groupIds = append(groupIds, groupId)
+		}
+		groups[groupId] = append(groups[groupId], filterTrigger(filter))
+	}
+	slices.Sort(groupIds)
+	for _, groupId := range groupIds {
+		newFilterKeys = append(newFilterKeys, filterTriggersText(groups[groupId]))
 	}
 
 	if len(newFilterKeys) > 0 {
@@ -349,6 +478,7 @@ func (moduleStruct) filtersButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error
 	switch response {
 	case "yes":
 		db.RemoveAllFilters(chat.Id)
+		logSettingsChange(b, ctx, filtersModule.moduleName, "/stopall")
 		helpText, _ = tr.GetString("filters_clear_all_success")
 	case "no":
 		helpText, _ = tr.GetString("filters_clear_all_cancelled")
@@ -391,16 +521,23 @@ func (m moduleStruct) filterOverWriteHandler(b *gotgbot.Bot, ctx *ext.Context) e
 
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 	args := strings.Split(query.Data, ".")
-	filterWord := args[1]
-	filterWordKey := fmt.Sprint(filterWord, "_", chat.Id)
+	filterWordKey := fmt.Sprint(args[1], "_", chat.Id)
 	var helpText string
-	filterData := m.overwriteFiltersMap[filterWordKey]
+	filterData, ok := m.overwriteFiltersMap[filterWordKey]
 
-	if db.DoesFilterExists(chat.Id, filterWord) {
-		db.RemoveFilter(chat.Id, filterWord)
-		db.AddFilter(chat.Id, filterData.filterWord, filterData.text, filterData.fileid, filterData.buttons, filterData.dataType)
+	if ok {
+		triggers := make([]keyword_matcher.Trigger, len(filterData.triggers))
+		for i, trigger := range filterData.triggers {
+			triggers[i] = keyword_matcher.Trigger{Pattern: trigger.KeyWord, Mode: trigger.MatchMode}
+		}
 		delete(m.overwriteFiltersMap, filterWordKey) // delete the key to make map clear
-		helpText, _ = tr.GetString("filters_overwrite_success")
+		// the existing filters of the triggers are replaced in the same transaction
+		if err := db.AddFilter(chat.Id, filterData.triggers, filterData.text, filterData.fileid, filterData.buttons, filterData.dataType); err != nil {
+			helpText, _ = tr.GetString("filters_add_failed")
+		} else {
+			logSettingsChange(b, ctx, m.moduleName, "/filter "+filterTriggersText(triggers))
+			helpText, _ = tr.GetString("filters_overwrite_success")
+		}
 	} else {
 		helpText, _ = tr.GetString("filters_overwrite_cancelled")
 	}
@@ -451,17 +588,17 @@ func (moduleStruct) filtersWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.ContinueGroups
 	}
 
-	// Build keyword list for Aho-Corasick matching
-	filterKeys := make([]string, len(allFilters))
-	filterMap := make(map[string]*db.ChatFilters, len(allFilters))
+	// Build trigger list for Aho-Corasick matching, each with the match mode of its filter
+	filterKeys := make([]keyword_matcher.Trigger, len(allFilters))
+	filterMap := make(map[keyword_matcher.Trigger]*db.ChatFilters, len(allFilters))
 	for i, filter := range allFilters {
-		filterKeys[i] = filter.KeyWord
-		filterMap[filter.KeyWord] = filter
+		filterKeys[i] = filterTrigger(filter)
+		filterMap[filterKeys[i]] = filter
 	}
 
-	// Use Aho-Corasick for efficient multi-pattern matching
-	cache := keyword_matcher.GetGlobalCache()
-	matcher := cache.GetOrCreateMatcher(chat.Id, filterKeys)
+	// Use Aho-Corasick for efficient multi-pattern matching, with the text normalization set by /blnormalize
+	cache := keyword_matcher.GetCache("filters")
+	matcher := cache.GetOrCreateTriggerMatcher(chat.Id, filterKeys, db.GetBlacklistChatSettings(chat.Id).Normalization)
 
 	// Check for any filter match first
 	if !matcher.HasMatch(msg.Text) {
@@ -476,14 +613,12 @@ func (moduleStruct) filtersWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 
 	// Process first match (same behavior as before)
 	firstMatch := matches[0]
-	i := firstMatch.Pattern
 
-	// Check for noformat pattern using simpler string matching
-	noformatPattern := i + " noformat"
-	noformatMatch := strings.Contains(strings.ToLower(msg.Text), strings.ToLower(noformatPattern))
+	// Check for noformat right after the matched trigger
+	noformatMatch := strings.HasPrefix(strings.ToLower(msg.Text[firstMatch.End:]), " noformat")
 
 	// Get filter data from pre-loaded map (no additional DB query)
-	filtData, exists := filterMap[i]
+	filtData, exists := filterMap[keyword_matcher.Trigger{Pattern: firstMatch.Pattern, Mode: firstMatch.Mode}]
 	if !exists {
 		return ext.ContinueGroups
 	}
@@ -528,6 +663,16 @@ func (moduleStruct) filtersWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.ContinueGroups
 }
 
+// filterTrigger returns the trigger of a filter along with its match mode.
+// Filters saved before match modes existed match anywhere in the message.
+func filterTrigger(filter *db.ChatFilters) keyword_matcher.Trigger {
+	mode := filter.MatchMode
+	if mode == "" {
+		mode = keyword_matcher.ModeSubstring
+	}
+	return keyword_matcher.Trigger{Pattern: filter.KeyWord, Mode: mode}
+}
+
 // LoadFilters registers all filter-related handlers with the dispatcher.
 // Sets up commands for managing filters and the message watcher for automatic responses.
 func LoadFilters(dispatcher *ext.Dispatcher) {
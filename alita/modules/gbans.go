@@ -0,0 +1,293 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
+	"github.com/eko/gocache/lib/v4/store"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/config"
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/i18n"
+	"github.com/divideprojects/Alita_Robot/alita/utils/cache"
+	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
+	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+)
+
+var gbansModule = moduleStruct{
+	moduleName:   "Gbans",
+	handlerGroup: -3,
+}
+
+// gbanWatcherSkipTTL is how long the gban watcher leaves a gbanned user alone in a chat
+// after banning them there, or failing to.
+const gbanWatcherSkipTTL = 10 * time.Minute
+
+// canGban checks whether a user is allowed to manage global bans.
+// The bot owner, devs and sudo users can gban.
+func canGban(userId int64) bool {
+	if userId == config.OwnerId {
+		return true
+	}
+	memStatus := db.GetTeamMemInfo(userId)
+	return memStatus.Sudo || memStatus.IsDev || memStatus.Dev
+}
+
+// broadcastGban sends a gban log message to the MessageDump chat if broadcasting is enabled.
+func broadcastGban(b *gotgbot.Bot, text string) {
+	if !config.GbanBroadcast {
+		return
+	}
+	_, err := b.SendMessage(config.MessageDump, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+	}
+}
+
+// gban handles the /gban command to ban a user in every chat of the bot.
+// Only accessible by team members, and a reason is required.
+func (moduleStruct) gban(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	if !canGban(user.Id) {
+		return ext.ContinueGroups
+	}
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	userId, reason := extraction.ExtractUserAndText(b, ctx)
+	if userId == -1 {
+		return ext.EndGroups
+	}
+
+	var text string
+	switch {
+	case userId == 0:
+		text, _ = tr.GetString("gbans_no_user_specified")
+	case helpers.IsChannelID(userId):
+		text, _ = tr.GetString("gbans_user_is_channel")
+	case userId == b.Id:
+		text, _ = tr.GetString("gbans_is_bot_itself")
+	case canGban(userId):
+		text, _ = tr.GetString("gbans_is_team_member")
+	case strings.TrimSpace(reason) == "":
+		text, _ = tr.GetString("gbans_reason_required")
+	default:
+		alreadyBanned, err := db.GbanUser(userId, user.Id, reason)
+		if err != nil {
+			text, _ = tr.GetString("gbans_action_failed")
+			break
+		}
+
+		var temp string
+		if alreadyBanned {
+			temp, _ = tr.GetString("gbans_updated")
+		} else {
+			temp, _ = tr.GetString("gbans_gbanned")
+		}
+		text = fmt.Sprintf(temp, mentionUserById(userId), html.EscapeString(reason))
+
+		if chat.Type != "private" && db.IsGbanEnforced(chat.Id) {
+			_, err = chat.BanMember(b, userId, nil)
+			if err != nil {
+				log.Debugf("[Gbans] Failed to ban %d in %d: %v", userId, chat.Id, err)
+			}
+		}
+
+		temp, _ = tr.GetString("gbans_log_gban")
+		go broadcastGban(b, fmt.Sprintf(temp,
+			helpers.MentionHtml(user.Id, user.FirstName),
+			mentionUserById(userId),
+			userId,
+			html.EscapeString(reason),
+		))
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// ungban handles the /ungban command to lift a global ban.
+// Only accessible by team members.
+func (moduleStruct) ungban(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	if !canGban(user.Id) {
+		return ext.ContinueGroups
+	}
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	userId, _ := extraction.ExtractUserAndText(b, ctx)
+	if userId == -1 {
+		return ext.EndGroups
+	}
+
+	var text string
+	switch {
+	case userId == 0:
+		text, _ = tr.GetString("gbans_no_user_specified")
+	case !db.UngbanUser(userId):
+		temp, _ := tr.GetString("gbans_not_gbanned")
+		text = fmt.Sprintf(temp, mentionUserById(userId))
+	default:
+		temp, _ := tr.GetString("gbans_ungbanned")
+		text = fmt.Sprintf(temp, mentionUserById(userId))
+
+		temp, _ = tr.GetString("gbans_log_ungban")
+		go broadcastGban(b, fmt.Sprintf(temp,
+			helpers.MentionHtml(user.Id, user.FirstName),
+			mentionUserById(userId),
+			userId,
+		))
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// gbanStat handles the /gbanstat command to toggle gban enforcement in a chat.
+// Without arguments, shows whether gbans are currently enforced.
+func (moduleStruct) gbanStat(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	if len(args) >= 2 {
+		switch strings.ToLower(args[1]) {
+		case "on", "yes", "true":
+			if err := db.SetGbanEnforcement(chat.Id, true); err != nil {
+				text, _ = tr.GetString("gbans_action_failed")
+			} else {
+				text, _ = tr.GetString("gbans_gbanstat_enabled")
+			}
+		case "off", "no", "false":
+			if err := db.SetGbanEnforcement(chat.Id, false); err != nil {
+				text, _ = tr.GetString("gbans_action_failed")
+			} else {
+				text, _ = tr.GetString("gbans_gbanstat_disabled")
+			}
+		default:
+			text, _ = tr.GetString("gbans_gbanstat_invalid_option")
+		}
+	} else if db.IsGbanEnforced(chat.Id) {
+		text, _ = tr.GetString("gbans_gbanstat_current_on")
+	} else {
+		text, _ = tr.GetString("gbans_gbanstat_current_off")
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// enforceGban bans a user from the current chat if they are gbanned and the chat enforces gbans.
+// Chats where the bot can't ban are skipped silently.
+// Returns true if the user was removed from the chat.
+func enforceGban(b *gotgbot.Bot, ctx *ext.Context, user gotgbot.User) bool {
+	chat := ctx.EffectiveChat
+
+	gban := db.GetGban(user.Id)
+	if gban == nil || !db.IsGbanEnforced(chat.Id) {
+		return false
+	}
+	if !chat_status.CanBotRestrict(b, ctx, chat, true) {
+		return false
+	}
+
+	_, err := chat.BanMember(b, user.Id, nil)
+	if err != nil {
+		log.Debugf("[Gbans] Failed to enforce gban of %d in %d: %v", user.Id, chat.Id, err)
+		return false
+	}
+
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	temp, _ := tr.GetString("gbans_enforced")
+	text := fmt.Sprintf(temp, helpers.MentionHtml(user.Id, user.FirstName), html.EscapeString(gban.Reason))
+	_, err = b.SendMessage(chat.Id, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+	}
+	return true
+}
+
+// gbanWatcherSkipKey returns the cache key marking that the gban watcher already
+// handled a gbanned user in a chat.
+func gbanWatcherSkipKey(chatId, userId int64) string {
+	return fmt.Sprintf("alita:gban_skip:%d:%d", chatId, userId)
+}
+
+// gbanWatcher enforces gbans on users who were already in the chat when they got gbanned.
+// Removes the user and their message the first time they speak. Once a user was banned,
+// or couldn't be, the watcher leaves them alone for gbanWatcherSkipTTL so messages still
+// on their way don't make the bot try again.
+func (moduleStruct) gbanWatcher(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+
+	if user == nil || chat.Type == "private" {
+		return ext.ContinueGroups
+	}
+
+	// gbans are cached, so most messages stop here without touching the skip marks
+	if db.GetGban(user.Id) == nil {
+		return ext.ContinueGroups
+	}
+	skipKey := gbanWatcherSkipKey(chat.Id, user.Id)
+	var skipped bool
+	if _, err := cache.Marshal.Get(cache.Context, skipKey, &skipped); err == nil && skipped {
+		return ext.ContinueGroups
+	}
+
+	enforced := enforceGban(b, ctx, *user)
+	if err := cache.Marshal.Set(cache.Context, skipKey, true, store.WithExpiration(gbanWatcherSkipTTL)); err != nil {
+		log.Debugf("[Gbans] Failed to mark gban of %d in %d as handled: %v", user.Id, chat.Id, err)
+	}
+	if !enforced {
+		return ext.ContinueGroups
+	}
+
+	_, err := ctx.EffectiveMessage.Delete(b, nil)
+	if err != nil {
+		log.Debugf("[Gbans] Failed to delete message of gbanned user %d: %v", user.Id, err)
+	}
+	return ext.EndGroups
+}
+
+// LoadGbans registers the gban commands and the gban enforcement watcher with the dispatcher.
+func LoadGbans(dispatcher *ext.Dispatcher) {
+	HelpModule.AbleMap.Store(gbansModule.moduleName, true)
+
+	dispatcher.AddHandler(handlers.NewCommand("gban", gbansModule.gban))
+	dispatcher.AddHandler(handlers.NewCommand("ungban", gbansModule.ungban))
+	dispatcher.AddHandler(handlers.NewCommand("gbanstat", gbansModule.gbanStat))
+	dispatcher.AddHandlerToGroup(handlers.NewMessage(message.All, gbansModule.gbanWatcher), gbansModule.handlerGroup)
+}
@@ -92,11 +92,13 @@ func (moduleStruct) welcome(bot *gotgbot.Bot, ctx *ext.Context) error {
 		switch strings.ToLower(args[0]) {
 		case "on", "yes":
 			db.SetWelcomeToggle(chat.Id, true)
+			logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 			tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 			text, _ := tr.GetString("greetings_welcome_enabled")
 			_, err = msg.Reply(bot, text, helpers.Shtml())
 		case "off", "no":
 			db.SetWelcomeToggle(chat.Id, false)
+			logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 			tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 			text, _ := tr.GetString("greetings_welcome_disabled")
 			_, err = msg.Reply(bot, text, helpers.Shtml())
@@ -143,6 +145,7 @@ func (moduleStruct) setWelcome(bot *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	db.SetWelcomeText(chat.Id, text, content, buttons, dataType)
+	logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 	successText, _ := tr.GetString("greetings_welcome_set_success")
 	_, err := msg.Reply(bot, successText, helpers.Shtml())
@@ -172,6 +175,7 @@ func (moduleStruct) resetWelcome(bot *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	go db.SetWelcomeText(chat.Id, db.DefaultWelcome, "", nil, db.TEXT)
+	logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 	successText, _ := tr.GetString("greetings_welcome_reset_success")
 	_, err := msg.Reply(bot, successText, helpers.Shtml())
@@ -250,11 +254,13 @@ func (moduleStruct) goodbye(bot *gotgbot.Bot, ctx *ext.Context) error {
 		switch strings.ToLower(args[0]) {
 		case "on", "yes":
 			db.SetGoodbyeToggle(chat.Id, true)
+			logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 			tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 			text, _ := tr.GetString("greetings_goodbye_enable")
 			_, err = msg.Reply(bot, text, helpers.Shtml())
 		case "off", "no":
 			db.SetGoodbyeToggle(chat.Id, false)
+			logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 			tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 			text, _ := tr.GetString("greetings_goodbye_disable")
 			_, err = msg.Reply(bot, text, helpers.Shtml())
@@ -299,6 +305,7 @@ func (moduleStruct) setGoodbye(bot *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	db.SetGoodbyeText(chat.Id, text, content, buttons, dataType)
+	logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 	successText, _ := tr.GetString("greetings_goodbye_set_success")
 	_, err := msg.Reply(bot, successText, helpers.Shtml())
@@ -331,6 +338,7 @@ func (moduleStruct) resetGoodbye(bot *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 	go db.SetGoodbyeText(chat.Id, db.DefaultGoodbye, "", nil, db.TEXT)
+	logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 	successText, _ := tr.GetString("greetings_goodbye_reset")
 	_, err := msg.Reply(bot, successText, helpers.Shtml())
@@ -381,11 +389,13 @@ func (moduleStruct) cleanWelcome(bot *gotgbot.Bot, ctx *ext.Context) error {
 	switch strings.ToLower(args[0]) {
 	case "off", "no":
 		db.SetCleanWelcomeSetting(chat.Id, false)
+		logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		text, _ := tr.GetString("greetings_clean_welcome_disable")
 		_, err = msg.Reply(bot, text, helpers.Shtml())
 	case "on", "yes":
 		db.SetCleanWelcomeSetting(chat.Id, true)
+		logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		text, _ := tr.GetString("greetings_clean_welcome_enable")
 		_, err = msg.Reply(bot, text, helpers.Shtml())
@@ -442,11 +452,13 @@ func (moduleStruct) cleanGoodbye(bot *gotgbot.Bot, ctx *ext.Context) error {
 	switch strings.ToLower(args[0]) {
 	case "off", "no":
 		db.SetCleanGoodbyeSetting(chat.Id, false)
+		logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		text, _ := tr.GetString("greetings_clean_goodbye_disable")
 		_, err = msg.Reply(bot, text, helpers.Shtml())
 	case "on", "yes":
 		db.SetCleanGoodbyeSetting(chat.Id, true)
+		logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		text, _ := tr.GetString("greetings_clean_goodbye_enable")
 		_, err = msg.Reply(bot, text, helpers.Shtml())
@@ -502,11 +514,13 @@ func (moduleStruct) delJoined(bot *gotgbot.Bot, ctx *ext.Context) error {
 	switch strings.ToLower(args[0]) {
 	case "off", "no":
 		db.SetShouldCleanService(chat.Id, false)
+		logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		text, _ := tr.GetString("greetings_clean_service_disable")
 		_, err = msg.Reply(bot, text, helpers.Shtml())
 	case "on", "yes":
 		db.SetShouldCleanService(chat.Id, true)
+		logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		text, _ := tr.GetString("greetings_clean_service_enable")
 		_, err = msg.Reply(bot, text, helpers.Shtml())
@@ -576,9 +590,15 @@ func (moduleStruct) newMember(bot *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
+	// Remove gbanned users and users banned in the federation of this chat
+	if enforceGban(bot, ctx, newMember) || enforceFedBan(bot, ctx, newMember) {
+		return ext.EndGroups
+	}
+	logMemberJoin(bot, ctx, newMember)
+
 	// Check if captcha is enabled
 	captchaSettings, _ := db.GetCaptchaSettings(chat.Id)
-	if captchaSettings.Enabled {
+	if captchaSettings.Enabled && !db.IsUserApproved(chat.Id, newMember.Id) {
 		// Mute the new member immediately
 		_, err := chat.RestrictMember(bot, newMember.Id, gotgbot.ChatPermissions{
 			CanSendMessages:       false,
@@ -648,6 +668,7 @@ func (moduleStruct) leftMember(bot *gotgbot.Bot, ctx *ext.Context) error {
 	if leftMember.Id == bot.Id {
 		return ext.EndGroups
 	}
+	logMemberEvent(bot, ctx, "logs_member_left", leftMember)
 
 	// Clean up any pending captcha for the leaving user
 	captchaAttempt, err := db.GetCaptchaAttempt(leftMember.Id, chat.Id)
@@ -708,7 +729,13 @@ func processSingleNewMember(bot *gotgbot.Bot, ctx *ext.Context, newMember gotgbo
 		return
 	}
 
-	if captchaEnabled {
+	// Remove gbanned users and users banned in the federation of this chat
+	if enforceGban(bot, ctx, newMember) || enforceFedBan(bot, ctx, newMember) {
+		return
+	}
+	logMemberJoin(bot, ctx, newMember)
+
+	if captchaEnabled && !db.IsUserApproved(chat.Id, newMember.Id) {
 		// Mute the new member immediately
 		_, err := chat.RestrictMember(bot, newMember.Id, gotgbot.ChatPermissions{
 			CanSendMessages:       false,
@@ -995,11 +1022,13 @@ func (moduleStruct) autoApprove(bot *gotgbot.Bot, ctx *ext.Context) error {
 	switch strings.ToLower(args[0]) {
 	case "off", "no":
 		db.SetShouldAutoApprove(chat.Id, false)
+		logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		text, _ := tr.GetString("greetings_auto_approve_disable")
 		_, err = msg.Reply(bot, text, helpers.Shtml())
 	case "on", "yes":
 		db.SetShouldAutoApprove(chat.Id, true)
+		logSettingsChange(bot, ctx, greetingsModule.moduleName, msg.GetText())
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		text, _ := tr.GetString("greetings_auto_approve_enable")
 		_, err = msg.Reply(bot, text, helpers.Shtml())
@@ -13,6 +13,7 @@ import (
 	"github.com/divideprojects/Alita_Robot/alita/db"
 	"github.com/divideprojects/Alita_Robot/alita/i18n"
 	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
 	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
 
 	"github.com/divideprojects/Alita_Robot/alita/utils/string_handling"
@@ -30,7 +31,6 @@ type moduleStruct struct {
 	defaultRulesBtn     string
 	overwriteFiltersMap map[string]overwriteFilter
 	overwriteNotesMap   map[string]overwriteNote
-	antiSpam            map[int64]*antiSpamInfo
 	AbleMap             moduleEnabled
 	AltHelpOptions      map[string][]string
 	helpableKb          map[string][][]gotgbot.InlineKeyboardButton
@@ -38,11 +38,11 @@ type moduleStruct struct {
 
 // struct for filters module
 type overwriteFilter struct {
-	filterWord string
-	text       string
-	fileid     string
-	buttons    []db.Button
-	dataType   int
+	triggers []db.FilterTrigger
+	text     string
+	fileid   string
+	buttons  []db.Button
+	dataType int
 }
 
 // struct for notes module
@@ -60,18 +60,13 @@ type overwriteNote struct {
 	noNotif     bool
 }
 
-// struct for antiSpam module - antiSpamInfo
-type antiSpamInfo struct {
-	Levels []antiSpamLevel
-}
-
 // struct for antiSpam module - antiSpamLevel
+// Limit is the most messages allowed within each window of length Expiry,
+// counted for each user on their own when PerUser is set.
 type antiSpamLevel struct {
-	Count    int
-	Limit    int
-	CurrTime time.Duration
-	Expiry   time.Duration
-	Spammed  bool
+	Limit   int
+	Expiry  time.Duration
+	PerUser bool
 }
 
 // helper functions for help module
@@ -379,3 +374,113 @@ func getHelpTextAndMarkup(ctx *ext.Context, module string) (helpText string, kbm
 
 	return
 }
+
+// mentionUserById returns an HTML mention for a user stored in the database,
+// falling back to the user ID as name when the user is not known to the bot.
+func mentionUserById(userId int64) string {
+	_, name, found := extraction.GetUserInfo(userId)
+	if !found {
+		name = strconv.FormatInt(userId, 10)
+	}
+	return helpers.MentionHtml(userId, name)
+}
+
+// displayTimeLayout is the date format used when listing stored moderation records.
+const displayTimeLayout = "2006-01-02 15:04 UTC"
+
+// durationUntil converts a unix time returned by extraction.ExtractTime into
+// a duration in seconds from now, rounded to whole minutes.
+func durationUntil(untilDate int64) int64 {
+	return (untilDate - time.Now().Unix() + 30) / 60 * 60
+}
+
+// punishmentDisplay formats a punishment along with its duration for timed actions.
+func punishmentDisplay(tr *i18n.Translator, action string, duration int64) string {
+	if duration > 0 {
+		return fmt.Sprintf("%s %s", action, formatWarnTime(tr, duration))
+	}
+	return action
+}
+
+// defaultChatPermissions returns the permissions members of a new group get,
+// used when the permissions a chat had before a lockdown can't be restored.
+func defaultChatPermissions() gotgbot.ChatPermissions {
+	return gotgbot.ChatPermissions{
+		CanSendMessages:       true,
+		CanSendAudios:         true,
+		CanSendDocuments:      true,
+		CanSendPhotos:         true,
+		CanSendVideos:         true,
+		CanSendVideoNotes:     true,
+		CanSendVoiceNotes:     true,
+		CanSendPolls:          true,
+		CanSendOtherMessages:  true,
+		CanAddWebPagePreviews: true,
+		CanInviteUsers:        true,
+	}
+}
+
+// applyPunishment mutes, kicks or bans a user, for the given duration in seconds
+// in case of the timed tmute and tban actions.
+// Timed actions are lifted by Telegram once their duration has passed, and kicked
+// users are unbanned a few seconds later so they can rejoin.
+func applyPunishment(b *gotgbot.Bot, chat *gotgbot.Chat, userId int64, action string, duration int64) error {
+	var untilDate int64
+	if duration > 0 {
+		untilDate = time.Now().Unix() + duration
+	}
+
+	switch action {
+	case "mute", "tmute":
+		_, err := chat.RestrictMember(b, userId,
+			gotgbot.ChatPermissions{
+				CanSendMessages:       false,
+				CanSendPhotos:         false,
+				CanSendVideos:         false,
+				CanSendAudios:         false,
+				CanSendDocuments:      false,
+				CanSendVideoNotes:     false,
+				CanSendVoiceNotes:     false,
+				CanAddWebPagePreviews: false,
+				CanChangeInfo:         false,
+				CanInviteUsers:        false,
+				CanPinMessages:        false,
+				CanManageTopics:       false,
+				CanSendPolls:          false,
+				CanSendOtherMessages:  false,
+			},
+			&gotgbot.RestrictChatMemberOpts{
+				UntilDate: untilDate,
+			},
+		)
+		return err
+	case "ban", "tban":
+		_, err := chat.BanMember(b, userId, &gotgbot.BanChatMemberOpts{UntilDate: untilDate})
+		return err
+	case "kick":
+		_, err := chat.BanMember(b, userId, nil)
+		if err != nil {
+			return err
+		}
+
+		// Use non-blocking delayed unban so the user can rejoin
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.WithField("panic", r).Error("Panic in punishment delayed unban goroutine")
+				}
+			}()
+
+			time.Sleep(3 * time.Second)
+			_, unbanErr := chat.UnbanMember(b, userId, nil)
+			if unbanErr != nil {
+				log.WithFields(log.Fields{
+					"chatId": chat.Id,
+					"userId": userId,
+					"error":  unbanErr,
+				}).Error("Failed to unban user after kick")
+			}
+		}()
+	}
+	return nil
+}
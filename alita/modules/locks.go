@@ -1,10 +1,14 @@
 package modules
 
 import (
+	"errors"
 	"fmt"
+	"html"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/message"
@@ -17,7 +21,10 @@ import (
 	"github.com/divideprojects/Alita_Robot/alita/db"
 	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
 	"github.com/divideprojects/Alita_Robot/alita/utils/decorators/misc"
+	"github.com/divideprojects/Alita_Robot/alita/utils/domains"
+	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
 	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+	"github.com/divideprojects/Alita_Robot/alita/utils/scheduler"
 
 	"github.com/divideprojects/Alita_Robot/alita/i18n"
 	"github.com/divideprojects/Alita_Robot/alita/utils/string_handling"
@@ -30,6 +37,9 @@ var (
 		restrHandlerGroup: 6,
 	}
 	arabmatch, _                 = regexp.Compile("[\u0600-\u06FF]") // the regex detects the arabic language
+	cyrmatch, _                  = regexp.Compile(`\p{Cyrillic}`)
+	cjkmatch, _                  = regexp.Compile(`[\p{Han}\p{Hiragana}\p{Katakana}\p{Hangul}]`)
+	devmatch, _                  = regexp.Compile(`\p{Devanagari}`)
 	GIF          filters.Message = message.Animation
 	OTHER        filters.Message = func(msg *gotgbot.Message) bool {
 		return msg.Game != nil || msg.Sticker != nil || message.Animation(msg)
@@ -61,7 +71,7 @@ var (
 		"contact":   message.Contact,
 		"photo":     message.Photo,
 		"gif":       message.Animation,
-		"url":       message.Entity("url"),
+		"url":       linkLock,
 		"bots":      message.NewChatMembers,
 		"forward":   message.Forwarded,
 		"game":      message.Game,
@@ -72,6 +82,34 @@ var (
 		"anonchannel": func(msg *gotgbot.Message) bool {
 			return msg.GetSender().IsAnonymousChannel() || !msg.GetSender().IsLinkedChannel()
 		},
+		"poll":   message.Poll,
+		"inline": message.ViaBot,
+		"dice":   message.Dice,
+		"story":  message.Story,
+		"spoiler": func(msg *gotgbot.Message) bool {
+			return msg.HasMediaSpoiler || entityLock("spoiler")(msg)
+		},
+		// the mention lock only applies above the max mentions of the chat, see permHandler
+		"mention": func(msg *gotgbot.Message) bool {
+			return countMentions(msg) > 0
+		},
+		"shortlink": func(msg *gotgbot.Message) bool {
+			return slices.ContainsFunc(linkHosts(msg), domains.IsShortLink)
+		},
+		"hashtag":     entityLock("hashtag"),
+		"cashtag":     entityLock("cashtag"),
+		"phone":       entityLock("phone_number"),
+		"email":       entityLock("email"),
+		"customemoji": entityLock("custom_emoji"),
+		"cyrillic": func(msg *gotgbot.Message) bool {
+			return cyrmatch.MatchString(msg.Text) || cyrmatch.MatchString(msg.Caption)
+		},
+		"cjk": func(msg *gotgbot.Message) bool {
+			return cjkmatch.MatchString(msg.Text) || cjkmatch.MatchString(msg.Caption)
+		},
+		"devanagari": func(msg *gotgbot.Message) bool {
+			return devmatch.MatchString(msg.Text) || devmatch.MatchString(msg.Caption)
+		},
 	}
 
 	restrMap = map[string]filters.Message{
@@ -84,6 +122,56 @@ var (
 	}
 )
 
+// entityLock returns a filter matching messages whose text or caption contains an entity of the given type.
+func entityLock(entType string) filters.Message {
+	return func(msg *gotgbot.Message) bool {
+		return message.Entity(entType)(msg) || message.CaptionEntity(entType)(msg)
+	}
+}
+
+// linkLock matches messages with links, whether written out or hidden behind text.
+func linkLock(msg *gotgbot.Message) bool {
+	return entityLock("url")(msg) || entityLock("text_link")(msg)
+}
+
+// invalidLinkHost stands for a link whose host can't be normalized. It never matches a
+// domain, so such links are treated like links to any domain that isn't allowed.
+const invalidLinkHost = "<invalid>"
+
+// linkHosts returns the normalized hosts of the links in the text or caption of a message,
+// with invalidLinkHost for the links that don't have a valid domain.
+func linkHosts(msg *gotgbot.Message) (hosts []string) {
+	if !linkLock(msg) {
+		return nil
+	}
+	accepted := map[string]struct{}{"url": {}, "text_link": {}}
+	entities := append(msg.ParseEntityTypes(accepted), msg.ParseCaptionEntityTypes(accepted)...)
+	for _, ent := range entities {
+		link := ent.Text
+		if ent.Type == "text_link" {
+			link = ent.Url
+		}
+		host, ok := domains.Normalize(link)
+		if !ok {
+			host = invalidLinkHost
+		}
+		hosts = append(hosts, host)
+	}
+	return
+}
+
+// countMentions counts the users mentioned in the text or caption of a message.
+func countMentions(msg *gotgbot.Message) (count int) {
+	for _, entities := range [][]gotgbot.MessageEntity{msg.Entities, msg.CaptionEntities} {
+		for _, ent := range entities {
+			if ent.Type == "mention" || ent.Type == "text_mention" {
+				count++
+			}
+		}
+	}
+	return
+}
+
 // getLockMapAsArray returns a sorted array of all available lock types
 // by combining restriction types and permission lock types.
 func (moduleStruct) getLockMapAsArray() (lockTypes []string) {
@@ -109,6 +197,8 @@ func (moduleStruct) getLockMapAsArray() (lockTypes []string) {
 // currently enabled in the specified chat.
 func (moduleStruct) buildLockTypesMessage(chatID int64) (res string) {
 	chatLocks := db.GetChatLocks(chatID)
+	expiries := db.GetChatLockExpiries(chatID)
+	actions := db.GetChatLockActions(chatID)
 
 	newMapLocks := chatLocks
 	tr := i18n.MustNewTranslator(db.GetLanguage(&ext.Context{EffectiveChat: &gotgbot.Chat{Id: chatID}}))
@@ -122,6 +212,18 @@ func (moduleStruct) buildLockTypesMessage(chatID int64) (res string) {
 	var sb strings.Builder
 	for _, k := range keys {
 		sb.WriteString(fmt.Sprintf("\n - %s = %v", k, newMapLocks[k]))
+		if expiresAt, ok := expiries[k]; ok && newMapLocks[k] {
+			expiry, _ := tr.GetString("locks_expires_in", i18n.TranslationParams{
+				"duration": formatWarnTime(tr, max(durationUntil(expiresAt.Unix()), 60)),
+			})
+			sb.WriteString(expiry)
+		}
+		if lock, ok := actions[k]; ok {
+			action, _ := tr.GetString("locks_action_suffix", i18n.TranslationParams{
+				"action": punishmentDisplay(tr, lock.Action, lock.ActionDuration),
+			})
+			sb.WriteString(action)
+		}
 	}
 	res += sb.String()
 
@@ -202,6 +304,17 @@ func (m moduleStruct) lockPerm(b *gotgbot.Bot, ctx *ext.Context) error {
 		return ext.EndGroups
 	}
 
+	// a trailing duration, as in /lock gif sticker 2h, makes the locks expire
+	var lockDuration time.Duration
+	if len(args) > 1 && !string_handling.FindInStringSlice(m.getLockMapAsArray(), args[len(args)-1]) {
+		untilDate, _, _ := extraction.ExtractTime(b, ctx, args[len(args)-1])
+		if untilDate == -1 {
+			return ext.EndGroups
+		}
+		lockDuration = time.Duration(durationUntil(untilDate)) * time.Second
+		args = args[:len(args)-1]
+	}
+
 	if len(args) == 0 {
 		tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 		text, _ := tr.GetString("locks_what_to_lock")
@@ -227,13 +340,37 @@ func (m moduleStruct) lockPerm(b *gotgbot.Bot, ctx *ext.Context) error {
 		toLock = append(toLock, perm)
 	}
 
-	for _, perm := range toLock {
-		go db.UpdateLock(chat.Id, perm, true)
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	var text string
+	if lockDuration > 0 {
+		expiresAt := time.Now().Add(lockDuration)
+		for _, perm := range toLock {
+			if err := db.UpdateTimedLock(chat.Id, perm, expiresAt); err != nil {
+				text, _ = tr.GetString("locks_action_failed")
+				_, err = msg.Reply(b, text, nil)
+				if err != nil {
+					log.Error(err)
+					return err
+				}
+				return ext.EndGroups
+			}
+		}
+		text, _ = tr.GetString("locks_locked_for_successfully", i18n.TranslationParams{
+			"duration": formatWarnTime(tr, int64(lockDuration.Seconds())),
+			"locks":    strings.Join(toLock, "\n - "),
+		})
+	} else {
+		for _, perm := range toLock {
+			db.UpdateLock(chat.Id, perm, true)
+		}
+		temp, _ := tr.GetString("locks_locked_successfully")
+		text = fmt.Sprintf(temp, strings.Join(toLock, "\n - "))
 	}
+	if err := scheduleLockExpiry(chat.Id); err != nil {
+		log.Errorf("[Locks] Failed to schedule lock expiry of %d: %v", chat.Id, err)
+	}
+	logModAction(b, ctx, db.ModActionLock, 0, strings.Join(toLock, ", "), int64(lockDuration.Seconds()))
 
-	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
-	temp, _ := tr.GetString("locks_locked_successfully")
-	text := fmt.Sprintf(temp, strings.Join(toLock, "\n - "))
 	_, err := msg.Reply(b, text, nil)
 	if err != nil {
 		log.Error(err)
@@ -292,8 +429,12 @@ func (m moduleStruct) unlockPerm(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	for _, perm := range toLock {
-		go db.UpdateLock(chat.Id, perm, false)
+		db.UpdateLock(chat.Id, perm, false)
+	}
+	if err := scheduleLockExpiry(chat.Id); err != nil {
+		log.Errorf("[Locks] Failed to schedule lock expiry of %d: %v", chat.Id, err)
 	}
+	logModAction(b, ctx, db.ModActionUnlock, 0, strings.Join(toLock, ", "), 0)
 
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 	temp, _ := tr.GetString("locks_unlocked_successfully")
@@ -309,71 +450,412 @@ func (m moduleStruct) unlockPerm(b *gotgbot.Bot, ctx *ext.Context) error {
 
 // restHandler monitors messages and deletes them if they match
 // restricted content types that are locked in the chat.
-func (moduleStruct) restHandler(b *gotgbot.Bot, ctx *ext.Context) error {
+func (m moduleStruct) restHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	chat := ctx.EffectiveChat
 	msg := ctx.EffectiveMessage
 	user := ctx.EffectiveSender.User
 
-	var err error
-
 	// don't work on admins and approved users
-	if chat_status.IsUserAdmin(b, chat.Id, user.Id) {
+	if chat_status.IsUserApprovedOrAdmin(b, chat.Id, user.Id) {
 		return ext.ContinueGroups
 	}
 
+	var broken []string
 	for restr, filter := range restrMap {
-		if filter(msg) && db.IsPermLocked(chat.Id, restr) && chat_status.CanBotDelete(b, ctx, nil, true) {
-			if restr == "comments" && msg.From.Id != 777000 {
-				if !chat_status.IsUserInChat(b, chat, user.Id) {
-					_, err = msg.Delete(b, nil)
-					if err != nil {
-						log.Error(err)
-						return err
-					}
-				}
-				break
-			}
-			_, err = msg.Delete(b, nil)
-			if err != nil {
-				log.Error(err)
-				return err
-			}
+		if !filter(msg) || !db.IsPermLocked(chat.Id, restr) {
+			continue
+		}
+		// the comments lock only applies to users who aren't members of the chat
+		if restr == "comments" && msg.From.Id != 777000 && chat_status.IsUserInChat(b, chat, user.Id) {
+			continue
 		}
+		broken = append(broken, restr)
 	}
 
+	if err := m.enforceLocks(b, ctx, broken); err != nil {
+		return err
+	}
 	return ext.ContinueGroups
 }
 
 // permHandler monitors messages and deletes them if they match
 // specific permission locks that are enabled in the chat.
-func (moduleStruct) permHandler(b *gotgbot.Bot, ctx *ext.Context) error {
+func (m moduleStruct) permHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	chat := ctx.EffectiveChat
 	msg := ctx.EffectiveMessage
 	user := ctx.EffectiveSender.User
 
-	var err error
-
 	// don't work on admins and approved users
-	if chat_status.IsUserAdmin(b, chat.Id, user.Id) {
+	if chat_status.IsUserApprovedOrAdmin(b, chat.Id, user.Id) {
 		return ext.ContinueGroups
 	}
 
+	var broken []string
 	for perm, filter := range lockMap {
-		if filter(msg) && db.IsPermLocked(chat.Id, perm) && chat_status.CanBotDelete(b, ctx, nil, true) {
-			if perm == "bots" {
+		if perm == "bots" {
+			continue
+		}
+		if !filter(msg) || !db.IsPermLocked(chat.Id, perm) {
+			continue
+		}
+		if perm == "mention" && countMentions(msg) <= db.GetMaxMentions(chat.Id) {
+			continue
+		}
+		// links to allowed domains pass the url lock
+		if perm == "url" {
+			allowed, _ := db.GetLockDomains(chat.Id)
+			if len(allowed) > 0 && !slices.ContainsFunc(linkHosts(msg), func(host string) bool {
+				return !domains.MatchesAny(host, allowed)
+			}) {
 				continue
 			}
-			_, err = msg.Delete(b, nil)
-			if err != nil {
-				log.Error(err)
-				return err
-			}
+		}
+		broken = append(broken, perm)
+	}
+
+	// links to blocked domains are removed even when the url lock is off,
+	// with the punishment set for the url lock
+	if !slices.Contains(broken, "url") && linkLock(msg) {
+		if _, blocked := db.GetLockDomains(chat.Id); len(blocked) > 0 && slices.ContainsFunc(linkHosts(msg), func(host string) bool {
+			return domains.MatchesAny(host, blocked)
+		}) {
+			broken = append(broken, "url")
 		}
 	}
 
+	if err := m.enforceLocks(b, ctx, broken); err != nil {
+		return err
+	}
 	return ext.ContinueGroups
 }
 
+// lockActionSeverity ranks the lock punishments, so the strictest one is applied
+// when a message breaks several locks at once.
+var lockActionSeverity = map[string]int{
+	db.LockActionDelete: 0,
+	db.LockActionWarn:   1,
+	db.LockActionTMute:  2,
+	db.LockActionMute:   3,
+	db.LockActionKick:   4,
+	db.LockActionTBan:   5,
+	db.LockActionBan:    6,
+}
+
+// enforceLocks deletes a message that broke locks of the chat and punishes its sender
+// with the strictest punishment set for the broken locks.
+func (moduleStruct) enforceLocks(b *gotgbot.Bot, ctx *ext.Context, broken []string) error {
+	if len(broken) == 0 || !chat_status.CanBotDelete(b, ctx, nil, true) {
+		return nil
+	}
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	sender := ctx.EffectiveSender
+
+	_, err := msg.Delete(b, nil)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	slices.Sort(broken)
+	lockType, action, duration := broken[0], db.LockActionDelete, int64(0)
+	for _, perm := range broken {
+		permAction, permDuration := db.GetLockAction(chat.Id, perm)
+		if lockActionSeverity[permAction] > lockActionSeverity[action] {
+			lockType, action, duration = perm, permAction, permDuration
+		}
+	}
+
+	// messages sent on behalf of chats can only be deleted
+	if action == db.LockActionDelete || !sender.IsUser() || !chat_status.CanBotRestrict(b, ctx, nil, true) {
+		return nil
+	}
+
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	if action == db.LockActionWarn {
+		reason, _ := tr.GetString("locks_warn_reason", i18n.TranslationParams{"lock": lockType})
+		err = warnsModule.warnThisUser(b, ctx, sender.Id(), b.Id, reason, "warn")
+		if err != nil && !errors.Is(err, ext.EndGroups) {
+			log.Error(err)
+			return err
+		}
+		return nil
+	}
+
+	err = applyPunishment(b, chat, sender.Id(), action, duration)
+	if err != nil {
+		log.Errorf("[Locks] %s (%d) - %v", action, sender.Id(), err)
+		return err
+	}
+
+	// timed actions are logged as their base action along with the duration
+	entry := newModAction(ctx, strings.TrimPrefix(action, "t"), sender.Id(), fmt.Sprintf("broke the %s lock", lockType), duration)
+	entry.ActorId = b.Id
+	recordModAction(b, ctx, entry)
+
+	text, _ := tr.GetString("locks_punished_"+action, i18n.TranslationParams{
+		"user": helpers.MentionHtml(sender.Id(), sender.Name()),
+		"lock": lockType,
+		"time": formatWarnTime(tr, duration),
+	})
+	_, err = b.SendMessage(chat.Id, text,
+		&gotgbot.SendMessageOpts{
+			ParseMode:       helpers.HTML,
+			MessageThreadId: msg.MessageThreadId,
+		},
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// lockAction handles the /lockaction command to view or set the punishment
+// for breaking a lock, requiring admin permissions.
+func (m moduleStruct) lockAction(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireBotAdmin(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	switch {
+	case len(args) == 0:
+		text, _ = tr.GetString("locks_lockaction_usage")
+	case !string_handling.FindInStringSlice(m.getLockMapAsArray(), args[0]):
+		text, _ = tr.GetString("locks_lockaction_invalid_lock", i18n.TranslationParams{"lock": html.EscapeString(args[0])})
+	case args[0] == "bots":
+		text, _ = tr.GetString("locks_lockaction_bots")
+	case len(args) == 1:
+		action, duration := db.GetLockAction(chat.Id, args[0])
+		text, _ = tr.GetString("locks_lockaction_current", i18n.TranslationParams{
+			"lock":   args[0],
+			"action": punishmentDisplay(tr, action, duration),
+		})
+	default:
+		action := strings.ToLower(args[1])
+		var duration int64
+		switch action {
+		case db.LockActionDelete, db.LockActionWarn, db.LockActionMute, db.LockActionKick, db.LockActionBan:
+		case db.LockActionTMute, db.LockActionTBan:
+			if len(args) < 3 {
+				text, _ = tr.GetString("locks_lockaction_time_required")
+				break
+			}
+			untilDate, _, _ := extraction.ExtractTime(b, ctx, args[2])
+			if untilDate == -1 {
+				return ext.EndGroups
+			}
+			duration = durationUntil(untilDate)
+		default:
+			text, _ = tr.GetString("locks_lockaction_invalid_action", i18n.TranslationParams{"action": html.EscapeString(args[1])})
+		}
+		if text != "" {
+			break
+		}
+
+		if err := db.SetLockAction(chat.Id, args[0], action, duration); err != nil {
+			text, _ = tr.GetString("locks_action_failed")
+			break
+		}
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+		text, _ = tr.GetString("locks_lockaction_set", i18n.TranslationParams{
+			"lock":   args[0],
+			"action": punishmentDisplay(tr, action, duration),
+		})
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// maxMentions handles the /maxmentions command to view or set how many users
+// a message may mention before it breaks the mention lock.
+func (m moduleStruct) maxMentions(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	if len(args) == 0 {
+		text, _ = tr.GetString("locks_max_mentions_current", i18n.TranslationParams{"count": strconv.Itoa(db.GetMaxMentions(chat.Id))})
+	} else if count, err := strconv.Atoi(args[0]); err != nil || count < 1 || count > 100 {
+		text, _ = tr.GetString("locks_max_mentions_invalid")
+	} else if err = db.SetMaxMentions(chat.Id, count); err != nil {
+		text, _ = tr.GetString("locks_action_failed")
+	} else {
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+		text, _ = tr.GetString("locks_max_mentions_set", i18n.TranslationParams{"count": strconv.Itoa(count)})
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// allowDomain handles the /allowdomain command to let links to domains pass the url lock.
+func (m moduleStruct) allowDomain(b *gotgbot.Bot, ctx *ext.Context) error {
+	return m.updateLockDomains(b, ctx, db.DomainListAllow)
+}
+
+// blockDomain handles the /blockdomain command to remove links to domains even when the url lock is off.
+func (m moduleStruct) blockDomain(b *gotgbot.Bot, ctx *ext.Context) error {
+	return m.updateLockDomains(b, ctx, db.DomainListBlock)
+}
+
+// rmDomain handles the /rmdomain command to take domains off the allowlist or blocklist.
+func (m moduleStruct) rmDomain(b *gotgbot.Bot, ctx *ext.Context) error {
+	return m.updateLockDomains(b, ctx, "")
+}
+
+// updateLockDomains puts the domains given as arguments on a domain list of the chat,
+// or takes them off both lists if listType is empty, requiring admin permissions.
+func (m moduleStruct) updateLockDomains(b *gotgbot.Bot, ctx *ext.Context, listType string) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	if len(args) == 0 {
+		text, _ = tr.GetString("locks_domains_none_given")
+	} else {
+		var updated, invalid []string
+		for _, arg := range args {
+			domain, ok := domains.Normalize(arg)
+			if !ok {
+				invalid = append(invalid, html.EscapeString(arg))
+				continue
+			}
+			if listType == "" {
+				if db.RemoveLockDomain(chat.Id, domain) {
+					updated = append(updated, domain)
+				} else {
+					invalid = append(invalid, domain)
+				}
+				continue
+			}
+			if err := db.AddLockDomain(chat.Id, domain, listType, user.Id); err != nil {
+				invalid = append(invalid, domain)
+				continue
+			}
+			updated = append(updated, domain)
+		}
+
+		if len(updated) > 0 {
+			logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+			key := "locks_domains_removed"
+			if listType != "" {
+				key = "locks_domains_" + listType + "ed"
+			}
+			text, _ = tr.GetString(key, i18n.TranslationParams{"domains": strings.Join(updated, "</code>, <code>")})
+		}
+		if len(invalid) > 0 {
+			key := "locks_domains_invalid"
+			if listType == "" {
+				key = "locks_domains_not_listed"
+			}
+			skipped, _ := tr.GetString(key, i18n.TranslationParams{"domains": strings.Join(invalid, "</code>, <code>")})
+			text = strings.TrimPrefix(text+"\n"+skipped, "\n")
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// listDomains handles the /domains command to show the allowlist and blocklist of the url lock.
+func (moduleStruct) listDomains(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	allowed, blocked := db.GetLockDomains(chat.Id)
+	var text string
+	if len(allowed) == 0 && len(blocked) == 0 {
+		text, _ = tr.GetString("locks_domains_empty")
+	} else {
+		var sb strings.Builder
+		for _, list := range []struct {
+			header  string
+			domains []string
+		}{{"locks_domains_allowed_header", allowed}, {"locks_domains_blocked_header", blocked}} {
+			if len(list.domains) == 0 {
+				continue
+			}
+			header, _ := tr.GetString(list.header)
+			sb.WriteString(header)
+			for _, domain := range list.domains {
+				sb.WriteString(fmt.Sprintf("\n - <code>%s</code>", domain))
+			}
+			sb.WriteString("\n\n")
+		}
+		text = strings.TrimSpace(sb.String())
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
 // botLockHandler handles the bots lock by automatically banning
 // bots that are added to the chat when bots lock is enabled.
 func (moduleStruct) botLockHandler(b *gotgbot.Bot, ctx *ext.Context) error {
@@ -382,7 +864,7 @@ func (moduleStruct) botLockHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	mem := ctx.ChatMember.NewChatMember.MergeChatMember().User
 
 	// don't work on admins and approved users
-	if chat_status.IsUserAdmin(b, chat.Id, user.Id) {
+	if chat_status.IsUserApprovedOrAdmin(b, chat.Id, user.Id) {
 		return ext.ContinueGroups
 	}
 
@@ -428,13 +910,69 @@ func (moduleStruct) botLockHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.ContinueGroups
 }
 
+// lockExpiryJob is the scheduler job type that lifts the expired timed locks of a chat
+const lockExpiryJob = "lock_expiry"
+
+// lockExpiryPayload identifies the chat a lock expiry job belongs to.
+type lockExpiryPayload struct {
+	ChatID int64 `json:"chat_id"`
+}
+
+// scheduleLockExpiry queues the lock expiry job of a chat for its earliest timed lock,
+// or cancels it when the chat has no timed locks left.
+func scheduleLockExpiry(chatID int64) error {
+	var next time.Time
+	for _, expiresAt := range db.GetChatLockExpiries(chatID) {
+		if next.IsZero() || expiresAt.Before(next) {
+			next = expiresAt
+		}
+	}
+
+	key := fmt.Sprint(chatID)
+	if next.IsZero() {
+		return scheduler.Cancel(lockExpiryJob, key)
+	}
+	return scheduler.Enqueue(lockExpiryJob, key, lockExpiryPayload{ChatID: chatID}, next)
+}
+
+// runLockExpiryJob lifts the expired timed locks of a chat and tells the chat about it.
+func runLockExpiryJob(b *gotgbot.Bot, job *db.ScheduledJob) error {
+	var payload lockExpiryPayload
+	if err := scheduler.DecodePayload(job, &payload); err != nil {
+		// a malformed payload can't succeed on retry
+		log.Errorf("[Locks] Invalid payload for job %d: %v", job.ID, err)
+		return nil
+	}
+
+	expired := db.ExpireChatLocks(payload.ChatID)
+	if len(expired) > 0 {
+		slices.Sort(expired)
+		tr := i18n.MustNewTranslator(db.GetLanguage(&ext.Context{EffectiveChat: &gotgbot.Chat{Id: payload.ChatID}}))
+		text, _ := tr.GetString("locks_expired", i18n.TranslationParams{"locks": strings.Join(expired, "\n - ")})
+		if _, err := b.SendMessage(payload.ChatID, text, nil); err != nil {
+			log.Debugf("[Locks] Failed to announce expired locks in %d: %v", payload.ChatID, err)
+		}
+	}
+
+	return scheduleLockExpiry(payload.ChatID)
+}
+
 // LoadLocks registers all locks module handlers with the dispatcher,
 // including commands and message filters for lock enforcement.
 func LoadLocks(dispatcher *ext.Dispatcher) {
 	HelpModule.AbleMap.Store(locksModule.moduleName, true)
 
+	scheduler.RegisterHandler(lockExpiryJob, runLockExpiryJob)
+
 	dispatcher.AddHandler(handlers.NewCommand("lock", locksModule.lockPerm))
 	dispatcher.AddHandler(handlers.NewCommand("unlock", locksModule.unlockPerm))
+	dispatcher.AddHandler(handlers.NewCommand("lockaction", locksModule.lockAction))
+	dispatcher.AddHandler(handlers.NewCommand("maxmentions", locksModule.maxMentions))
+	dispatcher.AddHandler(handlers.NewCommand("allowdomain", locksModule.allowDomain))
+	dispatcher.AddHandler(handlers.NewCommand("blockdomain", locksModule.blockDomain))
+	dispatcher.AddHandler(handlers.NewCommand("rmdomain", locksModule.rmDomain))
+	dispatcher.AddHandler(handlers.NewCommand("domains", locksModule.listDomains))
+	misc.AddCmdToDisableable("domains")
 	dispatcher.AddHandler(handlers.NewCommand("locktypes", locksModule.locktypes))
 	misc.AddCmdToDisableable("locktypes")
 	dispatcher.AddHandler(handlers.NewCommand("locks", locksModule.locks))
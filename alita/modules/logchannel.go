@@ -0,0 +1,348 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/i18n"
+	"github.com/divideprojects/Alita_Robot/alita/utils/cache"
+	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+)
+
+var logsModule = moduleStruct{moduleName: "Logs"}
+
+const (
+	// chatLogInterval is the minimum delay between two posts to the same log channel,
+	// keeping each channel below Telegram's limit of 20 messages per minute.
+	chatLogInterval = 3 * time.Second
+	// chatLogQueueSize is the number of log messages buffered per channel before new ones are dropped.
+	chatLogQueueSize = 50
+	// chatLogIdleTimeout is how long a channel's delivery worker waits for new messages before exiting.
+	chatLogIdleTimeout = time.Minute
+)
+
+// chatLogQueue buffers the log messages of a single log channel.
+type chatLogQueue struct {
+	messages chan string
+}
+
+var (
+	chatLogQueues   = make(map[int64]*chatLogQueue)
+	chatLogQueuesMu sync.Mutex
+)
+
+// enqueueChatLog queues a message for delivery to a log channel,
+// starting the channel's delivery worker if it isn't running.
+func enqueueChatLog(b *gotgbot.Bot, channelId int64, text string) {
+	chatLogQueuesMu.Lock()
+	defer chatLogQueuesMu.Unlock()
+
+	queue, ok := chatLogQueues[channelId]
+	if !ok {
+		queue = &chatLogQueue{messages: make(chan string, chatLogQueueSize)}
+		chatLogQueues[channelId] = queue
+		go queue.run(b, channelId)
+	}
+
+	select {
+	case queue.messages <- text:
+	default:
+		log.Warnf("[Logs] Queue of log channel %d is full, dropping message", channelId)
+	}
+}
+
+// run posts queued messages to a log channel no faster than chatLogInterval.
+// The worker exits once its queue has been idle for chatLogIdleTimeout.
+func (q *chatLogQueue) run(b *gotgbot.Bot, channelId int64) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithField("panic", r).Error("Panic in log channel delivery goroutine")
+			chatLogQueuesMu.Lock()
+			delete(chatLogQueues, channelId)
+			chatLogQueuesMu.Unlock()
+		}
+	}()
+
+	idle := time.NewTimer(chatLogIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case text := <-q.messages:
+			_, err := b.SendMessage(channelId, text,
+				&gotgbot.SendMessageOpts{
+					ParseMode: helpers.HTML,
+					LinkPreviewOptions: &gotgbot.LinkPreviewOptions{
+						IsDisabled: true,
+					},
+				},
+			)
+			if err != nil {
+				log.Debugf("[Logs] Failed to post to log channel %d: %v", channelId, err)
+			}
+			time.Sleep(chatLogInterval)
+			idle.Reset(chatLogIdleTimeout)
+		case <-idle.C:
+			chatLogQueuesMu.Lock()
+			if len(q.messages) == 0 {
+				delete(chatLogQueues, channelId)
+				chatLogQueuesMu.Unlock()
+				return
+			}
+			chatLogQueuesMu.Unlock()
+			idle.Reset(chatLogIdleTimeout)
+		}
+	}
+}
+
+// sendChatLog posts an event of a chat to its log channel, if the chat has one
+// and the event's category is enabled.
+func sendChatLog(b *gotgbot.Bot, chatId int64, category, text string) {
+	settings := db.GetLogChannel(chatId)
+	if settings.ChannelId == 0 || !settings.IsLogCategoryEnabled(category) {
+		return
+	}
+
+	chatName, lang := fmt.Sprint(chatId), "en"
+	if chat := db.GetChatSettings(chatId); chat != nil {
+		if chat.ChatName != "" {
+			chatName = chat.ChatName
+		}
+		if chat.Language != "" {
+			lang = chat.Language
+		}
+	}
+	tr := i18n.MustNewTranslator(lang)
+	header, _ := tr.GetString("logs_header", i18n.TranslationParams{
+		"chat": html.EscapeString(chatName),
+		"id":   fmt.Sprint(chatId),
+	})
+
+	enqueueChatLog(b, settings.ChannelId, header+text)
+}
+
+// maxLoggedCommandLength caps the length of a command quoted in a settings change log.
+const maxLoggedCommandLength = 300
+
+// logSettingsChange posts the command an admin used to change the settings of a module to the log channel.
+// Confirmation buttons pass the command they confirm, since their message is the bot's own prompt.
+func logSettingsChange(b *gotgbot.Bot, ctx *ext.Context, moduleName, commandText string) {
+	command := []rune(commandText)
+	if len(command) > maxLoggedCommandLength {
+		command = append(command[:maxLoggedCommandLength], '…')
+	}
+
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	text, _ := tr.GetString("logs_settings_changed", i18n.TranslationParams{
+		"module":  moduleName,
+		"admin":   helpers.MentionHtml(ctx.EffectiveSender.Id(), ctx.EffectiveSender.Name()),
+		"command": html.EscapeString(string(command)),
+	})
+	sendChatLog(b, ctx.EffectiveChat.Id, db.LogCategorySettings, text)
+}
+
+// logMemberEvent posts a member joining or leaving the chat to the log channel.
+func logMemberEvent(b *gotgbot.Bot, ctx *ext.Context, key string, user gotgbot.User) {
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	text, _ := tr.GetString(key, i18n.TranslationParams{
+		"user": helpers.MentionHtml(user.Id, user.FirstName),
+		"id":   fmt.Sprint(user.Id),
+	})
+	sendChatLog(b, ctx.EffectiveChat.Id, db.LogCategoryJoins, text)
+}
+
+// memberJoinLogTTL is how long a logged join is remembered, so the chat_member update
+// and the service message of the same join are only logged once.
+const memberJoinLogTTL = time.Minute
+
+// logMemberJoin posts a member joining the chat to the log channel, once per join.
+// Joins are seen both as chat_member updates and as new_chat_members service messages.
+func logMemberJoin(b *gotgbot.Bot, ctx *ext.Context, user gotgbot.User) {
+	key := fmt.Sprintf("alita:join_logged:%d:%d", ctx.EffectiveChat.Id, user.Id)
+	if first, err := cache.AcquireLock(key, "1", memberJoinLogTTL); err == nil && !first {
+		return
+	}
+	logMemberEvent(b, ctx, "logs_member_joined", user)
+}
+
+// isChannelAdmin checks whether a user administers a channel.
+// chat_status.IsUserAdmin only covers groups, so the member is looked up directly.
+func isChannelAdmin(b *gotgbot.Bot, channelId, userId int64) bool {
+	member, err := b.GetChatMember(channelId, userId, nil)
+	if err != nil {
+		log.Debugf("[Logs] Failed to get member %d of channel %d: %v", userId, channelId, err)
+		return false
+	}
+	status := member.GetStatus()
+	return status == "creator" || status == "administrator"
+}
+
+// setLog handles the /setlog command to choose the log channel of a chat.
+// Admins reply with /setlog to a message forwarded from the channel.
+func (moduleStruct) setLog(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var channel *gotgbot.Chat
+	if msg.ReplyToMessage != nil && msg.ReplyToMessage.ForwardOrigin != nil {
+		origin := msg.ReplyToMessage.ForwardOrigin.MergeMessageOrigin()
+		if origin.Type == "channel" && origin.Chat != nil {
+			channel = origin.Chat
+		}
+	}
+
+	var text string
+	switch {
+	case channel == nil:
+		text, _ = tr.GetString("logs_setlog_help")
+	case !isChannelAdmin(b, channel.Id, user.Id):
+		text, _ = tr.GetString("logs_not_channel_admin")
+	default:
+		testText, _ := tr.GetString("logs_channel_connected", i18n.TranslationParams{"chat": html.EscapeString(chat.Title)})
+		_, err := b.SendMessage(channel.Id, testText, helpers.Shtml())
+		if err != nil {
+			log.Debugf("[Logs] Failed to post to channel %d: %v", channel.Id, err)
+			text, _ = tr.GetString("logs_cannot_post")
+			break
+		}
+
+		if err = db.SetLogChannel(chat.Id, channel.Id); err != nil {
+			text, _ = tr.GetString("logs_action_failed")
+			break
+		}
+		text, _ = tr.GetString("logs_setlog_success", i18n.TranslationParams{"channel": html.EscapeString(channel.Title)})
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// unsetLog handles the /unsetlog command to stop posting events to the log channel.
+func (moduleStruct) unsetLog(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	if db.UnsetLogChannel(chat.Id) {
+		text, _ = tr.GetString("logs_unsetlog_success")
+	} else {
+		text, _ = tr.GetString("logs_no_log_channel")
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// logCategories handles the /logcategories command to view which events are posted
+// to the log channel, or toggle a category on or off.
+func (moduleStruct) logCategories(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	settings := db.GetLogChannel(chat.Id)
+
+	var text string
+	switch {
+	case settings.ChannelId == 0:
+		text, _ = tr.GetString("logs_no_log_channel")
+	case len(args) == 0:
+		text, _ = tr.GetString("logs_categories_header")
+		var sb strings.Builder
+		for _, category := range db.LogCategories {
+			key := "logs_category_disabled"
+			if settings.IsLogCategoryEnabled(category) {
+				key = "logs_category_enabled"
+			}
+			line, _ := tr.GetString(key, i18n.TranslationParams{"category": category})
+			sb.WriteString(line)
+		}
+		text += sb.String()
+	case len(args) < 2 || !slices.Contains(db.LogCategories, strings.ToLower(args[0])):
+		text, _ = tr.GetString("logs_categories_help", i18n.TranslationParams{"categories": strings.Join(db.LogCategories, "/")})
+	default:
+		category := strings.ToLower(args[0])
+		var enabled bool
+		switch strings.ToLower(args[1]) {
+		case "on", "yes", "true":
+			enabled = true
+		case "off", "no", "false":
+			enabled = false
+		default:
+			text, _ = tr.GetString("logs_categories_help", i18n.TranslationParams{"categories": strings.Join(db.LogCategories, "/")})
+		}
+		if text != "" {
+			break
+		}
+
+		if err := db.SetLogCategory(chat.Id, category, enabled); err != nil {
+			text, _ = tr.GetString("logs_action_failed")
+		} else if enabled {
+			text, _ = tr.GetString("logs_category_turned_on", i18n.TranslationParams{"category": category})
+		} else {
+			text, _ = tr.GetString("logs_category_turned_off", i18n.TranslationParams{"category": category})
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// LoadLogs registers the log channel commands with the dispatcher.
+func LoadLogs(dispatcher *ext.Dispatcher) {
+	HelpModule.AbleMap.Store(logsModule.moduleName, true)
+
+	dispatcher.AddHandler(handlers.NewCommand("setlog", logsModule.setLog))
+	dispatcher.AddHandler(handlers.NewCommand("unsetlog", logsModule.unsetLog))
+	dispatcher.AddHandler(handlers.NewCommand("logcategories", logsModule.logCategories))
+}
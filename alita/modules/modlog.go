@@ -0,0 +1,375 @@
+package modules
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/i18n"
+	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
+	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+)
+
+var modlogModule = moduleStruct{moduleName: "Modlog"}
+
+// modLogPageSize is the number of audit log entries shown per page.
+const modLogPageSize = 10
+
+// modLogTimeArg matches the time range filter of /modlog, eg 7d for the last 7 days
+// or 30d-7d for between 30 and 7 days ago.
+var modLogTimeArg = regexp.MustCompile(`^\d+[mhdw](-\d+[mhdw])?$`)
+
+// messageLink returns a link to a message in a chat, or an empty string
+// if the chat type has no message links.
+func messageLink(chat *gotgbot.Chat, messageId int64) string {
+	if chat.Username != "" {
+		return fmt.Sprintf("https://t.me/%s/%d", chat.Username, messageId)
+	}
+	if chat.Type == "supergroup" {
+		return fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(strconv.FormatInt(chat.Id, 10), "-100"), messageId)
+	}
+	return ""
+}
+
+// newModAction builds an audit log entry for an action taken in the current chat.
+// The sender of the effective message is recorded as the actor.
+func newModAction(ctx *ext.Context, action string, targetId int64, reason string, duration int64) *db.ModAction {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+
+	entry := &db.ModAction{
+		ChatId:   chat.Id,
+		ActorId:  ctx.EffectiveSender.Id(),
+		TargetId: targetId,
+		Action:   action,
+		Reason:   reason,
+		Duration: duration,
+	}
+	// connected chats are managed from PM, where the message can't be linked
+	if msg != nil && msg.Chat.Id == chat.Id {
+		entry.MessageLink = messageLink(chat, msg.MessageId)
+	}
+	return entry
+}
+
+// logModAction records a moderation action taken in the current chat in the audit log.
+func logModAction(b *gotgbot.Bot, ctx *ext.Context, action string, targetId int64, reason string, duration int64) {
+	recordModAction(b, ctx, newModAction(ctx, action, targetId, reason, duration))
+}
+
+// recordModAction stores an audit log entry and posts it to the log channel of the chat.
+func recordModAction(b *gotgbot.Bot, ctx *ext.Context, entry *db.ModAction) {
+	go func() {
+		_ = db.AddModAction(entry)
+	}()
+
+	category := db.LogCategoryModeration
+	if entry.Action == db.ModActionLock || entry.Action == db.ModActionUnlock {
+		category = db.LogCategorySettings
+	}
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	sendChatLog(b, entry.ChatId, category, formatModActionLog(tr, entry))
+}
+
+// formatModActionLog renders an audit log entry as a log channel post.
+func formatModActionLog(tr *i18n.Translator, entry *db.ModAction) string {
+	text, _ := tr.GetString("logs_mod_action", i18n.TranslationParams{
+		"action": entry.Action,
+		"actor":  mentionUserById(entry.ActorId),
+	})
+	return text + modActionDetails(tr, entry)
+}
+
+// modActionDetails renders the optional target, reason, duration and link lines of an audit log entry.
+func modActionDetails(tr *i18n.Translator, entry *db.ModAction) string {
+	var sb strings.Builder
+	if entry.TargetId != 0 {
+		line, _ := tr.GetString("modlog_entry_target", i18n.TranslationParams{"target": mentionUserById(entry.TargetId)})
+		sb.WriteString(line)
+	}
+	if entry.Reason != "" {
+		line, _ := tr.GetString("modlog_entry_reason", i18n.TranslationParams{"reason": html.EscapeString(entry.Reason)})
+		sb.WriteString(line)
+	}
+	if entry.Duration > 0 {
+		line, _ := tr.GetString("modlog_entry_duration", i18n.TranslationParams{"duration": formatWarnTime(tr, entry.Duration)})
+		sb.WriteString(line)
+	}
+	if entry.MessageLink != "" {
+		line, _ := tr.GetString("modlog_entry_link", i18n.TranslationParams{"link": entry.MessageLink})
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+// parseModLogTime converts a time such as 12h or 7d into the moment that long ago.
+// Returns false if the amount is too large to be a time.
+func parseModLogTime(arg string) (time.Time, bool) {
+	var unit time.Duration
+	switch arg[len(arg)-1] {
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return time.Time{}, false
+	}
+	num, err := strconv.ParseInt(arg[:len(arg)-1], 10, 64)
+	if err != nil || num > int64(math.MaxInt64/unit) {
+		return time.Time{}, false
+	}
+	return time.Now().Add(-time.Duration(num) * unit), true
+}
+
+// parseModLogRange converts a time range filter into the bounds it covers: 7d covers
+// the last 7 days, and 30d-7d the entries between 30 and 7 days ago in either order.
+// Returns false if either time is invalid.
+func parseModLogRange(arg string) (since, until time.Time, ok bool) {
+	from, to, isRange := strings.Cut(arg, "-")
+	if since, ok = parseModLogTime(from); !ok || !isRange {
+		return since, time.Time{}, ok
+	}
+	if until, ok = parseModLogTime(to); !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	if until.Before(since) {
+		since, until = until, since
+	}
+	return since, until, true
+}
+
+// buildModLogPage renders one page of the audit log of a chat along with its navigation buttons.
+func buildModLogPage(tr *i18n.Translator, chatId int64, filter db.ModLogFilter, page int) (string, gotgbot.InlineKeyboardMarkup) {
+	entries, total := db.GetModActions(chatId, filter, page*modLogPageSize, modLogPageSize)
+	if total == 0 {
+		text, _ := tr.GetString("modlog_empty")
+		return text, gotgbot.InlineKeyboardMarkup{}
+	}
+
+	pages := int((total + modLogPageSize - 1) / modLogPageSize)
+	text, _ := tr.GetString("modlog_header", i18n.TranslationParams{
+		"page":  strconv.Itoa(page + 1),
+		"pages": strconv.Itoa(pages),
+		"total": strconv.FormatInt(total, 10),
+	})
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		line, _ := tr.GetString("modlog_entry", i18n.TranslationParams{
+			"id":     strconv.FormatUint(uint64(entry.ID), 10),
+			"action": entry.Action,
+			"date":   entry.CreatedAt.UTC().Format(displayTimeLayout),
+			"actor":  mentionUserById(entry.ActorId),
+		})
+		sb.WriteString(line)
+		sb.WriteString(modActionDetails(tr, entry))
+	}
+	text += sb.String()
+
+	action := filter.Action
+	if action == "" {
+		action = "-"
+	}
+	var since, until int64
+	if !filter.Since.IsZero() {
+		since = filter.Since.Unix()
+	}
+	if !filter.Until.IsZero() {
+		until = filter.Until.Unix()
+	}
+	callbackData := func(p int) string {
+		return fmt.Sprintf("modlog.%d.%d.%s.%d.%d", p, filter.TargetId, action, since, until)
+	}
+
+	var buttons []gotgbot.InlineKeyboardButton
+	if page > 0 {
+		prevText, _ := tr.GetString("modlog_prev_button")
+		buttons = append(buttons, gotgbot.InlineKeyboardButton{Text: prevText, CallbackData: callbackData(page - 1)})
+	}
+	if page+1 < pages {
+		nextText, _ := tr.GetString("modlog_next_button")
+		buttons = append(buttons, gotgbot.InlineKeyboardButton{Text: nextText, CallbackData: callbackData(page + 1)})
+	}
+
+	keyboard := gotgbot.InlineKeyboardMarkup{InlineKeyboard: [][]gotgbot.InlineKeyboardButton{}}
+	if len(buttons) > 0 {
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, buttons)
+	}
+	return text, keyboard
+}
+
+// modLog handles the /modlog command to browse the moderation audit log of a chat.
+// Accepts a user, an action type and a time range (eg 7d) as filters, in any order.
+func (moduleStruct) modLog(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, false)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var filter db.ModLogFilter
+	if msg.ReplyToMessage != nil {
+		filter.TargetId, _ = extraction.IdFromReply(msg)
+	}
+
+	for _, arg := range args {
+		lowerArg := strings.ToLower(arg)
+		switch {
+		case slices.Contains(db.ModActionTypes, lowerArg):
+			filter.Action = lowerArg
+		case modLogTimeArg.MatchString(lowerArg):
+			var ok bool
+			filter.Since, filter.Until, ok = parseModLogRange(lowerArg)
+			if !ok {
+				text, _ := tr.GetString("modlog_invalid_time", i18n.TranslationParams{"filter": html.EscapeString(arg)})
+				_, err := msg.Reply(b, text, helpers.Shtml())
+				if err != nil {
+					log.Error(err)
+					return err
+				}
+				return ext.EndGroups
+			}
+		case strings.HasPrefix(arg, "@"):
+			filter.TargetId = extraction.GetUserId(arg)
+			if filter.TargetId == 0 {
+				text, _ := tr.GetString("modlog_user_not_found", i18n.TranslationParams{"user": html.EscapeString(arg)})
+				_, err := msg.Reply(b, text, helpers.Shtml())
+				if err != nil {
+					log.Error(err)
+					return err
+				}
+				return ext.EndGroups
+			}
+		default:
+			userId, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				text, _ := tr.GetString("modlog_invalid_filter", i18n.TranslationParams{
+					"filter":  html.EscapeString(arg),
+					"actions": strings.Join(db.ModActionTypes, "/"),
+				})
+				_, err := msg.Reply(b, text, helpers.Shtml())
+				if err != nil {
+					log.Error(err)
+					return err
+				}
+				return ext.EndGroups
+			}
+			filter.TargetId = userId
+		}
+	}
+
+	text, keyboard := buildModLogPage(tr, chat.Id, filter, 0)
+	_, err := msg.Reply(b, text,
+		&gotgbot.SendMessageOpts{
+			ParseMode: helpers.HTML,
+			LinkPreviewOptions: &gotgbot.LinkPreviewOptions{
+				IsDisabled: true,
+			},
+			ReplyMarkup: keyboard,
+		},
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// modLogPageHandler processes the navigation buttons of the /modlog output.
+// The filters are carried in the callback data so every page stays consistent.
+func (moduleStruct) modLogPageHandler(b *gotgbot.Bot, ctx *ext.Context) error {
+	query := ctx.CallbackQuery
+	user := query.From
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	// connected chats are browsed from PM, so resolve the chat the same way the command does
+	chat := ctx.EffectiveChat
+	if chat.Type == "private" {
+		conn := db.Connection(user.Id)
+		if !conn.Connected || conn.ChatId == 0 {
+			return ext.EndGroups
+		}
+		chat = &gotgbot.Chat{Id: conn.ChatId}
+	}
+
+	if !chat_status.RequireUserAdmin(b, ctx, chat, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	// modlog.<page>.<target>.<action>.<since>[.<until>]
+	args := strings.Split(query.Data, ".")
+	if len(args) != 5 && len(args) != 6 {
+		return ext.EndGroups
+	}
+	page, _ := strconv.Atoi(args[1])
+	var filter db.ModLogFilter
+	filter.TargetId, _ = strconv.ParseInt(args[2], 10, 64)
+	if args[3] != "-" {
+		filter.Action = args[3]
+	}
+	if since, _ := strconv.ParseInt(args[4], 10, 64); since > 0 {
+		filter.Since = time.Unix(since, 0)
+	}
+	if len(args) == 6 {
+		if until, _ := strconv.ParseInt(args[5], 10, 64); until > 0 {
+			filter.Until = time.Unix(until, 0)
+		}
+	}
+
+	text, keyboard := buildModLogPage(tr, chat.Id, filter, page)
+	_, _, err := query.Message.EditText(b, text,
+		&gotgbot.EditMessageTextOpts{
+			ParseMode: helpers.HTML,
+			LinkPreviewOptions: &gotgbot.LinkPreviewOptions{
+				IsDisabled: true,
+			},
+			ReplyMarkup: keyboard,
+		},
+	)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	_, err = query.Answer(b, nil)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// LoadModlog registers the /modlog command and its pagination buttons with the dispatcher.
+func LoadModlog(dispatcher *ext.Dispatcher) {
+	HelpModule.AbleMap.Store(modlogModule.moduleName, true)
+
+	dispatcher.AddHandler(handlers.NewCommand("modlog", modlogModule.modLog))
+	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("modlog."), modlogModule.modLogPageHandler))
+}
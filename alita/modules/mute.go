@@ -124,6 +124,8 @@ func (moduleStruct) tMute(b *gotgbot.Bot, ctx *ext.Context) error {
 		return err
 	}
 
+	logModAction(b, ctx, db.ModActionMute, userId, reason, durationUntil(_time))
+
 	muteUser, err := b.GetChat(userId, nil)
 	if err != nil {
 		log.Error(err)
@@ -246,6 +248,8 @@ func (moduleStruct) mute(b *gotgbot.Bot, ctx *ext.Context) error {
 		return err
 	}
 
+	logModAction(b, ctx, db.ModActionMute, userId, reason, 0)
+
 	muteUser, err := b.GetChat(userId, nil)
 	if err != nil {
 		log.Error(err)
@@ -374,6 +378,8 @@ func (moduleStruct) sMute(b *gotgbot.Bot, ctx *ext.Context) error {
 		return err
 	}
 
+	logModAction(b, ctx, db.ModActionMute, userId, "", 0)
+
 	_, err = msg.Delete(b, nil)
 	if err != nil {
 		log.Error(err)
@@ -490,6 +496,8 @@ func (moduleStruct) dMute(b *gotgbot.Bot, ctx *ext.Context) error {
 		return err
 	}
 
+	logModAction(b, ctx, db.ModActionMute, userId, reason, 0)
+
 	muteUser, err := b.GetChat(userId, nil)
 	if err != nil {
 		log.Error(err)
@@ -612,6 +620,8 @@ func (moduleStruct) unmute(b *gotgbot.Bot, ctx *ext.Context) error {
 		return err
 	}
 
+	logModAction(b, ctx, db.ModActionUnmute, userId, "", 0)
+
 	muteUser, err := b.GetChat(userId, nil)
 	if err != nil {
 		log.Error(err)
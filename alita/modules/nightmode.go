@@ -0,0 +1,375 @@
+package modules
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	// embed the timezone database so chat timezones resolve on hosts without zoneinfo
+	_ "time/tzdata"
+
+	tgmd2html "github.com/PaulSonOfLars/gotg_md2html"
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/i18n"
+	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+	"github.com/divideprojects/Alita_Robot/alita/utils/scheduler"
+)
+
+var nightModeModule = moduleStruct{moduleName: "Nightmode"}
+
+// Scheduler job types that start and end the night mode window of a chat
+const (
+	nightModeStartJob = "nightmode_start"
+	nightModeEndJob   = "nightmode_end"
+)
+
+// nightModePayload identifies the chat a night mode job belongs to.
+type nightModePayload struct {
+	ChatID int64 `json:"chat_id"`
+}
+
+// nightModeLocation returns the timezone of the night mode settings, falling back to UTC.
+func nightModeLocation(settings *db.NightModeSettings) *time.Location {
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// formatMinuteOfDay formats a minute of the day as HH:MM.
+func formatMinuteOfDay(minute int) string {
+	return fmt.Sprintf("%02d:%02d", minute/60, minute%60)
+}
+
+// nextMinuteOfDay returns the next time after now that is the given minute of the day, in now's timezone.
+func nextMinuteOfDay(now time.Time, minute int) time.Time {
+	t := time.Date(now.Year(), now.Month(), now.Day(), minute/60, minute%60, 0, 0, now.Location())
+	if !t.After(now) {
+		t = time.Date(now.Year(), now.Month(), now.Day()+1, minute/60, minute%60, 0, 0, now.Location())
+	}
+	return t
+}
+
+// inNightModeWindow checks whether now falls in the night mode window, which may span midnight.
+func inNightModeWindow(now time.Time, settings *db.NightModeSettings) bool {
+	minute := now.Hour()*60 + now.Minute()
+	if settings.StartMinute < settings.EndMinute {
+		return minute >= settings.StartMinute && minute < settings.EndMinute
+	}
+	return minute >= settings.StartMinute || minute < settings.EndMinute
+}
+
+// nightModePermissions returns the permissions applied while night mode is active,
+// restricting the saved permissions according to the preset.
+func nightModePermissions(saved gotgbot.ChatPermissions, preset string) gotgbot.ChatPermissions {
+	restricted := saved
+	restricted.CanSendAudios = false
+	restricted.CanSendDocuments = false
+	restricted.CanSendPhotos = false
+	restricted.CanSendVideos = false
+	restricted.CanSendVideoNotes = false
+	restricted.CanSendVoiceNotes = false
+	restricted.CanSendPolls = false
+	restricted.CanSendOtherMessages = false
+	restricted.CanAddWebPagePreviews = false
+	if preset != db.NightModePresetMedia {
+		restricted.CanSendMessages = false
+	}
+	return restricted
+}
+
+// scheduleNightMode queues the next start or end of the night mode window of a chat.
+// A window that should already be running is started right away, and an active
+// window is ended right away once night mode is turned off or the window moves.
+func scheduleNightMode(settings *db.NightModeSettings) error {
+	key := fmt.Sprint(settings.ChatId)
+	payload := nightModePayload{ChatID: settings.ChatId}
+	now := time.Now().In(nightModeLocation(settings))
+
+	if !settings.Enabled {
+		if err := scheduler.Cancel(nightModeStartJob, key); err != nil {
+			return err
+		}
+		if settings.Active {
+			return scheduler.Enqueue(nightModeEndJob, key, payload, now.UTC())
+		}
+		return scheduler.Cancel(nightModeEndJob, key)
+	}
+
+	inWindow := inNightModeWindow(now, settings)
+	switch {
+	case inWindow && !settings.Active:
+		return scheduler.Enqueue(nightModeStartJob, key, payload, now.UTC())
+	case inWindow && settings.Active:
+		return scheduler.Enqueue(nightModeEndJob, key, payload, nextMinuteOfDay(now, settings.EndMinute).UTC())
+	case settings.Active:
+		return scheduler.Enqueue(nightModeEndJob, key, payload, now.UTC())
+	default:
+		return scheduler.Enqueue(nightModeStartJob, key, payload, nextMinuteOfDay(now, settings.StartMinute).UTC())
+	}
+}
+
+// sendNightModeAnnouncement posts the optional start or end message of night mode.
+func sendNightModeAnnouncement(b *gotgbot.Bot, chatId int64, text string) {
+	if text == "" {
+		return
+	}
+	if _, err := b.SendMessage(chatId, text, helpers.Shtml()); err != nil {
+		log.Debugf("[Nightmode] Failed to send announcement to %d: %v", chatId, err)
+	}
+}
+
+// runNightModeStartJob saves the current permissions of a chat and restricts them for the night.
+// If the bot can't change the permissions, this night is skipped rather than retried.
+func runNightModeStartJob(b *gotgbot.Bot, job *db.ScheduledJob) error {
+	var payload nightModePayload
+	if err := scheduler.DecodePayload(job, &payload); err != nil {
+		// a malformed payload can't succeed on retry
+		log.Errorf("[Nightmode] Invalid payload for job %d: %v", job.ID, err)
+		return nil
+	}
+
+	settings := db.GetNightMode(payload.ChatID)
+	if !settings.Enabled || settings.Active {
+		return scheduleNightMode(settings)
+	}
+	now := time.Now().In(nightModeLocation(settings))
+
+	// the antispam restrict action holds the permissions to restore, so night mode
+	// starts once they are back instead of saving the restricted ones
+	if until, pending := antispamRestrictPending(payload.ChatID); pending {
+		runAt := until.Add(time.Minute)
+		if runAt.Before(now) {
+			runAt = now.Add(time.Minute)
+		}
+		// a lockdown lasting the whole night skips it
+		if end := nextMinuteOfDay(now, settings.EndMinute); !runAt.Before(end) {
+			runAt = nextMinuteOfDay(end, settings.StartMinute)
+		}
+		return scheduler.Enqueue(nightModeStartJob, fmt.Sprint(payload.ChatID), payload, runAt.UTC())
+	}
+
+	chat, err := b.GetChat(payload.ChatID, nil)
+	if err != nil {
+		return err
+	}
+	saved := gotgbot.ChatPermissions{}
+	if chat.Permissions != nil {
+		saved = *chat.Permissions
+	}
+	savedJSON, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.SetChatPermissions(payload.ChatID, nightModePermissions(saved, settings.Preset),
+		&gotgbot.SetChatPermissionsOpts{UseIndependentChatPermissions: true})
+	if err != nil {
+		log.Warnf("[Nightmode] Failed to restrict chat %d, skipping tonight: %v", payload.ChatID, err)
+		return scheduler.Enqueue(nightModeStartJob, fmt.Sprint(payload.ChatID), payload,
+			nextMinuteOfDay(nextMinuteOfDay(now, settings.EndMinute), settings.StartMinute).UTC())
+	}
+
+	if err = db.SetNightModeActive(payload.ChatID, true, string(savedJSON)); err != nil {
+		return err
+	}
+	sendNightModeAnnouncement(b, payload.ChatID, settings.StartMessage)
+
+	return scheduler.Enqueue(nightModeEndJob, fmt.Sprint(payload.ChatID), payload, nextMinuteOfDay(now, settings.EndMinute).UTC())
+}
+
+// runNightModeEndJob restores the permissions a chat had before night mode started.
+// Failures are retried, since the chat stays restricted until this succeeds.
+func runNightModeEndJob(b *gotgbot.Bot, job *db.ScheduledJob) error {
+	var payload nightModePayload
+	if err := scheduler.DecodePayload(job, &payload); err != nil {
+		// a malformed payload can't succeed on retry
+		log.Errorf("[Nightmode] Invalid payload for job %d: %v", job.ID, err)
+		return nil
+	}
+
+	settings := db.GetNightMode(payload.ChatID)
+	if !settings.Active {
+		return scheduleNightMode(settings)
+	}
+
+	var saved gotgbot.ChatPermissions
+	if err := json.Unmarshal([]byte(settings.SavedPermissions), &saved); err != nil {
+		// the chat must not stay restricted, so it gets the usual member permissions back
+		log.Errorf("[Nightmode] Invalid saved permissions of chat %d, restoring defaults: %v", payload.ChatID, err)
+		saved = defaultChatPermissions()
+	}
+
+	_, err := b.SetChatPermissions(payload.ChatID, saved,
+		&gotgbot.SetChatPermissionsOpts{UseIndependentChatPermissions: true})
+	if err != nil {
+		return err
+	}
+
+	if err = db.SetNightModeActive(payload.ChatID, false, ""); err != nil {
+		return err
+	}
+	if settings.Enabled {
+		sendNightModeAnnouncement(b, payload.ChatID, settings.EndMessage)
+	}
+
+	settings.Active = false
+	return scheduleNightMode(settings)
+}
+
+// nightModeStatus renders the night mode settings of a chat.
+func nightModeStatus(tr *i18n.Translator, settings *db.NightModeSettings) string {
+	key := "nightmode_status_off"
+	if settings.Enabled {
+		key = "nightmode_status_on"
+	}
+	text, _ := tr.GetString(key, i18n.TranslationParams{
+		"start":    formatMinuteOfDay(settings.StartMinute),
+		"end":      formatMinuteOfDay(settings.EndMinute),
+		"timezone": settings.Timezone,
+		"preset":   settings.Preset,
+	})
+	if settings.Active {
+		active, _ := tr.GetString("nightmode_status_active")
+		text += active
+	}
+	return text
+}
+
+// nightMode handles the /nightmode command to view or change the night mode of a chat.
+//
+//	/nightmode                      show the settings
+//	/nightmode on|off               turn night mode on or off
+//	/nightmode <HH:MM> <HH:MM>      set the window
+//	/nightmode tz <timezone>        set the timezone of the window
+//	/nightmode preset <readonly|media>
+//	/nightmode startmsg|endmsg <text|off>
+func (m moduleStruct) nightMode(b *gotgbot.Bot, ctx *ext.Context) error {
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	if !chat_status.RequireGroup(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.CanUserRestrict(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var (
+		text    string
+		updates map[string]any
+	)
+	option := ""
+	if len(args) > 0 {
+		option = strings.ToLower(args[0])
+	}
+
+	switch option {
+	case "":
+		text = nightModeStatus(tr, db.GetNightMode(chat.Id))
+	case "on", "yes", "true":
+		if !chat_status.CanBotRestrict(b, ctx, nil, false) {
+			return ext.EndGroups
+		}
+		updates = map[string]any{"enabled": true}
+	case "off", "no", "false":
+		updates = map[string]any{"enabled": false}
+	case "tz", "timezone":
+		if len(args) < 2 {
+			text, _ = tr.GetString("nightmode_invalid_timezone")
+			break
+		}
+		loc, err := time.LoadLocation(args[1])
+		if err != nil || args[1] == "Local" {
+			text, _ = tr.GetString("nightmode_invalid_timezone")
+			break
+		}
+		updates = map[string]any{"timezone": loc.String()}
+	case "preset":
+		if len(args) < 2 || (args[1] != db.NightModePresetReadOnly && args[1] != db.NightModePresetMedia) {
+			text, _ = tr.GetString("nightmode_invalid_preset")
+			break
+		}
+		updates = map[string]any{"preset": args[1]}
+	case "startmsg", "endmsg":
+		column := "start_message"
+		if option == "endmsg" {
+			column = "end_message"
+		}
+		parts := strings.SplitN(msg.OriginalMDV2(), " ", 3)
+		switch {
+		case len(parts) < 3:
+			text, _ = tr.GetString("nightmode_message_required")
+		case strings.ToLower(strings.TrimSpace(parts[2])) == "off":
+			updates = map[string]any{column: ""}
+		default:
+			updates = map[string]any{column: tgmd2html.MD2HTMLV2(parts[2])}
+		}
+	default:
+		start, startOk := 0, false
+		end, endOk := 0, false
+		if len(args) >= 2 {
+			var h, mi int
+			if h, mi, startOk = parseClock(args[0]); startOk {
+				start = h*60 + mi
+			}
+			if h, mi, endOk = parseClock(args[1]); endOk {
+				end = h*60 + mi
+			}
+		}
+		switch {
+		case !startOk || !endOk:
+			text, _ = tr.GetString("nightmode_usage")
+		case start == end:
+			text, _ = tr.GetString("nightmode_empty_window")
+		default:
+			updates = map[string]any{"start_minute": start, "end_minute": end}
+		}
+	}
+
+	if updates != nil {
+		if err := db.UpdateNightMode(chat.Id, updates); err != nil {
+			text, _ = tr.GetString("nightmode_action_failed")
+		} else {
+			settings := db.GetNightMode(chat.Id)
+			if err := scheduleNightMode(settings); err != nil {
+				log.Errorf("[Nightmode] Failed to schedule night mode of %d: %v", chat.Id, err)
+				text, _ = tr.GetString("nightmode_action_failed")
+			} else {
+				logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+				text, _ = tr.GetString("nightmode_updated")
+				text += "\n\n" + nightModeStatus(tr, settings)
+			}
+		}
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// LoadNightmode registers the night mode command and job handlers.
+func LoadNightmode(dispatcher *ext.Dispatcher) {
+	HelpModule.AbleMap.Store(nightModeModule.moduleName, true)
+
+	dispatcher.AddHandler(handlers.NewCommand("nightmode", nightModeModule.nightMode))
+
+	scheduler.RegisterHandler(nightModeStartJob, runNightModeStartJob)
+	scheduler.RegisterHandler(nightModeEndJob, runNightModeEndJob)
+}
@@ -140,6 +140,7 @@ func (m moduleStruct) addNote(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	go db.AddNote(chat.Id, noteWord, text, fileid, buttons, dataType, pvtOnly, grpOnly, adminOnly, webPrev, isProtected, noNotif)
+	logSettingsChange(b, ctx, m.moduleName, msg.GetText())
 
 	_, err := msg.Reply(b, fmt.Sprintf(noteString, noteWord, noteWord, noteWord), helpers.Shtml())
 	if err != nil {
@@ -196,11 +197,16 @@ func (moduleStruct) rmNote(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 	noteWord, _ = extraction.ExtractQuotes(noteWord, false, true)
 
-	db.RemoveNote(chat.Id, strings.ToLower(noteWord))
-
 	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
-	text, _ := tr.GetString("notes_removed_success")
-	_, err := msg.Reply(b, fmt.Sprintf(text, noteWord), helpers.Shtml())
+	var text string
+	if db.RemoveNote(chat.Id, strings.ToLower(noteWord)) {
+		logSettingsChange(b, ctx, notesModule.moduleName, msg.GetText())
+		temp, _ := tr.GetString("notes_removed_success")
+		text = fmt.Sprintf(temp, noteWord)
+	} else {
+		text, _ = tr.GetString("notes_not_exists")
+	}
+	_, err := msg.Reply(b, text, helpers.Shtml())
 	if err != nil {
 		log.Error(err)
 		return err
@@ -223,10 +229,12 @@ func (moduleStruct) privNote(b *gotgbot.Bot, ctx *ext.Context) error {
 			tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 			txt, _ = tr.GetString("notes_private_enabled")
 			go db.TooglePrivateNote(chat.Id, true)
+			logSettingsChange(b, ctx, notesModule.moduleName, msg.GetText())
 		case "off", "no", "false":
 			tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 			txt, _ = tr.GetString("notes_private_disabled")
 			go db.TooglePrivateNote(chat.Id, false)
+			logSettingsChange(b, ctx, notesModule.moduleName, msg.GetText())
 		default:
 			tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 			txt, _ = tr.GetString("notes_private_invalid_option")
@@ -438,6 +446,7 @@ func (m moduleStruct) noteOverWriteHandler(b *gotgbot.Bot, ctx *ext.Context) err
 			db.RemoveNote(chatId, noteWord)
 			db.AddNote(chatId, noteData.noteWord, noteData.text, noteData.fileId, noteData.buttons, noteData.dataType, noteData.pvtOnly, noteData.grpOnly, noteData.adminOnly, noteData.webPrev, noteData.isProtected, noteData.noNotif)
 			delete(m.overwriteNotesMap, noteWordMapKey) // delete the key to make map clear
+			logSettingsChange(b, ctx, m.moduleName, "/save "+noteWord)
 			helpText, _ = tr.GetString("notes_overwrite_success")
 		}
 	}
@@ -484,6 +493,7 @@ func (moduleStruct) notesButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	switch response {
 	case "yes":
 		db.RemoveAllNotes(query.Message.GetChat().Id)
+		logSettingsChange(b, ctx, notesModule.moduleName, "/clearall")
 		helpText, _ = tr.GetString("notes_clear_all_success")
 	case "no":
 		helpText, _ = tr.GetString("notes_clear_all_cancelled")
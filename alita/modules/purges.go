@@ -128,6 +128,18 @@ func (moduleStruct) purgeMsgs(bot *gotgbot.Bot, chat *gotgbot.Chat, pFrom bool,
 	return purgesModule.purgeMsgsConcurrent(bot, chat, pFrom, msgId, deleteTo)
 }
 
+// purgeLogReason describes a purge for the audit log, including the reason given by the admin.
+func purgeLogReason(totalMsgs int64, args []string) string {
+	reason := fmt.Sprintf("%d messages", totalMsgs)
+	if totalMsgs == 1 {
+		reason = "1 message"
+	}
+	if len(args) >= 1 {
+		reason += ": " + strings.Join(args, " ")
+	}
+	return reason
+}
+
 // purge handles the /purge command to delete all messages from a replied
 // message up to the command message, requiring admin permissions.
 func (m moduleStruct) purge(bot *gotgbot.Bot, ctx *ext.Context) error {
@@ -172,6 +184,7 @@ func (m moduleStruct) purge(bot *gotgbot.Bot, ctx *ext.Context) error {
 		}
 
 		if purge {
+			logModAction(bot, ctx, db.ModActionPurge, 0, purgeLogReason(totalMsgs, args), 0)
 			tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 			var Text string
 			if len(args) >= 1 {
@@ -243,6 +256,7 @@ func (moduleStruct) delCmd(bot *gotgbot.Bot, ctx *ext.Context) error {
 	} else {
 		msgId := msg.ReplyToMessage.MessageId
 		_, _ = bot.DeleteMessage(chat.Id, msgId, nil)
+		logModAction(bot, ctx, db.ModActionPurge, msg.ReplyToMessage.GetSender().Id(), purgeLogReason(1, nil), 0)
 		/* if err.Error() == "unable to deleteMessage: Bad Request: message to delete not found" {
 		// 	log.WithFields(
 		// 		log.Fields{
@@ -446,6 +460,7 @@ func (m moduleStruct) purgeTo(bot *gotgbot.Bot, ctx *ext.Context) error {
 			log.Error(err)
 		}
 		if purge {
+			logModAction(bot, ctx, db.ModActionPurge, 0, purgeLogReason(totalMsgs, args), 0)
 			var Text string
 			tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
 			if len(args) >= 1 {
@@ -225,6 +225,14 @@ func (moduleStruct) report(b *gotgbot.Bot, ctx *ext.Context) error {
 		return err
 	}
 
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+	logText, _ := tr.GetString("logs_report", i18n.TranslationParams{
+		"reporter": helpers.MentionHtml(user.Id, user.FirstName),
+		"user":     helpers.MentionHtml(reportedUser.Id, reportedUser.FirstName),
+		"link":     helpers.GetMessageLinkFromMessageId(chat, reportedMsgId),
+	})
+	sendChatLog(b, chat.Id, db.LogCategoryReports, logText)
+
 	return ext.EndGroups
 }
 
@@ -0,0 +1,533 @@
+package modules
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext"
+	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/i18n"
+	"github.com/divideprojects/Alita_Robot/alita/utils/chat_status"
+	"github.com/divideprojects/Alita_Robot/alita/utils/cron"
+	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+	"github.com/divideprojects/Alita_Robot/alita/utils/scheduler"
+)
+
+var schedulesModule = moduleStruct{moduleName: "Schedules"}
+
+const (
+	// scheduledMessageJob is the scheduler job type that posts a scheduled message.
+	scheduledMessageJob = "scheduled_message"
+	// maxSchedulesPerChat limits how many scheduled messages a chat can have.
+	maxSchedulesPerChat = 10
+	// minScheduleInterval is the shortest interval a recurring message can use.
+	minScheduleInterval = 10 * time.Minute
+	// maxScheduleDelay is how far ahead a one-off message can be scheduled.
+	maxScheduleDelay = 365 * 24 * time.Hour
+)
+
+// Options that can be added to the /schedule command
+const (
+	schedulePinOption     = "{pin}"
+	scheduleDelPrevOption = "{delprev}"
+)
+
+var (
+	errInvalidScheduleSpec  = errors.New("invalid schedule")
+	errScheduleTooFrequent  = errors.New("schedule interval too short")
+	errScheduleTooFarAhead  = errors.New("schedule too far ahead")
+	errScheduleInThePast    = errors.New("schedule in the past")
+	errScheduleNeverMatches = errors.New("schedule never fires")
+)
+
+// scheduleErrorKeys maps schedule parsing errors to the message shown to the admin.
+var scheduleErrorKeys = map[error]string{
+	errScheduleTooFrequent:  "schedules_too_frequent",
+	errScheduleTooFarAhead:  "schedules_too_far_ahead",
+	errScheduleInThePast:    "schedules_in_the_past",
+	errScheduleNeverMatches: "schedules_never_fires",
+}
+
+var scheduleWeekdays = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// scheduledMessagePayload identifies the scheduled message a job posts.
+type scheduledMessagePayload struct {
+	ID uint `json:"id"`
+}
+
+// scheduleSpec is a parsed schedule given to the /schedule command.
+type scheduleSpec struct {
+	kind     string
+	display  string
+	cronExpr string
+	interval time.Duration
+	firstRun time.Time
+}
+
+// parseScheduleDuration parses a duration in the m/h/d/w units used by time arguments.
+func parseScheduleDuration(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	unit := map[byte]time.Duration{
+		'm': time.Minute,
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}[s[len(s)-1]]
+	if unit == 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// parseClock parses a time of day written as HH:MM.
+func parseClock(s string) (hour, minute int, ok bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, false
+	}
+	return t.Hour(), t.Minute(), true
+}
+
+// parseScheduleSpec parses the schedule at the start of the /schedule arguments.
+// Returns the schedule and the number of arguments it used; the remaining ones are the message.
+//
+// Supported schedules, with times in UTC:
+//
+//	in <duration>              once, after the duration
+//	at <HH:MM>                 once, at the next occurrence of the time
+//	at <YYYY-MM-DD> <HH:MM>    once, at the date and time
+//	every <duration>           repeatedly, starting one interval from now
+//	daily <HH:MM>              every day at the time
+//	weekly <day> <HH:MM>       every week on the day at the time
+//	cron <m> <h> <dom> <mon> <dow>  on a cron schedule
+func parseScheduleSpec(args []string, now time.Time) (*scheduleSpec, int, error) {
+	if len(args) < 2 {
+		return nil, 0, errInvalidScheduleSpec
+	}
+	now = now.UTC()
+	kind := strings.ToLower(args[0])
+
+	switch kind {
+	case "in", "every":
+		d, ok := parseScheduleDuration(strings.ToLower(args[1]))
+		if !ok {
+			return nil, 0, errInvalidScheduleSpec
+		}
+		if d > maxScheduleDelay {
+			return nil, 0, errScheduleTooFarAhead
+		}
+		spec := &scheduleSpec{kind: db.ScheduleTypeOnce, display: kind + " " + args[1], firstRun: now.Add(d)}
+		if kind == "every" {
+			if d < minScheduleInterval {
+				return nil, 0, errScheduleTooFrequent
+			}
+			spec.kind, spec.interval = db.ScheduleTypeInterval, d
+		}
+		return spec, 2, nil
+
+	case "at":
+		if hour, minute, ok := parseClock(args[1]); ok {
+			runAt := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
+			if !runAt.After(now) {
+				runAt = runAt.AddDate(0, 0, 1)
+			}
+			return &scheduleSpec{kind: db.ScheduleTypeOnce, display: "at " + args[1], firstRun: runAt}, 2, nil
+		}
+		if len(args) < 3 {
+			return nil, 0, errInvalidScheduleSpec
+		}
+		runAt, err := time.Parse("2006-01-02 15:04", args[1]+" "+args[2])
+		switch {
+		case err != nil:
+			return nil, 0, errInvalidScheduleSpec
+		case !runAt.After(now):
+			return nil, 0, errScheduleInThePast
+		case runAt.Sub(now) > maxScheduleDelay:
+			return nil, 0, errScheduleTooFarAhead
+		}
+		return &scheduleSpec{kind: db.ScheduleTypeOnce, display: "at " + args[1] + " " + args[2], firstRun: runAt}, 3, nil
+
+	case "daily", "weekly", "cron":
+		var expr, display string
+		used := 2
+		switch kind {
+		case "daily":
+			hour, minute, ok := parseClock(args[1])
+			if !ok {
+				return nil, 0, errInvalidScheduleSpec
+			}
+			expr, display = fmt.Sprintf("%d %d * * *", minute, hour), "daily "+args[1]
+		case "weekly":
+			if len(args) < 3 {
+				return nil, 0, errInvalidScheduleSpec
+			}
+			day, validDay := scheduleWeekdays[strings.ToLower(args[1])[:min(3, len(args[1]))]]
+			hour, minute, validClock := parseClock(args[2])
+			if !validDay || !validClock {
+				return nil, 0, errInvalidScheduleSpec
+			}
+			expr, display, used = fmt.Sprintf("%d %d * * %d", minute, hour, day), "weekly "+strings.ToLower(args[1])+" "+args[2], 3
+		case "cron":
+			if len(args) < 6 {
+				return nil, 0, errInvalidScheduleSpec
+			}
+			expr = strings.Join(args[1:6], " ")
+			display, used = "cron "+expr, 6
+		}
+
+		schedule, err := cron.Parse(expr)
+		if err != nil {
+			return nil, 0, errInvalidScheduleSpec
+		}
+		firstRun := schedule.Next(now)
+		if firstRun.IsZero() {
+			return nil, 0, errScheduleNeverMatches
+		}
+		if next := schedule.Next(firstRun); !next.IsZero() && next.Sub(firstRun) < minScheduleInterval {
+			return nil, 0, errScheduleTooFrequent
+		}
+		return &scheduleSpec{kind: db.ScheduleTypeCron, display: display, cronExpr: expr, firstRun: firstRun}, used, nil
+	}
+
+	return nil, 0, errInvalidScheduleSpec
+}
+
+// nextScheduledRun returns when a recurring message runs after the given run, skipping
+// occurrences that were missed while the bot was down.
+// Returns the zero time for one-off messages and schedules that no longer fire.
+func nextScheduledRun(message *db.ScheduledMessage, lastRun time.Time) time.Time {
+	now := time.Now()
+	switch message.ScheduleType {
+	case db.ScheduleTypeInterval:
+		if message.IntervalSeconds <= 0 {
+			return time.Time{}
+		}
+		interval := time.Duration(message.IntervalSeconds) * time.Second
+		next := lastRun.Add(interval)
+		if !next.After(now) {
+			next = next.Add(now.Sub(next).Truncate(interval) + interval)
+		}
+		return next
+	case db.ScheduleTypeCron:
+		schedule, err := cron.Parse(message.CronExpr)
+		if err != nil {
+			log.Errorf("[Schedules] Invalid cron expression of scheduled message %d: %v", message.ID, err)
+			return time.Time{}
+		}
+		return schedule.Next(now.UTC())
+	}
+	return time.Time{}
+}
+
+// scheduleOptionText lists the options enabled on a scheduled message.
+func scheduleOptionText(tr *i18n.Translator, message *db.ScheduledMessage) string {
+	var options []string
+	if message.AutoPin {
+		option, _ := tr.GetString("schedules_option_pin")
+		options = append(options, option)
+	}
+	if message.DeletePrevious {
+		option, _ := tr.GetString("schedules_option_delprev")
+		options = append(options, option)
+	}
+	if len(options) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(options, ", ") + ")"
+}
+
+// schedule handles the /schedule command to post a message at a set time or on a recurring schedule.
+// The message is given after the schedule, or taken from the replied message like notes.
+func (m moduleStruct) schedule(b *gotgbot.Bot, ctx *ext.Context) error {
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	lang := db.GetLanguage(ctx)
+	tr := i18n.MustNewTranslator(lang)
+
+	// check permission
+	if !chat_status.CanUserChangeInfo(b, ctx, chat, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	commandText := msg.GetText()
+	autoPin := strings.Contains(commandText, schedulePinOption)
+	deletePrevious := strings.Contains(commandText, scheduleDelPrevOption)
+	commandText = strings.NewReplacer(schedulePinOption, "", scheduleDelPrevOption, "").Replace(commandText)
+	args := strings.Fields(commandText)[1:]
+
+	var text string
+	spec, used, err := parseScheduleSpec(args, time.Now())
+	switch {
+	case err != nil:
+		key, ok := scheduleErrorKeys[err]
+		if !ok {
+			key = "schedules_usage"
+		}
+		text, _ = tr.GetString(key)
+	case msg.ReplyToMessage == nil && len(args) == used:
+		text, _ = tr.GetString("schedules_no_content")
+	case db.CountChatScheduledMessages(chat.Id) >= maxSchedulesPerChat:
+		text, _ = tr.GetString("schedules_limit_reached", i18n.TranslationParams{"limit": fmt.Sprint(maxSchedulesPerChat)})
+	}
+	if text != "" {
+		_, err := msg.Reply(b, text, helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	// capture the content the same way notes do, with the schedule in place of the note name
+	capture := *msg
+	capture.Entities = nil
+	capture.Text = "/schedule announcement"
+	if msg.ReplyToMessage == nil {
+		capture.Text += " " + strings.Join(args[used:], " ")
+	}
+	_, fileid, content, dataType, buttons, _, _, _, webPrev, isProtected, noNotif, errorMsg := helpers.GetNoteAndFilterType(&capture, false, lang)
+	if dataType == -1 && errorMsg != "" {
+		_, err := msg.Reply(b, errorMsg, helpers.Shtml())
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		return ext.EndGroups
+	}
+
+	message := &db.ScheduledMessage{
+		ChatId:          chat.Id,
+		CreatedBy:       user.Id,
+		NoteContent:     content,
+		FileID:          fileid,
+		MsgType:         dataType,
+		Buttons:         buttons,
+		WebPreview:      webPrev,
+		IsProtected:     isProtected,
+		NoNotif:         noNotif,
+		ScheduleType:    spec.kind,
+		Spec:            spec.display,
+		CronExpr:        spec.cronExpr,
+		IntervalSeconds: int64(spec.interval / time.Second),
+		NextRunAt:       spec.firstRun,
+		AutoPin:         autoPin,
+		DeletePrevious:  deletePrevious,
+	}
+
+	if err = db.AddScheduledMessage(message); err == nil {
+		err = scheduler.Enqueue(scheduledMessageJob, fmt.Sprint(message.ID), scheduledMessagePayload{ID: message.ID}, message.NextRunAt)
+		if err != nil {
+			log.Errorf("[Schedules] Failed to enqueue scheduled message %d: %v", message.ID, err)
+			_ = db.DeleteScheduledMessage(message.ID)
+		}
+	}
+
+	if err != nil {
+		text, _ = tr.GetString("schedules_action_failed")
+	} else {
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+		text, _ = tr.GetString("schedules_added", i18n.TranslationParams{
+			"id":      fmt.Sprint(message.ID),
+			"spec":    message.Spec,
+			"next":    message.NextRunAt.UTC().Format(displayTimeLayout),
+			"options": scheduleOptionText(tr, message),
+		})
+	}
+
+	_, err = msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// schedules handles the /schedules command to list the scheduled messages of a chat.
+func (moduleStruct) schedules(b *gotgbot.Bot, ctx *ext.Context) error {
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	var text string
+	messages := db.GetChatScheduledMessages(chat.Id)
+	if len(messages) == 0 {
+		text, _ = tr.GetString("schedules_none")
+	} else {
+		text, _ = tr.GetString("schedules_list_header")
+		var sb strings.Builder
+		for _, message := range messages {
+			line, _ := tr.GetString("schedules_list_entry", i18n.TranslationParams{
+				"id":      fmt.Sprint(message.ID),
+				"spec":    message.Spec,
+				"next":    message.NextRunAt.UTC().Format(displayTimeLayout),
+				"options": scheduleOptionText(tr, message),
+			})
+			sb.WriteString(line)
+		}
+		text += sb.String()
+	}
+
+	_, err := msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// unschedule handles the /unschedule command to remove a scheduled message by its ID.
+func (m moduleStruct) unschedule(b *gotgbot.Bot, ctx *ext.Context) error {
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	msg := ctx.EffectiveMessage
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	// check permission
+	if !chat_status.CanUserChangeInfo(b, ctx, chat, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var text string
+	var id uint64
+	var err error
+	if len(args) > 0 {
+		id, err = strconv.ParseUint(strings.TrimPrefix(args[0], "#"), 10, 32)
+	}
+
+	switch {
+	case len(args) == 0 || err != nil:
+		text, _ = tr.GetString("schedules_unschedule_usage")
+	case !db.RemoveScheduledMessage(chat.Id, uint(id)):
+		text, _ = tr.GetString("schedules_not_found", i18n.TranslationParams{"id": fmt.Sprint(id)})
+	default:
+		if err := scheduler.Cancel(scheduledMessageJob, fmt.Sprint(id)); err != nil {
+			log.Errorf("[Schedules] Failed to cancel job of scheduled message %d: %v", id, err)
+		}
+		logSettingsChange(b, ctx, m.moduleName, msg.GetText())
+		text, _ = tr.GetString("schedules_removed", i18n.TranslationParams{"id": fmt.Sprint(id)})
+	}
+
+	_, err = msg.Reply(b, text, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	return ext.EndGroups
+}
+
+// runScheduledMessageJob posts a scheduled message and queues its next run.
+// Recurring messages move on to their next run even if posting fails, so one
+// failure doesn't stop the schedule; one-off messages are retried instead.
+// The next run is recorded before it is queued, so a retry after a failure to
+// queue it doesn't post the message twice.
+func runScheduledMessageJob(b *gotgbot.Bot, job *db.ScheduledJob) error {
+	var payload scheduledMessagePayload
+	if err := scheduler.DecodePayload(job, &payload); err != nil {
+		// a malformed payload can't succeed on retry
+		log.Errorf("[Schedules] Invalid scheduled message payload for job %d: %v", job.ID, err)
+		return nil
+	}
+
+	message := db.GetScheduledMessage(payload.ID)
+	if message == nil {
+		return nil
+	}
+
+	// a retried job whose run was already posted and recorded only has to queue the next run
+	if message.NextRunAt.After(time.Now()) {
+		return scheduler.Enqueue(scheduledMessageJob, fmt.Sprint(message.ID), scheduledMessagePayload{ID: message.ID}, message.NextRunAt)
+	}
+
+	lastMessageId := message.LastMessageId
+	sent, err := sendScheduledMessage(b, message)
+	if err != nil {
+		log.Warnf("[Schedules] Failed to post scheduled message %d to chat %d: %v", message.ID, message.ChatId, err)
+	} else {
+		if message.DeletePrevious && lastMessageId != 0 {
+			_, _ = b.DeleteMessage(message.ChatId, lastMessageId, nil)
+		}
+		if message.AutoPin {
+			_, pinErr := b.PinChatMessage(message.ChatId, sent.MessageId, &gotgbot.PinChatMessageOpts{DisableNotification: true})
+			if pinErr != nil {
+				log.Debugf("[Schedules] Failed to pin scheduled message %d: %v", message.ID, pinErr)
+			}
+		}
+		lastMessageId = sent.MessageId
+	}
+
+	// retries move the run time of the job, so the schedule goes on from the run it was due at
+	nextRun := nextScheduledRun(message, message.NextRunAt)
+	if nextRun.IsZero() {
+		if err != nil && message.ScheduleType == db.ScheduleTypeOnce && job.Attempts < job.MaxAttempts {
+			return err
+		}
+		return db.DeleteScheduledMessage(message.ID)
+	}
+
+	if err := db.UpdateScheduledMessageRun(message.ID, lastMessageId, nextRun); err != nil {
+		return err
+	}
+	return scheduler.Enqueue(scheduledMessageJob, fmt.Sprint(message.ID), scheduledMessagePayload{ID: message.ID}, nextRun)
+}
+
+// sendScheduledMessage posts the content of a scheduled message to its chat through the notes sending path.
+// There is no triggering update, so the bot stands in as the user for fillings like {first}.
+func sendScheduledMessage(b *gotgbot.Bot, message *db.ScheduledMessage) (*gotgbot.Message, error) {
+	chatInfo, err := b.GetChat(message.ChatId, nil)
+	if err != nil {
+		return nil, err
+	}
+	chat := chatInfo.ToChat()
+	ctx := &ext.Context{
+		Update:        &gotgbot.Update{Message: &gotgbot.Message{Chat: chat}},
+		EffectiveChat: &chat,
+		EffectiveUser: &b.User,
+	}
+	return helpers.SendNote(b, &chat, ctx, message.AsNote(), 0)
+}
+
+// LoadSchedules registers the scheduled message commands and job handler.
+func LoadSchedules(dispatcher *ext.Dispatcher) {
+	HelpModule.AbleMap.Store(schedulesModule.moduleName, true)
+
+	dispatcher.AddHandler(handlers.NewCommand("schedule", schedulesModule.schedule))
+	dispatcher.AddHandler(handlers.NewCommand("schedules", schedulesModule.schedules))
+	dispatcher.AddHandler(handlers.NewCommand("unschedule", schedulesModule.unschedule))
+
+	scheduler.RegisterHandler(scheduledMessageJob, runScheduledMessageJob)
+}
@@ -5,6 +5,7 @@ import (
 	"html"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext/handlers/filters/callbackquery"
@@ -16,6 +17,7 @@ import (
 	"github.com/divideprojects/Alita_Robot/alita/utils/decorators/misc"
 	"github.com/divideprojects/Alita_Robot/alita/utils/extraction"
 	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
+	"github.com/divideprojects/Alita_Robot/alita/utils/scheduler"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/PaulSonOfLars/gotgbot/v2/ext"
@@ -78,7 +80,8 @@ func (moduleStruct) setWarnMode(b *gotgbot.Bot, ctx *ext.Context) error {
 
 // warnThisUser is a helper function that performs the actual warning process,
 // including limit checking and enforcement of warn mode actions.
-func (moduleStruct) warnThisUser(b *gotgbot.Bot, ctx *ext.Context, userId int64, reason, warnType string) (err error) {
+// issuerId is recorded as the user who issued the warning.
+func (moduleStruct) warnThisUser(b *gotgbot.Bot, ctx *ext.Context, userId, issuerId int64, reason, warnType string) (err error) {
 	var (
 		reply    string
 		keyboard gotgbot.InlineKeyboardMarkup
@@ -122,53 +125,63 @@ func (moduleStruct) warnThisUser(b *gotgbot.Bot, ctx *ext.Context, userId int64,
 
 	u := chatMember.MergeChatMember().User
 	warnrc := db.GetWarnSetting(chat.Id)
-	numWarns, reasons := db.WarnUser(userId, chat.Id, reason)
+	ladder := db.GetWarnLadder(chat.Id)
+	warnLimit := warnLimitFor(warnrc, ladder)
+	numWarns, reasons := db.WarnUser(userId, chat.Id, issuerId, reason)
 
-	if numWarns >= warnrc.WarnLimit {
+	entry := newModAction(ctx, db.ModActionWarn, userId, reason, 0)
+	entry.ActorId = issuerId
+	recordModAction(b, ctx, entry)
+
+	if numWarns >= warnLimit && len(ladder) > 0 {
+		db.ResetUserWarns(userId, chat.Id)
+		step := ladder[len(ladder)-1]
+		err = applyWarnLadderStep(b, chat, userId, step)
+		if err != nil {
+			log.Errorf("[warn] warnladder: %s (%d) - %s", step.Action, userId, err)
+			return err
+		}
+		reply, _ = tr.GetString("warns_ladder_limit_reached", i18n.TranslationParams{
+			"current": strconv.Itoa(numWarns),
+			"limit":   strconv.Itoa(warnLimit),
+		})
+		logWarnPunishment(b, ctx, issuerId, userId, step.Action, step.Duration, numWarns, warnLimit)
+		reply += warnLadderActionText(tr, step, helpers.MentionHtml(u.Id, u.FirstName))
+		var sb strings.Builder
+		for _, warnReason := range reasons {
+			sb.WriteString(fmt.Sprintf("\n - %s", html.EscapeString(warnReason)))
+		}
+		reply += sb.String()
+	} else if numWarns >= warnLimit {
 		db.ResetUserWarns(userId, chat.Id)
 		switch warnrc.WarnMode {
 		case "kick":
-			_, err = chat.BanMember(b, userId, nil)
+			err = applyPunishment(b, chat, userId, "kick", 0)
 			temp, _ := tr.GetString("warns_limit_reached_kick")
 			reply = fmt.Sprintf(temp, numWarns, warnrc.WarnLimit, helpers.MentionHtml(u.Id, u.FirstName))
 			if err != nil {
 				log.Errorf("[warn] warnlimit: kick (%d) - %s", userId, err)
 				return err
 			}
+			logWarnPunishment(b, ctx, issuerId, userId, db.ModActionKick, 0, numWarns, warnLimit)
 		case "mute":
-			_, err = chat.RestrictMember(b, userId,
-				gotgbot.ChatPermissions{
-					CanSendMessages:       false,
-					CanSendPhotos:         false,
-					CanSendVideos:         false,
-					CanSendAudios:         false,
-					CanSendDocuments:      false,
-					CanSendVideoNotes:     false,
-					CanSendVoiceNotes:     false,
-					CanAddWebPagePreviews: false,
-					CanChangeInfo:         false,
-					CanInviteUsers:        false,
-					CanPinMessages:        false,
-					CanManageTopics:       false,
-					CanSendPolls:          false,
-					CanSendOtherMessages:  false,
-				},
-				nil,
-			)
+			err = applyPunishment(b, chat, userId, "mute", 0)
 			temp, _ := tr.GetString("warns_limit_reached_mute")
 			reply = fmt.Sprintf(temp, numWarns, warnrc.WarnLimit, helpers.MentionHtml(u.Id, u.FirstName))
 			if err != nil {
 				log.Errorf("[warn] warnlimit: mute (%d) - %s", userId, err)
 				return err
 			}
+			logWarnPunishment(b, ctx, issuerId, userId, db.ModActionMute, 0, numWarns, warnLimit)
 		case "ban":
-			_, err = chat.BanMember(b, userId, nil)
+			err = applyPunishment(b, chat, userId, "ban", 0)
 			temp, _ := tr.GetString("warns_limit_reached_ban")
 			reply = fmt.Sprintf(temp, numWarns, warnrc.WarnLimit, helpers.MentionHtml(u.Id, u.FirstName))
 			if err != nil {
 				log.Errorf("[warn] warnlimit: ban (%d) - %s", userId, err)
 				return err
 			}
+			logWarnPunishment(b, ctx, issuerId, userId, db.ModActionBan, 0, numWarns, warnLimit)
 		}
 		var sb strings.Builder
 		for _, warnReason := range reasons {
@@ -206,12 +219,27 @@ func (moduleStruct) warnThisUser(b *gotgbot.Bot, ctx *ext.Context, userId int64,
 		}
 
 		temp, _ := tr.GetString("warns_current_count")
-		reply = fmt.Sprintf(temp, helpers.MentionHtml(u.Id, u.FirstName), numWarns, warnrc.WarnLimit)
+		reply = fmt.Sprintf(temp, helpers.MentionHtml(u.Id, u.FirstName), numWarns, warnLimit)
 
 		if reason != "" {
 			temp, _ := tr.GetString("warns_reason_display")
 			reply += fmt.Sprintf(temp, html.EscapeString(reason))
 		}
+
+		// apply the intermediate ladder step for this warn count, if any
+		for _, step := range ladder {
+			if step.WarnCount != numWarns {
+				continue
+			}
+			err = applyWarnLadderStep(b, chat, userId, step)
+			if err != nil {
+				log.Errorf("[warn] warnladder: %s (%d) - %s", step.Action, userId, err)
+				return err
+			}
+			logWarnPunishment(b, ctx, issuerId, userId, step.Action, step.Duration, numWarns, warnLimit)
+			reply += warnLadderActionText(tr, step, helpers.MentionHtml(u.Id, u.FirstName))
+			break
+		}
 	}
 	_, err = b.SendMessage(chat.Id, reply,
 		&gotgbot.SendMessageOpts{
@@ -234,6 +262,39 @@ func (moduleStruct) warnThisUser(b *gotgbot.Bot, ctx *ext.Context, userId int64,
 	return ext.EndGroups
 }
 
+// warnLimitFor returns the number of warnings at which the final punishment is applied.
+// The top step of a warn ladder takes precedence over the chat's warn limit.
+func warnLimitFor(warnrc *db.WarnSettings, ladder []*db.WarnLadderStep) int {
+	if len(ladder) > 0 {
+		return ladder[len(ladder)-1].WarnCount
+	}
+	return warnrc.WarnLimit
+}
+
+// applyWarnLadderStep punishes a user according to a warn ladder step.
+// Timed actions are lifted by Telegram once their duration has passed.
+func applyWarnLadderStep(b *gotgbot.Bot, chat *gotgbot.Chat, userId int64, step *db.WarnLadderStep) error {
+	return applyPunishment(b, chat, userId, step.Action, step.Duration)
+}
+
+// logWarnPunishment records a punishment applied for collecting warnings in the audit log.
+// The issuer of the last warning is recorded as the actor.
+func logWarnPunishment(b *gotgbot.Bot, ctx *ext.Context, issuerId, userId int64, action string, duration int64, numWarns, warnLimit int) {
+	// timed ladder actions are logged as their base action along with the duration
+	entry := newModAction(ctx, strings.TrimPrefix(action, "t"), userId, fmt.Sprintf("%d/%d warns", numWarns, warnLimit), duration)
+	entry.ActorId = issuerId
+	recordModAction(b, ctx, entry)
+}
+
+// warnLadderActionText describes the punishment a user received from a warn ladder step.
+func warnLadderActionText(tr *i18n.Translator, step *db.WarnLadderStep, userMention string) string {
+	text, _ := tr.GetString("warns_ladder_action_"+step.Action, i18n.TranslationParams{
+		"user": userMention,
+		"time": formatWarnTime(tr, step.Duration),
+	})
+	return text
+}
+
 // warnUser handles the /warn command to issue warnings to users
 // with optional reasons, requiring admin permissions.
 func (m moduleStruct) warnUser(b *gotgbot.Bot, ctx *ext.Context) error {
@@ -290,7 +351,7 @@ func (m moduleStruct) warnUser(b *gotgbot.Bot, ctx *ext.Context) error {
 		warnusr = userId
 	}
 
-	return m.warnThisUser(b, ctx, warnusr, reason, "warn")
+	return m.warnThisUser(b, ctx, warnusr, user.Id, reason, "warn")
 }
 
 // sWarnUser handles the /swarn command to silently warn users
@@ -349,7 +410,7 @@ func (m moduleStruct) sWarnUser(b *gotgbot.Bot, ctx *ext.Context) error {
 		warnusr = userId
 	}
 
-	return m.warnThisUser(b, ctx, warnusr, reason, "swarn")
+	return m.warnThisUser(b, ctx, warnusr, user.Id, reason, "swarn")
 }
 
 // dWarnUser handles the /dwarn command to warn users and delete
@@ -408,7 +469,7 @@ func (m moduleStruct) dWarnUser(b *gotgbot.Bot, ctx *ext.Context) error {
 		warnusr = userId
 	}
 
-	return m.warnThisUser(b, ctx, warnusr, reason, "dwarn")
+	return m.warnThisUser(b, ctx, warnusr, user.Id, reason, "dwarn")
 }
 
 // warnings handles the /warnings command to display current
@@ -431,8 +492,16 @@ func (moduleStruct) warnings(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	warnrc := db.GetWarnSetting(chat.Id)
-	temp, _ := tr.GetString("warns_settings_current")
-	text := fmt.Sprintf(temp, warnrc.WarnLimit, warnrc.WarnMode)
+	text, _ := tr.GetString("warns_settings_current", i18n.TranslationParams{
+		"limit": strconv.Itoa(warnrc.WarnLimit),
+		"mode":  warnrc.WarnMode,
+	})
+	warnTime, _ := tr.GetString("warns_settings_warn_time", i18n.TranslationParams{"time": formatWarnTime(tr, warnrc.WarnTime)})
+	text += warnTime
+	if ladder := db.GetWarnLadder(chat.Id); len(ladder) > 0 {
+		ladderText, _ := tr.GetString("warns_settings_ladder", i18n.TranslationParams{"steps": strconv.Itoa(len(ladder))})
+		text += ladderText
+	}
 	_, err := msg.Reply(b, text, helpers.Shtml())
 	if err != nil {
 		log.Error(err)
@@ -475,29 +544,41 @@ func (moduleStruct) warns(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	numWarns, reasons := db.GetWarns(userId, chat.Id)
-	text := ""
 
 	if numWarns != 0 {
-		warnrc := db.GetWarnSetting(chat.Id)
-		if len(reasons) > 0 {
-			temp, _ := tr.GetString("warns_user_has_warnings")
-			text = fmt.Sprintf(temp, numWarns, warnrc.WarnLimit)
+		warnLimit := warnLimitFor(db.GetWarnSetting(chat.Id), db.GetWarnLadder(chat.Id))
+		records := db.GetWarnRecords(userId, chat.Id)
+		if len(records) > 0 || len(reasons) > 0 {
+			text, _ := tr.GetString("warns_user_has_warnings", i18n.TranslationParams{
+				"current": strconv.Itoa(numWarns),
+				"limit":   strconv.Itoa(warnLimit),
+			})
 			var sb strings.Builder
-			for _, reason := range reasons {
-				sb.WriteString(fmt.Sprintf("\n - %s", reason))
+			if len(records) > 0 {
+				for i, record := range records {
+					sb.WriteString(formatWarnRecord(tr, i+1, record))
+				}
+			} else {
+				// warnings issued before individual records were kept
+				for _, reason := range reasons {
+					sb.WriteString(fmt.Sprintf("\n - %s", html.EscapeString(reason)))
+				}
 			}
 			text += sb.String()
 			msgs := helpers.SplitMessage(text)
 			for _, msgText := range msgs {
-				_, err := msg.Reply(b, msgText, nil)
+				_, err := msg.Reply(b, msgText, helpers.Shtml())
 				if err != nil {
 					log.Error(err)
 					return err
 				}
 			}
 		} else {
-			temp, _ := tr.GetString("warns_user_no_reasons")
-			_, err := msg.Reply(b, fmt.Sprintf(temp, numWarns, warnrc.WarnLimit), nil)
+			text, _ := tr.GetString("warns_user_no_reasons", i18n.TranslationParams{
+				"current": strconv.Itoa(numWarns),
+				"limit":   strconv.Itoa(warnLimit),
+			})
+			_, err := msg.Reply(b, text, nil)
 			if err != nil {
 				log.Error(err)
 				return err
@@ -514,6 +595,52 @@ func (moduleStruct) warns(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// formatWarnRecord formats a single warning for the /warns history,
+// including who issued it, when, and when it expires.
+func formatWarnRecord(tr *i18n.Translator, index int, record *db.WarnRecord) string {
+	var issuer string
+	if record.IssuedBy == 0 {
+		issuer, _ = tr.GetString("warns_record_unknown_issuer")
+	} else {
+		issuer = mentionUserById(record.IssuedBy)
+	}
+
+	line, _ := tr.GetString("warns_record_line", i18n.TranslationParams{
+		"index":  strconv.Itoa(index),
+		"reason": html.EscapeString(record.Reason),
+		"issuer": issuer,
+		"date":   record.CreatedAt.UTC().Format(displayTimeLayout),
+	})
+	if record.ExpiresAt != nil {
+		expiry, _ := tr.GetString("warns_record_expires", i18n.TranslationParams{
+			"date": record.ExpiresAt.UTC().Format(displayTimeLayout),
+		})
+		line += expiry
+	}
+	return line
+}
+
+// formatWarnTime formats a duration in seconds using the largest of the m/h/d/w units
+// accepted by time arguments that divides it evenly, or "never" for a warn time of 0.
+// It is used for the durations of the other moderation actions as well.
+func formatWarnTime(tr *i18n.Translator, seconds int64) string {
+	if seconds <= 0 {
+		text, _ := tr.GetString("warns_time_never")
+		return text
+	}
+
+	switch {
+	case seconds%(7*24*60*60) == 0:
+		return fmt.Sprintf("%dw", seconds/(7*24*60*60))
+	case seconds%(24*60*60) == 0:
+		return fmt.Sprintf("%dd", seconds/(24*60*60))
+	case seconds%(60*60) == 0:
+		return fmt.Sprintf("%dh", seconds/(60*60))
+	default:
+		return fmt.Sprintf("%dm", seconds/60)
+	}
+}
+
 // rmWarnButton processes callback queries from remove warning buttons
 // to remove the latest warning from a user, requiring admin permissions.
 func (moduleStruct) rmWarnButton(b *gotgbot.Bot, ctx *ext.Context) error {
@@ -537,6 +664,7 @@ func (moduleStruct) rmWarnButton(b *gotgbot.Bot, ctx *ext.Context) error {
 
 	res := db.RemoveWarn(int64(userId), chat.Id)
 	if res {
+		logModAction(b, ctx, db.ModActionUnwarn, int64(userId), "", 0)
 		temp, _ := tr.GetString("warns_removed_success")
 		replyText = fmt.Sprintf(temp, helpers.MentionHtml(user.Id, user.FirstName))
 	} else {
@@ -616,6 +744,192 @@ func (moduleStruct) setWarnLimit(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// setWarnTime handles the /setwarntime command to configure how long
+// warnings last before they expire, or disable expiry with "off".
+func (moduleStruct) setWarnTime(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	// Check permissions
+	if !chat_status.RequireBotAdmin(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var replyText string
+
+	if len(args) == 0 {
+		warnrc := db.GetWarnSetting(chat.Id)
+		replyText, _ = tr.GetString("warns_time_current", i18n.TranslationParams{"time": formatWarnTime(tr, warnrc.WarnTime)})
+	} else {
+		switch strings.ToLower(args[0]) {
+		case "off", "no", "0":
+			go db.SetWarnTime(chat.Id, 0)
+			replyText, _ = tr.GetString("warns_time_disabled")
+		default:
+			expiresAt, _, _ := extraction.ExtractTime(b, ctx, args[0])
+			if expiresAt == -1 {
+				return ext.EndGroups
+			}
+			warnTime := durationUntil(expiresAt)
+			if warnTime < 60 {
+				replyText, _ = tr.GetString("warns_time_too_short")
+				break
+			}
+			go db.SetWarnTime(chat.Id, warnTime)
+			replyText, _ = tr.GetString("warns_time_set_success", i18n.TranslationParams{"time": formatWarnTime(tr, warnTime)})
+		}
+	}
+
+	_, err := msg.Reply(b, replyText, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return ext.EndGroups
+}
+
+// warnLadder handles the /warnladder command to view and edit the
+// escalating punishments applied as users collect warnings.
+func (moduleStruct) warnLadder(b *gotgbot.Bot, ctx *ext.Context) error {
+	msg := ctx.EffectiveMessage
+	// connection status
+	connectedChat := helpers.IsUserConnected(b, ctx, true, true)
+	if connectedChat == nil {
+		return ext.EndGroups
+	}
+	ctx.EffectiveChat = connectedChat
+	chat := ctx.EffectiveChat
+	user := ctx.EffectiveSender.User
+	args := ctx.Args()[1:]
+	tr := i18n.MustNewTranslator(db.GetLanguage(ctx))
+
+	// Check permissions
+	if !chat_status.RequireBotAdmin(b, ctx, nil, false) {
+		return ext.EndGroups
+	}
+	if !chat_status.RequireUserAdmin(b, ctx, nil, user.Id, false) {
+		return ext.EndGroups
+	}
+
+	var replyText string
+
+	if len(args) == 0 {
+		ladder := db.GetWarnLadder(chat.Id)
+		if len(ladder) == 0 {
+			warnrc := db.GetWarnSetting(chat.Id)
+			replyText, _ = tr.GetString("warns_ladder_empty", i18n.TranslationParams{
+				"limit": strconv.Itoa(warnrc.WarnLimit),
+				"mode":  warnrc.WarnMode,
+			})
+		} else {
+			replyText, _ = tr.GetString("warns_ladder_header")
+			var sb strings.Builder
+			for _, step := range ladder {
+				action := step.Action
+				if step.Duration > 0 {
+					action = fmt.Sprintf("%s %s", step.Action, formatWarnTime(tr, step.Duration))
+				}
+				line, _ := tr.GetString("warns_ladder_step_line", i18n.TranslationParams{
+					"count":  strconv.Itoa(step.WarnCount),
+					"action": action,
+				})
+				sb.WriteString(line)
+			}
+			replyText += sb.String()
+		}
+	} else {
+		switch strings.ToLower(args[0]) {
+		case "add", "set":
+			if len(args) < 3 {
+				replyText, _ = tr.GetString("warns_ladder_help")
+				break
+			}
+			count, err := strconv.Atoi(args[1])
+			if err != nil || count < 1 || count > 100 {
+				replyText, _ = tr.GetString("warns_ladder_invalid_count")
+				break
+			}
+
+			action := strings.ToLower(args[2])
+			var duration int64
+			switch action {
+			case "mute", "kick", "ban":
+			case "tmute", "tban":
+				if len(args) < 4 {
+					replyText, _ = tr.GetString("warns_ladder_time_required")
+					break
+				}
+				untilDate, _, _ := extraction.ExtractTime(b, ctx, args[3])
+				if untilDate == -1 {
+					return ext.EndGroups
+				}
+				// Telegram makes restrictions without a duration permanent
+				if duration = durationUntil(untilDate); duration <= 0 {
+					replyText, _ = tr.GetString("warns_ladder_invalid_time")
+				}
+			default:
+				replyText, _ = tr.GetString("warns_ladder_invalid_action", i18n.TranslationParams{"action": html.EscapeString(args[2])})
+			}
+			if replyText != "" {
+				break
+			}
+
+			if err := db.SetWarnLadderStep(chat.Id, count, action, duration); err != nil {
+				replyText, _ = tr.GetString("warns_ladder_failed")
+				break
+			}
+			replyText, _ = tr.GetString("warns_ladder_step_set", i18n.TranslationParams{
+				"count":  strconv.Itoa(count),
+				"action": action,
+			})
+		case "rm", "remove", "del":
+			if len(args) < 2 {
+				replyText, _ = tr.GetString("warns_ladder_help")
+				break
+			}
+			count, err := strconv.Atoi(args[1])
+			if err != nil {
+				replyText, _ = tr.GetString("warns_ladder_invalid_count")
+				break
+			}
+			if db.RemoveWarnLadderStep(chat.Id, count) {
+				replyText, _ = tr.GetString("warns_ladder_step_removed", i18n.TranslationParams{"count": strconv.Itoa(count)})
+			} else {
+				replyText, _ = tr.GetString("warns_ladder_step_not_found", i18n.TranslationParams{"count": strconv.Itoa(count)})
+			}
+		case "clear", "reset":
+			if err := db.ClearWarnLadder(chat.Id); err != nil {
+				replyText, _ = tr.GetString("warns_ladder_failed")
+			} else {
+				replyText, _ = tr.GetString("warns_ladder_cleared")
+			}
+		default:
+			replyText, _ = tr.GetString("warns_ladder_help")
+		}
+	}
+
+	_, err := msg.Reply(b, replyText, helpers.Shtml())
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+
+	return ext.EndGroups
+}
+
 // resetWarns handles the /resetwarns command to clear all warnings
 // for a specific user, requiring admin permissions.
 func (moduleStruct) resetWarns(b *gotgbot.Bot, ctx *ext.Context) error {
@@ -657,6 +971,7 @@ func (moduleStruct) resetWarns(b *gotgbot.Bot, ctx *ext.Context) error {
 	}
 
 	db.ResetUserWarns(userId, chat.Id)
+	logModAction(b, ctx, db.ModActionResetWarns, userId, "", 0)
 	text, _ := tr.GetString("warns_reset_individual_success")
 	_, err := msg.Reply(b, text, helpers.Shtml())
 	if err != nil {
@@ -735,6 +1050,7 @@ func (moduleStruct) warnsButtonHandler(b *gotgbot.Bot, ctx *ext.Context) error {
 	switch response {
 	case "yes":
 		go db.ResetAllChatWarns(query.Message.GetChat().Id)
+		logModAction(b, ctx, db.ModActionResetWarns, 0, "", 0)
 		helpText, _ = tr.GetString("warns_reset_all_done")
 	case "no":
 		helpText, _ = tr.GetString("warns_reset_all_cancelled")
@@ -806,6 +1122,7 @@ func (moduleStruct) removeWarn(b *gotgbot.Bot, ctx *ext.Context) error {
 
 	var replyText string
 	if db.RemoveWarn(userId, chat.Id) {
+		logModAction(b, ctx, db.ModActionUnwarn, userId, "", 0)
 		temp, _ := tr.GetString("warns_removed_success")
 		replyText = fmt.Sprintf(temp, helpers.MentionHtml(user.Id, user.FirstName))
 	} else {
@@ -827,6 +1144,22 @@ func (moduleStruct) removeWarn(b *gotgbot.Bot, ctx *ext.Context) error {
 	return ext.EndGroups
 }
 
+// warnSweepJob is the scheduler job type that removes expired warnings.
+// A single job is kept queued, which schedules its next run every time it runs.
+const warnSweepJob = "warn_sweep"
+
+// warnSweepInterval is how often expired warnings are removed.
+const warnSweepInterval = time.Minute
+
+// runWarnSweepJob removes expired warnings and schedules the next sweep.
+// Failures are left to the next sweep instead of being retried.
+func runWarnSweepJob(_ *gotgbot.Bot, _ *db.ScheduledJob) error {
+	if _, err := db.CleanupExpiredWarns(); err != nil {
+		log.Errorf("[Warns] Failed to cleanup expired warns: %v", err)
+	}
+	return scheduler.Enqueue(warnSweepJob, warnSweepJob, struct{}{}, time.Now().Add(warnSweepInterval))
+}
+
 // LoadWarns registers all warns module handlers with the dispatcher,
 // including warning commands and callback handlers.
 func LoadWarns(dispatcher *ext.Dispatcher) {
@@ -845,8 +1178,16 @@ func LoadWarns(dispatcher *ext.Dispatcher) {
 	misc.AddCmdToDisableable("warns")
 	dispatcher.AddHandler(handlers.NewCommand("setwarnlimit", warnsModule.setWarnLimit))
 	dispatcher.AddHandler(handlers.NewCommand("setwarnmode", warnsModule.setWarnMode))
+	dispatcher.AddHandler(handlers.NewCommand("setwarntime", warnsModule.setWarnTime))
+	dispatcher.AddHandler(handlers.NewCommand("warnladder", warnsModule.warnLadder))
 	dispatcher.AddHandler(handlers.NewCommand("resetallwarns", warnsModule.resetAllWarns))
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("rmAllChatWarns"), warnsModule.warnsButtonHandler))
 	dispatcher.AddHandler(handlers.NewCommand("warnings", warnsModule.warnings))
 	dispatcher.AddHandler(handlers.NewCallback(callbackquery.Prefix("rmWarn"), warnsModule.rmWarnButton))
+
+	// Start periodic sweep of expired warnings
+	scheduler.RegisterHandler(warnSweepJob, runWarnSweepJob)
+	if err := scheduler.Enqueue(warnSweepJob, warnSweepJob, struct{}{}, time.Now()); err != nil {
+		log.Errorf("[Warns] Failed to schedule expired warns sweep: %v", err)
+	}
 }
@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 	"github.com/divideprojects/Alita_Robot/alita/config"
@@ -59,6 +60,11 @@ func InitCache() error {
 	return nil
 }
 
+// RedisClient returns the client connected by InitCache, or nil before the cache is initialized.
+func RedisClient() *redis.Client {
+	return redisClient
+}
+
 // ClearAllCaches clears all cache entries from Redis using FLUSHDB.
 // This function is called on bot startup to ensure fresh data and eliminate cache coherence issues.
 // Since Redis is dedicated to the bot, FLUSHDB safely clears all keys in the current database.
@@ -78,3 +84,28 @@ func ClearAllCaches() error {
 	log.Info("[Cache] Successfully cleared all cache entries")
 	return nil
 }
+
+// releaseLockScript deletes a lock only if it is still held by the caller's token,
+// so a lock that expired and was taken by someone else is never released by mistake.
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// AcquireLock tries to take a Redis lock that expires after ttl.
+// Returns true if the lock was taken; token must be passed to ReleaseLock to free it.
+func AcquireLock(key, token string, ttl time.Duration) (bool, error) {
+	if redisClient == nil {
+		return false, fmt.Errorf("redis client not initialized")
+	}
+	return redisClient.SetNX(Context, key, token, ttl).Result()
+}
+
+// ReleaseLock frees a lock taken with AcquireLock, if it is still held with the given token.
+func ReleaseLock(key, token string) error {
+	if redisClient == nil {
+		return fmt.Errorf("redis client not initialized")
+	}
+	return releaseLockScript.Run(Context, redisClient, []string{key}, token).Err()
+}
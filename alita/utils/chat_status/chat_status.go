@@ -190,6 +190,13 @@ func IsUserAdmin(b *gotgbot.Bot, chatID, userId int64) bool {
 	return false
 }
 
+// IsUserApprovedOrAdmin checks whether a user is exempt from automated moderation in a chat,
+// either as an admin or as a member approved with /approve.
+// The approved users are checked first since their list is cached per chat.
+func IsUserApprovedOrAdmin(b *gotgbot.Bot, chatID, userId int64) bool {
+	return db.IsUserApproved(chatID, userId) || IsUserAdmin(b, chatID, userId)
+}
+
 // IsBotAdmin checks if the bot has administrator privileges in the specified chat.
 // Returns true for private chats (bot is always "admin" in private).
 // For groups, verifies the bot's actual admin status.
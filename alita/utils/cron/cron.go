@@ -0,0 +1,155 @@
+// Package cron parses standard five-field cron expressions and computes their next occurrence.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidExpression is returned when a cron expression cannot be parsed.
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// maxLookahead bounds the search for the next occurrence, so expressions that
+// can never match (such as the 31st of February) don't loop forever.
+const maxLookahead = 366 * 24 * time.Hour
+
+// field describes the range of values allowed in one position of a cron expression.
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Schedule is a parsed cron expression.
+// Each set marks the values at which the schedule fires.
+type Schedule struct {
+	minutes, hours, days, months, weekdays uint64
+	// anyDay and anyWeekday record whether the day fields were "*", which
+	// decides how the two fields are combined as in standard cron.
+	anyDay, anyWeekday bool
+}
+
+// Parse parses a cron expression of the form "minute hour day-of-month month day-of-week".
+// Each field accepts "*", single values, ranges ("1-5"), steps ("*/15", "0-30/10") and
+// comma separated lists of those. Day of week runs from 0 (Sunday) to 7 (also Sunday).
+func Parse(expr string) (*Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%w: expected %d fields, got %d", ErrInvalidExpression, len(fields), len(parts))
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	// 7 is an alias for Sunday
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+		sets[4] &^= 1 << 7
+	}
+
+	return &Schedule{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   sets[4],
+		anyDay:     parts[2] == "*",
+		anyWeekday: parts[4] == "*",
+	}, nil
+}
+
+// parseField parses one comma separated field into a bit set of the values it allows.
+func parseField(part string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(part, ",") {
+		rangePart, step := item, 1
+		if before, after, found := strings.Cut(item, "/"); found {
+			n, err := strconv.Atoi(after)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%w: bad step %q in %s", ErrInvalidExpression, after, f.name)
+			}
+			rangePart, step = before, n
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			start, end, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(start); err != nil {
+				return 0, fmt.Errorf("%w: bad value %q in %s", ErrInvalidExpression, start, f.name)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(end); err != nil {
+					return 0, fmt.Errorf("%w: bad value %q in %s", ErrInvalidExpression, end, f.name)
+				}
+			} else if step > 1 {
+				// "5/15" means every 15 starting at 5
+				high = f.max
+			}
+		}
+
+		if low < f.min || high > f.max || low > high {
+			return 0, fmt.Errorf("%w: %s must be between %d and %d", ErrInvalidExpression, f.name, f.min, f.max)
+		}
+		for v := low; v <= high; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first time after t at which the schedule fires, in t's location.
+// Returns the zero time if the schedule doesn't fire within the next year.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxLookahead)
+
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay checks the day of month and day of week fields.
+// As in standard cron, when both are restricted a day matching either one fires.
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dayMatch := s.days&(1<<uint(t.Day())) != 0
+	weekdayMatch := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return dayMatch && weekdayMatch
+	}
+	return dayMatch || weekdayMatch
+}
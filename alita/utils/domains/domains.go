@@ -0,0 +1,109 @@
+// Package domains normalizes domain names and matches the hosts of links against domain lists.
+package domains
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// shortLinkHosts are the link shortening services recognised by IsShortLink.
+var shortLinkHosts = []string{
+	"bit.ly",
+	"bl.ink",
+	"buff.ly",
+	"cutt.ly",
+	"goo.gl",
+	"is.gd",
+	"lnkd.in",
+	"ow.ly",
+	"rb.gy",
+	"rebrand.ly",
+	"s.id",
+	"shorturl.at",
+	"t.co",
+	"t.ly",
+	"tiny.cc",
+	"tinyurl.com",
+	"v.gd",
+}
+
+// Normalize turns a domain name, or a link to it, into the lowercase ASCII form used
+// for matching, with internationalized labels encoded as punycode.
+// Returns false if the input isn't a valid domain name.
+func Normalize(input string) (string, bool) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if !strings.Contains(input, "://") {
+		input = "http://" + input
+	}
+	u, err := url.Parse(input)
+	if err != nil {
+		return "", false
+	}
+
+	host := strings.TrimSuffix(u.Hostname(), ".")
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return "", false
+	}
+	for i, label := range labels {
+		ascii, ok := labelToASCII(label)
+		if !ok {
+			return "", false
+		}
+		labels[i] = ascii
+	}
+	return strings.Join(labels, "."), true
+}
+
+// labelToASCII encodes a single domain label as punycode if it isn't plain ASCII,
+// and checks it only uses the characters allowed in host names.
+func labelToASCII(label string) (string, bool) {
+	if label == "" {
+		return "", false
+	}
+	for _, r := range label {
+		if r >= 0x80 {
+			encoded, err := idna.Lookup.ToASCII(label)
+			if err != nil {
+				return "", false
+			}
+			label = encoded
+			break
+		}
+	}
+	// the length limit applies to the encoded label
+	if len(label) > 63 {
+		return "", false
+	}
+	for _, r := range label {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", false
+		}
+	}
+	return label, true
+}
+
+// Matches checks whether a normalized host is the given normalized domain or one of its subdomains.
+func Matches(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// MatchesAny checks whether a normalized host matches any of the given normalized domains.
+func MatchesAny(host string, domainList []string) bool {
+	for _, domain := range domainList {
+		if Matches(host, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsShortLink checks whether a normalized host belongs to a known link shortening service.
+func IsShortLink(host string) bool {
+	return slices.ContainsFunc(shortLinkHosts, func(domain string) bool {
+		return Matches(host, domain)
+	})
+}
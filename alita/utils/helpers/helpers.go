@@ -653,11 +653,23 @@ func GetNoteAndFilterType(msg *gotgbot.Message, isFilter bool, language string)
 				text = strings.Join(args[1:], " ")
 			}
 		}
+		if isFilter {
+			// filters can have a quoted trigger or a list of them, as in (hi, "good morning") reply
+			keyWord, text = SplitFilterKeyword(strings.Join(args, " "))
+			if text == "" {
+				// only triggers were given, the filter has no content
+				return
+			}
+		}
 		text, _buttons = tgmd2html.MD2HTMLButtonsV2(text)
 		dataType = db.TEXT
 	} else if replyMsg != nil && len(args) >= 1 {
 		// TODO: Fix circular dependency with extraction package
 		keyWord = strings.Join(args, " ")
+		if isFilter && replyMsg.Caption == "" {
+			// all the arguments are triggers, the reply is the content
+			rawText = replyMsg.OriginalMDV2()
+		}
 
 		if replyMsg.ReplyMarkup == nil {
 			text, _buttons = tgmd2html.MD2HTMLButtonsV2(rawText)
@@ -704,6 +716,39 @@ func GetNoteAndFilterType(msg *gotgbot.Message, isFilter bool, language string)
 	return
 }
 
+// SplitFilterKeyword splits the arguments of a filter into its triggers and its reply.
+// The triggers are either a list in parentheses, a quoted trigger or the first word;
+// commas and parentheses inside quotes don't end them.
+func SplitFilterKeyword(s string) (keyWord, rest string) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ""
+	}
+
+	var closing rune
+	switch s[0] {
+	case '(':
+		closing = ')'
+	case '"':
+		closing = '"'
+	default:
+		keyWord, rest, _ = strings.Cut(s, " ")
+		return keyWord, strings.TrimSpace(rest)
+	}
+
+	inQuotes := false
+	for i, r := range s[1:] {
+		switch {
+		case r == closing && (closing == '"' || !inQuotes):
+			return s[:i+2], strings.TrimSpace(s[i+2:])
+		case r == '"':
+			inQuotes = !inQuotes
+		}
+	}
+	// unterminated, the whole text is the keyword
+	return s, ""
+}
+
 // GetWelcomeType extracts and processes welcome/greeting content from a Telegram message.
 // Similar to GetNoteAndFilterType but specifically for greeting messages.
 // Returns processed content with data type, file ID, and buttons for the greeting.
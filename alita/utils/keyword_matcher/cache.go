@@ -24,8 +24,14 @@ func NewCache(ttl time.Duration) *Cache {
 	}
 }
 
-// GetOrCreateMatcher gets or creates a keyword matcher for the given chat
+// GetOrCreateMatcher gets or creates a keyword matcher for the given chat,
+// with patterns matching anywhere in the text like substring triggers
 func (c *Cache) GetOrCreateMatcher(chatID int64, patterns []string) *KeywordMatcher {
+	return c.GetOrCreateTriggerMatcher(chatID, substringTriggers(patterns), NormalizeOff)
+}
+
+// GetOrCreateTriggerMatcher gets or creates a keyword matcher for the given chat, triggers and normalization level
+func (c *Cache) GetOrCreateTriggerMatcher(chatID int64, triggers []Trigger, level string) *KeywordMatcher {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -34,20 +40,20 @@ func (c *Cache) GetOrCreateMatcher(chatID int64, patterns []string) *KeywordMatc
 
 	// Check if matcher exists
 	if matcher, exists := c.matchers[chatID]; exists {
-		// Check if patterns have changed
-		existingPatterns := matcher.GetPatterns()
-		if patternsEqual(existingPatterns, patterns) {
+		// Check if triggers have changed
+		existingTriggers := matcher.GetTriggers()
+		if matcher.GetLevel() == level && triggersEqual(existingTriggers, triggers) {
 			return matcher
 		}
 	}
 
 	// Create new matcher
-	matcher := NewKeywordMatcher(patterns)
+	matcher := NewTriggerMatcher(triggers, level)
 	c.matchers[chatID] = matcher
 
 	log.WithFields(log.Fields{
 		"chatID":        chatID,
-		"pattern_count": len(patterns),
+		"pattern_count": len(triggers),
 	}).Debug("Created/updated keyword matcher")
 
 	return matcher
@@ -77,20 +83,20 @@ func (c *Cache) CleanupExpired() {
 	}
 }
 
-// patternsEqual checks if two pattern slices are equal
-func patternsEqual(a, b []string) bool {
+// triggersEqual checks if two trigger slices are equal
+func triggersEqual(a, b []Trigger) bool {
 	if len(a) != len(b) {
 		return false
 	}
 
 	// Create maps for efficient comparison
-	aMap := make(map[string]bool)
-	for _, pattern := range a {
-		aMap[pattern] = true
+	aMap := make(map[Trigger]bool)
+	for _, trigger := range a {
+		aMap[trigger] = true
 	}
 
-	for _, pattern := range b {
-		if !aMap[pattern] {
+	for _, trigger := range b {
+		if !aMap[trigger] {
 			return false
 		}
 	}
@@ -98,24 +104,38 @@ func patternsEqual(a, b []string) bool {
 	return true
 }
 
-// Global cache instance
+// Cache instances of the modules, by name
 var (
-	globalCache *Cache
-	once        sync.Once
+	caches   = make(map[string]*Cache)
+	cachesMu sync.Mutex
+	once     sync.Once
 )
 
-// GetGlobalCache returns the singleton keyword matcher cache
-func GetGlobalCache() *Cache {
+// GetCache returns the keyword matcher cache of the named module.
+// Each module gets its own cache, since matchers are stored by chat only
+// and the modules match different triggers in the same chats.
+func GetCache(name string) *Cache {
 	once.Do(func() {
-		globalCache = NewCache(30 * time.Minute) // 30 minute TTL
 		// Start cleanup routine
 		go func() {
 			ticker := time.NewTicker(10 * time.Minute)
 			defer ticker.Stop()
 			for range ticker.C {
-				globalCache.CleanupExpired()
+				cachesMu.Lock()
+				for _, cache := range caches {
+					cache.CleanupExpired()
+				}
+				cachesMu.Unlock()
 			}
 		}()
 	})
-	return globalCache
+
+	cachesMu.Lock()
+	defer cachesMu.Unlock()
+	cache, exists := caches[name]
+	if !exists {
+		cache = NewCache(30 * time.Minute) // 30 minute TTL
+		caches[name] = cache
+	}
+	return cache
 }
@@ -1,154 +1,272 @@
 package keyword_matcher
 
 import (
-	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/cloudflare/ahocorasick"
 	log "github.com/sirupsen/logrus"
 )
 
-// KeywordMatcher provides efficient multi-pattern matching using Aho-Corasick algorithm
+// KeywordMatcher provides efficient multi-pattern matching of triggers.
+// It compiles a hybrid automaton: an Aho-Corasick matcher over a literal taken from
+// each trigger prefilters the text, and only the triggers whose literal was found
+// are verified according to their match mode.
+// Triggers and texts are both normalized at the level of the matcher first,
+// except for regexes, which are matched against the text at regexLevel.
 type KeywordMatcher struct {
-	matcher   *ahocorasick.Matcher
-	patterns  []string
-	mu        sync.RWMutex
-	lastBuild time.Time
+	matcher    *ahocorasick.Matcher
+	triggers   []Trigger
+	level      string
+	compiled   []compiledTrigger
+	literals   [][]int // trigger indexes of each literal in the Aho-Corasick matcher
+	unfiltered []int   // triggers without a literal, verified on every text
+	mu         sync.RWMutex
+	lastBuild  time.Time
+}
+
+// compiledTrigger holds what is needed to verify a trigger on a normalized text.
+type compiledTrigger struct {
+	literal string
+	re      *regexp.Regexp // glob and regex triggers only
+	invalid bool           // never matches, eg a stored regex that no longer compiles
 }
 
 // MatchResult contains information about a matched pattern
 type MatchResult struct {
 	Pattern string // The original pattern that matched
-	Start   int    // Start position of match in text
-	End     int    // End position of match in text
+	Mode    string // The match mode of the pattern
+	Start   int    // Start position of match in the original text
+	End     int    // End position of match in the original text
 }
 
-// NewKeywordMatcher creates a new keyword matcher with the given patterns
+// NewKeywordMatcher creates a new keyword matcher with the given patterns,
+// which match anywhere in the text like substring triggers.
 func NewKeywordMatcher(patterns []string) *KeywordMatcher {
+	return NewTriggerMatcher(substringTriggers(patterns), NormalizeOff)
+}
+
+// NewTriggerMatcher creates a new keyword matcher with the given triggers and normalization level
+func NewTriggerMatcher(triggers []Trigger, level string) *KeywordMatcher {
 	km := &KeywordMatcher{
-		patterns: make([]string, len(patterns)),
+		triggers: make([]Trigger, len(triggers)),
+		level:    level,
 	}
-	copy(km.patterns, patterns)
+	copy(km.triggers, triggers)
 	km.build()
 	return km
 }
 
-// build compiles the patterns into an Aho-Corasick matcher
+// substringTriggers wraps plain patterns into substring triggers.
+func substringTriggers(patterns []string) []Trigger {
+	triggers := make([]Trigger, len(patterns))
+	for i, pattern := range patterns {
+		triggers[i] = Trigger{Pattern: pattern, Mode: ModeSubstring}
+	}
+	return triggers
+}
+
+// build compiles the triggers into the prefilter and their verifiers
 func (km *KeywordMatcher) build() {
-	if len(km.patterns) == 0 {
-		km.matcher = nil
+	km.matcher = nil
+	km.compiled = make([]compiledTrigger, len(km.triggers))
+	km.literals = nil
+	km.unfiltered = nil
+	if len(km.triggers) == 0 {
 		return
 	}
 
-	// Convert patterns to lowercase for case-insensitive matching
-	lowerPatterns := make([]string, len(km.patterns))
-	for i, pattern := range km.patterns {
-		lowerPatterns[i] = strings.ToLower(pattern)
+	start := time.Now()
+	literalIndex := make(map[string]int)
+	var literals []string
+
+	for i, original := range km.triggers {
+		trigger := normalizeTrigger(original, km.level)
+		compiled := compiledTrigger{literal: trigger.literal()}
+
+		var err error
+		switch trigger.Mode {
+		case ModeGlob:
+			compiled.re, err = regexp.Compile(globToRegex(strings.ToLower(trigger.Pattern)))
+		case ModeRegex:
+			compiled.re, err = compileRegex(trigger.Pattern)
+		}
+		if err != nil {
+			log.WithFields(log.Fields{
+				"trigger": original.String(),
+				"error":   err,
+			}).Warn("Skipping trigger that failed to compile")
+			compiled.invalid = true
+			km.compiled[i] = compiled
+			continue
+		}
+		km.compiled[i] = compiled
+
+		// a trigger made only of characters dropped by normalization can't match anything,
+		// and sticker triggers don't match text
+		if trigger.Pattern == "" || trigger.IsSticker() {
+			compiled.invalid = true
+			km.compiled[i] = compiled
+			continue
+		}
+		// the literal of a regex isn't normalized, so it can't prefilter a differently normalized text
+		if compiled.literal == "" || (trigger.Mode == ModeRegex && regexLevel(km.level) != km.level) {
+			km.unfiltered = append(km.unfiltered, i)
+			continue
+		}
+		idx, exists := literalIndex[compiled.literal]
+		if !exists {
+			idx = len(literals)
+			literalIndex[compiled.literal] = idx
+			literals = append(literals, compiled.literal)
+			km.literals = append(km.literals, nil)
+		}
+		km.literals[idx] = append(km.literals[idx], i)
 	}
 
-	km.matcher = ahocorasick.NewStringMatcher(lowerPatterns)
+	if len(literals) > 0 {
+		km.matcher = ahocorasick.NewStringMatcher(literals)
+	}
 	km.lastBuild = time.Now()
 
 	log.WithFields(log.Fields{
-		"patterns_count": len(km.patterns),
-		"build_time":     time.Since(km.lastBuild),
-	}).Debug("Built Aho-Corasick matcher")
+		"patterns_count": len(km.triggers),
+		"literals_count": len(literals),
+		"build_time":     km.lastBuild.Sub(start),
+	}).Debug("Built keyword matcher")
 }
 
-// FindMatches returns all matches in the given text
+// candidates returns the indexes of the triggers that may match the normalized text, in trigger order
+func (km *KeywordMatcher) candidates(lowerText string) []int {
+	candidates := append([]int(nil), km.unfiltered...)
+	if km.matcher != nil {
+		for _, hit := range km.matcher.Match([]byte(lowerText)) {
+			if hit < len(km.literals) {
+				candidates = append(candidates, km.literals[hit]...)
+			}
+		}
+	}
+	sort.Ints(candidates)
+	return candidates
+}
+
+// FindMatches returns all matches in the given text, ordered by their position
 func (km *KeywordMatcher) FindMatches(text string) []MatchResult {
 	km.mu.RLock()
 	defer km.mu.RUnlock()
 
-	if km.matcher == nil {
+	if len(text) == 0 || len(km.triggers) == 0 {
 		return nil
 	}
 
-	lowerText := strings.ToLower(text)
-	matches := km.findMatchesWithPositions([]byte(lowerText))
-
-	if len(matches) == 0 {
-		return nil
+	normalized := Normalize(text, km.level)
+	regexNormalized := normalized
+	if level := regexLevel(km.level); level != km.level {
+		regexNormalized = Normalize(text, level)
 	}
-
-	results := make([]MatchResult, 0, len(matches))
-	for _, match := range matches {
-		if match.PatternIndex < len(km.patterns) {
-			pattern := km.patterns[match.PatternIndex]
+	var results []MatchResult
+	for _, i := range km.candidates(normalized.Text) {
+		n := normalized
+		if km.triggers[i].Mode == ModeRegex {
+			n = regexNormalized
+		}
+		for _, loc := range km.verify(i, n.Text, -1) {
+			start, end := n.Original(loc[0], loc[1])
 			results = append(results, MatchResult{
-				Pattern: pattern,
-				Start:   match.Start,
-				End:     match.End,
+				Pattern: km.triggers[i].Pattern,
+				Mode:    km.triggers[i].Mode,
+				Start:   start,
+				End:     end,
 			})
 		}
 	}
 
+	sort.SliceStable(results, func(a, b int) bool {
+		return results[a].Start < results[b].Start
+	})
 	return results
 }
 
-// matchInfo holds information about a match including position
-type matchInfo struct {
-	PatternIndex int
-	Start        int
-	End          int
-}
-
-// findMatchesWithPositions finds all matches with their positions in the text
-// This implementation uses the Aho-Corasick matcher for efficient multi-pattern matching
-func (km *KeywordMatcher) findMatchesWithPositions(text []byte) []matchInfo {
-	if len(text) == 0 || len(km.patterns) == 0 || km.matcher == nil {
+// verify returns the positions where trigger i matches the normalized text,
+// stopping after n matches unless n is negative.
+func (km *KeywordMatcher) verify(i int, lowerText string, n int) [][2]int {
+	compiled := km.compiled[i]
+	if compiled.invalid {
 		return nil
 	}
 
-	// Use the Aho-Corasick matcher to find all matches
-	hits := km.matcher.Match(text)
-	if len(hits) == 0 {
+	switch km.triggers[i].Mode {
+	case ModeGlob:
+		var locs [][2]int
+		for _, m := range compiled.re.FindAllStringSubmatchIndex(lowerText, n) {
+			locs = append(locs, [2]int{m[2], m[3]})
+		}
+		return locs
+	case ModeRegex:
+		var locs [][2]int
+		for _, m := range compiled.re.FindAllStringIndex(lowerText, n) {
+			locs = append(locs, [2]int{m[0], m[1]})
+		}
+		return locs
+	case ModeExact, ModePrefix:
+		// leading and trailing spaces of the message are ignored
+		start := len(lowerText) - len(strings.TrimLeftFunc(lowerText, unicode.IsSpace))
+		end := len(strings.TrimRightFunc(lowerText, unicode.IsSpace))
+		switch {
+		case km.triggers[i].Mode == ModePrefix && strings.HasPrefix(lowerText[start:], compiled.literal):
+			return [][2]int{{start, start + len(compiled.literal)}}
+		case km.triggers[i].Mode == ModeExact && start < end && lowerText[start:end] == compiled.literal:
+			return [][2]int{{start, end}}
+		}
 		return nil
+	default:
+		return findLiteral(lowerText, compiled.literal, km.triggers[i].Mode == ModeWord, n)
 	}
+}
 
-	var allMatches []matchInfo
-	seen := make(map[string]bool)
-
-	// Convert hits to matchInfo
-	for _, hit := range hits {
-		// hit contains the pattern index and end position
-		patternIdx := hit
-		if patternIdx >= len(km.patterns) {
-			continue
+// findLiteral returns the positions of literal in text, only counting whole words
+// if wholeWord is set, and stopping after n matches unless n is negative.
+func findLiteral(text, literal string, wholeWord bool, n int) [][2]int {
+	var locs [][2]int
+	searchStart := 0
+	for n < 0 || len(locs) < n {
+		pos := strings.Index(text[searchStart:], literal)
+		if pos == -1 {
+			break
 		}
-
-		pattern := strings.ToLower(km.patterns[patternIdx])
-		patternLen := len(pattern)
-
-		// Find all occurrences of this pattern in the text
-		textStr := string(text)
-		lowerTextStr := strings.ToLower(textStr)
-		searchStart := 0
-
-		for {
-			pos := strings.Index(lowerTextStr[searchStart:], pattern)
-			if pos == -1 {
-				break
-			}
-
-			actualPos := searchStart + pos
-			key := fmt.Sprintf("%d:%d", patternIdx, actualPos)
-			if !seen[key] {
-				seen[key] = true
-				allMatches = append(allMatches, matchInfo{
-					PatternIndex: patternIdx,
-					Start:        actualPos,
-					End:          actualPos + patternLen,
-				})
-			}
-
-			searchStart = actualPos + 1
+		start := searchStart + pos
+		end := start + len(literal)
+		if !wholeWord || atWordBoundaries(text, literal, start, end) {
+			locs = append(locs, [2]int{start, end})
 		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		searchStart = start + size
 	}
+	return locs
+}
 
-	return allMatches
+// atWordBoundaries reports whether the literal found at text[start:end] isn't
+// part of a longer word. Edges of the literal that aren't word characters,
+// like the $ of "$$$", need no boundary.
+func atWordBoundaries(text, literal string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(literal)
+	if isWordRune(first) && start > 0 {
+		if before, _ := utf8.DecodeLastRuneInString(text[:start]); isWordRune(before) {
+			return false
+		}
+	}
+	last, _ := utf8.DecodeLastRuneInString(literal)
+	if isWordRune(last) && end < len(text) {
+		if after, _ := utf8.DecodeRuneInString(text[end:]); isWordRune(after) {
+			return false
+		}
+	}
+	return true
 }
 
 // HasMatch returns true if any pattern matches the text
@@ -156,13 +274,25 @@ func (km *KeywordMatcher) HasMatch(text string) bool {
 	km.mu.RLock()
 	defer km.mu.RUnlock()
 
-	if km.matcher == nil {
+	if len(text) == 0 || len(km.triggers) == 0 {
 		return false
 	}
 
-	lowerText := strings.ToLower(text)
-	hits := km.matcher.Match([]byte(lowerText))
-	return len(hits) > 0
+	normalized := Normalize(text, km.level).Text
+	regexNormalized := normalized
+	if level := regexLevel(km.level); level != km.level {
+		regexNormalized = Normalize(text, level).Text
+	}
+	for _, i := range km.candidates(normalized) {
+		candidateText := normalized
+		if km.triggers[i].Mode == ModeRegex {
+			candidateText = regexNormalized
+		}
+		if len(km.verify(i, candidateText, 1)) > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 // GetPatterns returns a copy of the current patterns
@@ -170,7 +300,24 @@ func (km *KeywordMatcher) GetPatterns() []string {
 	km.mu.RLock()
 	defer km.mu.RUnlock()
 
-	patterns := make([]string, len(km.patterns))
-	copy(patterns, km.patterns)
+	patterns := make([]string, len(km.triggers))
+	for i, trigger := range km.triggers {
+		patterns[i] = trigger.Pattern
+	}
 	return patterns
 }
+
+// GetLevel returns the normalization level of the matcher
+func (km *KeywordMatcher) GetLevel() string {
+	return km.level
+}
+
+// GetTriggers returns a copy of the current triggers
+func (km *KeywordMatcher) GetTriggers() []Trigger {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	triggers := make([]Trigger, len(km.triggers))
+	copy(triggers, km.triggers)
+	return triggers
+}
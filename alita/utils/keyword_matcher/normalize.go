@@ -0,0 +1,207 @@
+package keyword_matcher
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalization levels applied to both triggers and texts before matching
+const (
+	NormalizeOff    = "off"    // lowercasing only
+	NormalizeBasic  = "basic"  // NFKC, zero-width and diacritic stripping, homoglyph folding
+	NormalizeStrict = "strict" // basic plus leetspeak mapping and separator collapsing
+)
+
+// confusables folds letters from other scripts that look like latin ones, after lowercasing.
+var confusables = map[rune]rune{
+	// cyrillic
+	'а': 'a', 'в': 'b', 'е': 'e', 'ё': 'e', 'һ': 'h', 'н': 'h', 'і': 'i', 'ї': 'i', 'ј': 'j',
+	'к': 'k', 'ӏ': 'l', 'м': 'm', 'о': 'o', 'р': 'p', 'ԛ': 'q', 'ѕ': 's', 'т': 't', 'с': 'c',
+	'у': 'y', 'ԝ': 'w', 'х': 'x', 'ԁ': 'd', 'ɡ': 'g',
+	// greek
+	'α': 'a', 'β': 'b', 'ε': 'e', 'η': 'n', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x', 'ω': 'w',
+	// latin lookalikes
+	'ı': 'i', 'ℓ': 'l', 'ø': 'o', 'ł': 'l', 'đ': 'd', 'ħ': 'h',
+}
+
+// leetspeak maps digits and symbols standing in for letters. They are only mapped
+// within words that also contain letters, so plain numbers are left alone.
+var leetspeak = map[rune]rune{
+	'0': 'o', '1': 'i', '3': 'e', '4': 'a', '5': 's', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '€': 'e', '¥': 'y', '£': 'l',
+}
+
+// separators are dropped in strict mode when they split up a word, as in "f.r.e.e".
+const separators = ".,-_*'`~|/\\:;^=+"
+
+// Normalized is a text after normalization, along with the range of the original
+// text each of its bytes comes from.
+type Normalized struct {
+	Text   string
+	starts []int
+	ends   []int
+	length int // length of the original text
+}
+
+// normRune is a rune of a normalized text with the byte range of the original text it comes from.
+type normRune struct {
+	r          rune
+	start, end int
+}
+
+// Normalize prepares a text for matching at the given level. Unknown levels are treated as off.
+func Normalize(text, level string) Normalized {
+	runes := make([]normRune, 0, len(text))
+	for start, r := range text {
+		end := start + utf8.RuneLen(r)
+		if r == utf8.RuneError {
+			_, size := utf8.DecodeRuneInString(text[start:])
+			end = start + size
+		}
+		if level != NormalizeBasic && level != NormalizeStrict {
+			for _, lr := range strings.ToLower(string(r)) {
+				runes = append(runes, normRune{lr, start, end})
+			}
+			continue
+		}
+		for _, fr := range foldRune(r) {
+			runes = append(runes, normRune{fr, start, end})
+		}
+	}
+
+	if level == NormalizeStrict {
+		runes = collapseSeparators(mapLeetspeak(runes))
+	}
+
+	n := Normalized{length: len(text)}
+	var sb strings.Builder
+	sb.Grow(len(text))
+	for _, nr := range runes {
+		size, _ := sb.WriteRune(nr.r)
+		for range size {
+			n.starts = append(n.starts, nr.start)
+			n.ends = append(n.ends, nr.end)
+		}
+	}
+	n.Text = sb.String()
+	return n
+}
+
+// foldRune applies the basic normalization to a single rune: zero-width and other
+// format characters are dropped, compatibility characters are decomposed with NFKC,
+// diacritics are stripped, and the result is lowercased with homoglyphs folded.
+func foldRune(r rune) []rune {
+	if unicode.Is(unicode.Cf, r) {
+		return nil
+	}
+
+	decomposed := string(r)
+	if r >= utf8.RuneSelf {
+		decomposed = norm.NFD.String(norm.NFKC.String(decomposed))
+	}
+
+	var folded []rune
+	for _, dr := range decomposed {
+		if unicode.Is(unicode.Mn, dr) || unicode.Is(unicode.Cf, dr) {
+			continue
+		}
+		for _, lr := range strings.ToLower(string(dr)) {
+			if c, ok := confusables[lr]; ok {
+				lr = c
+			}
+			folded = append(folded, lr)
+		}
+	}
+	return folded
+}
+
+// mapLeetspeak maps leetspeak characters to letters within the words that contain letters.
+func mapLeetspeak(runes []normRune) []normRune {
+	for wordStart := 0; wordStart < len(runes); {
+		wordEnd := wordStart
+		hasLetter := false
+		for wordEnd < len(runes) && !unicode.IsSpace(runes[wordEnd].r) {
+			hasLetter = hasLetter || unicode.IsLetter(runes[wordEnd].r)
+			wordEnd++
+		}
+		if hasLetter {
+			for i := wordStart; i < wordEnd; i++ {
+				if l, ok := leetspeak[runes[i].r]; ok {
+					runes[i].r = l
+				}
+			}
+		}
+		wordStart = wordEnd + 1
+	}
+	return runes
+}
+
+// collapseSeparators drops runs of separators that have letters on both sides.
+func collapseSeparators(runes []normRune) []normRune {
+	collapsed := runes[:0:0]
+	for i := 0; i < len(runes); i++ {
+		if !strings.ContainsRune(separators, runes[i].r) {
+			collapsed = append(collapsed, runes[i])
+			continue
+		}
+		runEnd := i
+		for runEnd < len(runes) && strings.ContainsRune(separators, runes[runEnd].r) {
+			runEnd++
+		}
+		betweenLetters := len(collapsed) > 0 && unicode.IsLetter(collapsed[len(collapsed)-1].r) &&
+			runEnd < len(runes) && unicode.IsLetter(runes[runEnd].r)
+		if !betweenLetters {
+			collapsed = append(collapsed, runes[i:runEnd]...)
+		}
+		i = runEnd - 1
+	}
+	return collapsed
+}
+
+// Original maps the byte range [start, end) of the normalized text back to the original text.
+func (n Normalized) Original(start, end int) (int, int) {
+	if start >= len(n.starts) {
+		return n.length, n.length
+	}
+	if end <= start {
+		return n.starts[start], n.starts[start]
+	}
+	return n.starts[start], n.ends[end-1]
+}
+
+// regexLevel returns the level texts are normalized at for regex triggers, which are kept as written.
+// Strict normalization rewrites digits, symbols and separators a regex may be written for,
+// so regexes are matched against the text at the basic level instead.
+func regexLevel(level string) string {
+	if level == NormalizeStrict {
+		return NormalizeBasic
+	}
+	return level
+}
+
+// normalizeTrigger normalizes the pattern of a trigger the same way as the texts it is matched against.
+// Wildcards of globs are kept, and regexes and sticker triggers are left as written.
+func normalizeTrigger(t Trigger, level string) Trigger {
+	switch t.Mode {
+	case ModeRegex, ModeSticker, ModeStickerSet:
+		return t
+	case ModeGlob:
+		var sb strings.Builder
+		segmentStart := 0
+		for i, r := range t.Pattern {
+			if r == '*' || r == '?' {
+				sb.WriteString(Normalize(t.Pattern[segmentStart:i], level).Text)
+				sb.WriteRune(r)
+				segmentStart = i + 1
+			}
+		}
+		sb.WriteString(Normalize(t.Pattern[segmentStart:], level).Text)
+		return Trigger{Pattern: sb.String(), Mode: t.Mode}
+	default:
+		return Trigger{Pattern: Normalize(t.Pattern, level).Text, Mode: t.Mode}
+	}
+}
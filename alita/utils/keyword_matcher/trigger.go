@@ -0,0 +1,265 @@
+package keyword_matcher
+
+import (
+	"errors"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Match modes of a trigger
+const (
+	ModeWord      = "word"      // whole words only, the default for plain triggers
+	ModeSubstring = "substring" // anywhere in the text, even inside other words
+	ModeGlob      = "glob"      // whole words with * and ? wildcards
+	ModeRegex     = "regex"     // RE2 regular expression
+
+	// Filter triggers can also match the whole message or its start
+	ModeExact  = "exact"  // the whole message, ignoring surrounding spaces
+	ModePrefix = "prefix" // the start of the message, ignoring leading spaces
+
+	// Sticker triggers never match text, callers compare them with the stickers they receive
+	ModeSticker    = "sticker"     // a single sticker, by its file_unique_id
+	ModeStickerSet = "sticker_set" // every sticker of a pack, by its set name
+)
+
+// Prefixes selecting a match mode when parsing a trigger
+const (
+	RegexPrefix      = "regex:"
+	SubstringPrefix  = "substring:"
+	StickerPrefix    = "sticker:"
+	StickerSetPrefix = "stickerset:"
+	ExactPrefix      = "exact:"
+	StartsWithPrefix = "startswith:"
+)
+
+// Limits guarding against regex triggers that are too costly to compile or run
+const (
+	maxRegexLength       = 512
+	maxRegexInstructions = 2000
+	regexCompileTimeout  = 100 * time.Millisecond
+)
+
+var (
+	// ErrEmptyTrigger is returned when a trigger has nothing to match.
+	ErrEmptyTrigger = errors.New("trigger is empty")
+	// ErrRegexTooComplex is returned when a regex trigger is too long or compiles to too many instructions.
+	ErrRegexTooComplex = errors.New("regex is too complex")
+	// ErrRegexTimeout is returned when a regex trigger takes too long to compile.
+	ErrRegexTimeout = errors.New("regex took too long to compile")
+)
+
+// Trigger is a pattern along with the way it is matched against text.
+type Trigger struct {
+	Pattern string
+	Mode    string
+}
+
+// ParseTrigger reads a trigger as typed by a user: regex:, substring:, sticker: and
+// stickerset: prefixes select those modes, patterns containing * or ? are globs, and
+// anything else matches whole words. Patterns other than regexes and sticker ids are
+// lowercased, since all matching is case-insensitive.
+func ParseTrigger(text string) (Trigger, error) {
+	text = strings.TrimSpace(text)
+	lowerText := strings.ToLower(text)
+
+	var trigger Trigger
+	switch {
+	case strings.HasPrefix(lowerText, RegexPrefix):
+		trigger = Trigger{Pattern: strings.TrimSpace(text[len(RegexPrefix):]), Mode: ModeRegex}
+	case strings.HasPrefix(lowerText, StickerPrefix):
+		trigger = Trigger{Pattern: strings.TrimSpace(text[len(StickerPrefix):]), Mode: ModeSticker}
+	case strings.HasPrefix(lowerText, StickerSetPrefix):
+		trigger = Trigger{Pattern: strings.TrimSpace(lowerText[len(StickerSetPrefix):]), Mode: ModeStickerSet}
+	case strings.HasPrefix(lowerText, SubstringPrefix):
+		trigger = Trigger{Pattern: strings.TrimSpace(lowerText[len(SubstringPrefix):]), Mode: ModeSubstring}
+	case strings.ContainsAny(lowerText, "*?"):
+		trigger = Trigger{Pattern: lowerText, Mode: ModeGlob}
+	default:
+		trigger = Trigger{Pattern: lowerText, Mode: ModeWord}
+	}
+
+	if trigger.Pattern == "" || (trigger.Mode == ModeGlob && strings.Trim(trigger.Pattern, "*?") == "") {
+		return Trigger{}, ErrEmptyTrigger
+	}
+	if trigger.Mode == ModeRegex {
+		if _, err := compileRegex(trigger.Pattern); err != nil {
+			return Trigger{}, err
+		}
+	}
+	return trigger, nil
+}
+
+// ParseFilterTrigger reads a filter trigger as typed by a user: regex:, exact: and startswith:
+// prefixes select those modes, and anything else matches anywhere in the message.
+// Patterns other than regexes are lowercased, since all matching is case-insensitive.
+func ParseFilterTrigger(text string) (Trigger, error) {
+	text = strings.TrimSpace(text)
+	lowerText := strings.ToLower(text)
+
+	var trigger Trigger
+	switch {
+	case strings.HasPrefix(lowerText, RegexPrefix):
+		trigger = Trigger{Pattern: strings.TrimSpace(text[len(RegexPrefix):]), Mode: ModeRegex}
+	case strings.HasPrefix(lowerText, ExactPrefix):
+		trigger = Trigger{Pattern: strings.TrimSpace(lowerText[len(ExactPrefix):]), Mode: ModeExact}
+	case strings.HasPrefix(lowerText, StartsWithPrefix):
+		trigger = Trigger{Pattern: strings.TrimSpace(lowerText[len(StartsWithPrefix):]), Mode: ModePrefix}
+	default:
+		trigger = Trigger{Pattern: lowerText, Mode: ModeSubstring}
+	}
+
+	if trigger.Pattern == "" {
+		return Trigger{}, ErrEmptyTrigger
+	}
+	if trigger.Mode == ModeRegex {
+		if _, err := compileRegex(trigger.Pattern); err != nil {
+			return Trigger{}, err
+		}
+	}
+	return trigger, nil
+}
+
+// FilterString returns the trigger the way ParseFilterTrigger reads it.
+func (t Trigger) FilterString() string {
+	if t.Mode == ModeSubstring {
+		return t.Pattern
+	}
+	return t.String()
+}
+
+// String returns the trigger the way ParseTrigger reads it.
+func (t Trigger) String() string {
+	switch t.Mode {
+	case ModeRegex:
+		return RegexPrefix + t.Pattern
+	case ModeSubstring:
+		return SubstringPrefix + t.Pattern
+	case ModeExact:
+		return ExactPrefix + t.Pattern
+	case ModePrefix:
+		return StartsWithPrefix + t.Pattern
+	case ModeSticker:
+		return StickerPrefix + t.Pattern
+	case ModeStickerSet:
+		return StickerSetPrefix + t.Pattern
+	default:
+		return t.Pattern
+	}
+}
+
+// IsSticker reports whether the trigger matches stickers rather than text.
+func (t Trigger) IsSticker() bool {
+	return t.Mode == ModeSticker || t.Mode == ModeStickerSet
+}
+
+// compileRegex compiles a case-insensitive regex trigger, refusing patterns that
+// exceed the complexity limits or take longer than regexCompileTimeout to compile.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > maxRegexLength {
+		return nil, ErrRegexTooComplex
+	}
+
+	parsed, err := syntax.Parse(pattern, syntax.Perl|syntax.FoldCase)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Inst) > maxRegexInstructions {
+		return nil, ErrRegexTooComplex
+	}
+
+	type result struct {
+		re  *regexp.Regexp
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		re, err := regexp.Compile("(?i)" + pattern)
+		done <- result{re, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.re, r.err
+	case <-time.After(regexCompileTimeout):
+		return nil, ErrRegexTimeout
+	}
+}
+
+// globToRegex converts a glob trigger into a regex matching it as whole words,
+// with * standing for any run of non-space characters and ? for a single one.
+// The trigger itself is captured by the first group.
+func globToRegex(pattern string) string {
+	var sb strings.Builder
+	sb.WriteString(`(?:^|[^\p{L}\p{N}_])(`)
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(`\S*`)
+		case '?':
+			sb.WriteString(`\S`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString(`)(?:[^\p{L}\p{N}_]|$)`)
+	return sb.String()
+}
+
+// literal returns the longest lowercase text every match of the trigger contains,
+// used to prefilter texts before verifying the trigger on them.
+// An empty literal means the trigger has to be verified on every text.
+func (t Trigger) literal() string {
+	switch t.Mode {
+	case ModeGlob:
+		var longest string
+		for _, part := range strings.FieldsFunc(strings.ToLower(t.Pattern), func(r rune) bool { return r == '*' || r == '?' }) {
+			if len(part) > len(longest) {
+				longest = part
+			}
+		}
+		return longest
+	case ModeRegex:
+		parsed, err := syntax.Parse(t.Pattern, syntax.Perl|syntax.FoldCase)
+		if err != nil {
+			return ""
+		}
+		return requiredLiteral(parsed.Simplify())
+	default:
+		return strings.ToLower(t.Pattern)
+	}
+}
+
+// requiredLiteral returns the longest lowercase literal that any text matching re must contain.
+func requiredLiteral(re *syntax.Regexp) string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		return strings.ToLower(string(re.Rune))
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiteral(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min >= 1 {
+			return requiredLiteral(re.Sub[0])
+		}
+	case syntax.OpConcat:
+		var longest string
+		for _, sub := range re.Sub {
+			if lit := requiredLiteral(sub); len(lit) > len(longest) {
+				longest = lit
+			}
+		}
+		return longest
+	}
+	return ""
+}
+
+// isWordRune reports whether r is part of a word for whole-word matching.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,122 @@
+package ratestate
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// memoryCleanupInterval is how often expired entries are dropped from a Memory backend.
+const memoryCleanupInterval = 5 * time.Minute
+
+// memoryEntry is the state of a single key of a Memory backend.
+type memoryEntry struct {
+	count      int64
+	timestamps []int64 // Unix milliseconds, oldest first
+	ids        []int64
+	expiresAt  time.Time
+}
+
+// Memory keeps the rate state in the memory of the current process.
+// It can't be shared between instances of the bot.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+// NewMemory creates an in-memory backend and starts dropping its expired entries in the background.
+func NewMemory() *Memory {
+	m := &Memory{entries: map[string]*memoryEntry{}}
+	go m.cleanupLoop()
+	return m
+}
+
+// cleanupLoop periodically removes the entries whose window or idle time has passed.
+func (m *Memory) cleanupLoop() {
+	ticker := time.NewTicker(memoryCleanupInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.mu.Lock()
+		for key, entry := range m.entries {
+			if now.After(entry.expiresAt) {
+				delete(m.entries, key)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// entry returns the live entry of key, replacing it with an empty one if it has expired.
+// Must be called with mu held.
+func (m *Memory) entry(key string, now time.Time) *memoryEntry {
+	entry, ok := m.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+	}
+	return entry
+}
+
+// Incr adds one to the fixed window counter of key.
+func (m *Memory) Incr(key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entry(key, now)
+	if entry.count == 0 {
+		entry.expiresAt = now.Add(window)
+	}
+	entry.count++
+	return entry.count, nil
+}
+
+// Push records a message id under key, returning all ids once more than limit are recorded.
+func (m *Memory) Push(key string, id int64, limit int, idle time.Duration) ([]int64, error) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entry(key, now)
+	entry.ids = append(entry.ids, id)
+	entry.expiresAt = now.Add(idle)
+	if len(entry.ids) > limit {
+		delete(m.entries, key)
+		return entry.ids, nil
+	}
+	return nil, nil
+}
+
+// Slide records a message id in the sliding window of key, returning all ids in the
+// window once more than limit fall within it.
+func (m *Memory) Slide(key string, id int64, limit int, window time.Duration) ([]int64, error) {
+	now := time.Now()
+	windowStart := now.Add(-window).UnixMilli()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.entry(key, now)
+	// drop the messages that slid out of the window
+	start := 0
+	for start < len(entry.timestamps) && entry.timestamps[start] < windowStart {
+		start++
+	}
+	entry.timestamps = append(slices.Clone(entry.timestamps[start:]), now.UnixMilli())
+	entry.ids = append(slices.Clone(entry.ids[start:]), id)
+	entry.expiresAt = now.Add(window)
+
+	if len(entry.ids) > limit {
+		delete(m.entries, key)
+		return entry.ids, nil
+	}
+	return nil, nil
+}
+
+// Reset clears the state of key.
+func (m *Memory) Reset(key string) error {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+	return nil
+}
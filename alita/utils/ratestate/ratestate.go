@@ -0,0 +1,87 @@
+// Package ratestate keeps the counters behind the antiflood and antispam checks,
+// either in memory or in Redis so that several instances of the bot share them.
+package ratestate
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/utils/cache"
+)
+
+// Names of the backends, as used by the RATE_STATE_BACKEND setting
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// Backend stores the state of the rate checks.
+// Implementations must be safe for concurrent use.
+type Backend interface {
+	// Incr adds one to the fixed window counter of key and returns the count within
+	// the window, opening a window of the given length if none is running.
+	Incr(key string, window time.Duration) (int64, error)
+
+	// Push records a message id under key, forgetting all of them once idle passes
+	// without a new one. When more than limit ids have been recorded, they are all
+	// returned and key is cleared; otherwise nil is returned.
+	Push(key string, id int64, limit int, idle time.Duration) ([]int64, error)
+
+	// Slide records a message id in the sliding window of key. When more than limit
+	// ids fall within the window, they are all returned and key is cleared; otherwise
+	// nil is returned.
+	Slide(key string, id int64, limit int, window time.Duration) ([]int64, error)
+
+	// Reset clears the state of key.
+	Reset(key string) error
+}
+
+var (
+	backend   Backend
+	backendMu sync.RWMutex
+)
+
+// Init selects the backend used by Get.
+// The Redis backend needs the cache to be initialized first.
+func Init(name string) error {
+	var selected Backend
+	switch name {
+	case "", BackendMemory:
+		selected = NewMemory()
+	case BackendRedis:
+		client := cache.RedisClient()
+		if client == nil {
+			return fmt.Errorf("redis client not initialized")
+		}
+		selected = NewRedis(client)
+	default:
+		return fmt.Errorf("unknown rate state backend %q", name)
+	}
+
+	backendMu.Lock()
+	backend = selected
+	backendMu.Unlock()
+
+	log.Infof("[RateState] Using %s backend", name)
+	return nil
+}
+
+// Get returns the selected backend, falling back to an in-memory one if Init wasn't called.
+func Get() Backend {
+	backendMu.RLock()
+	current := backend
+	backendMu.RUnlock()
+	if current != nil {
+		return current
+	}
+
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	if backend == nil {
+		backend = NewMemory()
+	}
+	return backend
+}
@@ -0,0 +1,108 @@
+package ratestate
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/divideprojects/Alita_Robot/alita/utils/cache"
+)
+
+// redisKeyPrefix namespaces the rate state keys in Redis.
+const redisKeyPrefix = "alita:rate:"
+
+// incrScript bumps a fixed window counter, starting its expiry with the first hit.
+var incrScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count`)
+
+// pushScript appends a message id to a list that expires once idle, and hands the
+// whole list back while clearing it when it grows past the limit.
+var pushScript = redis.NewScript(`
+local count = redis.call("RPUSH", KEYS[1], ARGV[1])
+if count > tonumber(ARGV[2]) then
+	local ids = redis.call("LRANGE", KEYS[1], 0, -1)
+	redis.call("DEL", KEYS[1])
+	return ids
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return {}`)
+
+// slideScript keeps a sliding window of message ids in a sorted set scored by their
+// time in milliseconds, and hands the window back while clearing it when it holds
+// more ids than the limit.
+var slideScript = redis.NewScript(`
+redis.call("ZREMRANGEBYSCORE", KEYS[1], "-inf", "(" .. ARGV[2])
+redis.call("ZADD", KEYS[1], ARGV[1], ARGV[3])
+if redis.call("ZCARD", KEYS[1]) > tonumber(ARGV[4]) then
+	local ids = redis.call("ZRANGE", KEYS[1], 0, -1)
+	redis.call("DEL", KEYS[1])
+	return ids
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[5])
+return {}`)
+
+// Redis keeps the rate state in Redis, so every instance of the bot enforces the same limits.
+// Each operation runs as a single Lua script, which makes it atomic across instances.
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis creates a backend storing the rate state with the given client.
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+// Incr adds one to the fixed window counter of key.
+func (r *Redis) Incr(key string, window time.Duration) (int64, error) {
+	return incrScript.Run(cache.Context, r.client, []string{redisKeyPrefix + key}, window.Milliseconds()).Int64()
+}
+
+// Push records a message id under key, returning all ids once more than limit are recorded.
+func (r *Redis) Push(key string, id int64, limit int, idle time.Duration) ([]int64, error) {
+	result, err := pushScript.Run(cache.Context, r.client, []string{redisKeyPrefix + key}, id, limit, idle.Milliseconds()).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	return parseIds(result)
+}
+
+// Slide records a message id in the sliding window of key, returning all ids in the
+// window once more than limit fall within it.
+// Times come from the clock of the calling instance.
+func (r *Redis) Slide(key string, id int64, limit int, window time.Duration) ([]int64, error) {
+	now := time.Now()
+	result, err := slideScript.Run(cache.Context, r.client, []string{redisKeyPrefix + key},
+		now.UnixMilli(), now.Add(-window).UnixMilli(), id, limit, window.Milliseconds(),
+	).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	return parseIds(result)
+}
+
+// Reset clears the state of key.
+func (r *Redis) Reset(key string) error {
+	return r.client.Del(cache.Context, redisKeyPrefix+key).Err()
+}
+
+// parseIds converts the message ids returned by a script, giving nil for an empty result.
+func parseIds(result []string) ([]int64, error) {
+	if len(result) == 0 {
+		return nil, nil
+	}
+	ids := make([]int64, 0, len(result))
+	for _, s := range result {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid message id %q in rate state: %w", s, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,246 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/PaulSonOfLars/gotgbot/v2"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/divideprojects/Alita_Robot/alita/config"
+	"github.com/divideprojects/Alita_Robot/alita/db"
+	"github.com/divideprojects/Alita_Robot/alita/utils/cache"
+)
+
+const (
+	// pollInterval is how often the database is checked for due jobs.
+	pollInterval = 2 * time.Second
+	// jobLease is how long a claimed job is reserved for its runner.
+	// A job still running after its lease may be claimed again by another instance.
+	jobLease = 5 * time.Minute
+	// retryBaseDelay is the delay before the first retry of a failed job, doubled on every attempt.
+	retryBaseDelay = 30 * time.Second
+	// retryMaxDelay caps the delay between retries.
+	retryMaxDelay = time.Hour
+	// cleanupInterval is how often finished jobs are removed.
+	cleanupInterval = time.Hour
+	// finishedJobRetention is how long done and failed jobs are kept for inspection.
+	finishedJobRetention = 24 * time.Hour
+)
+
+// Handler runs a scheduled job. Returning an error retries the job with backoff
+// until it runs out of attempts.
+type Handler func(b *gotgbot.Bot, job *db.ScheduledJob) error
+
+var (
+	handlers   = make(map[string]Handler)
+	handlersMu sync.RWMutex
+)
+
+// RegisterHandler sets the function that runs jobs of the given type.
+// Modules call this from their Load function.
+func RegisterHandler(jobType string, handler Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers[jobType] = handler
+}
+
+// getHandler returns the handler registered for a job type.
+func getHandler(jobType string) (Handler, bool) {
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	handler, ok := handlers[jobType]
+	return handler, ok
+}
+
+// Enqueue schedules a job of the given type to run at runAt with payload encoded as JSON.
+// A non-empty key identifies the job so it can be cancelled, and replaces a pending job with the same type and key.
+func Enqueue(jobType, key string, payload any, runAt time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload of %s job: %w", jobType, err)
+	}
+	return db.EnqueueJob(&db.ScheduledJob{
+		JobType: jobType,
+		JobKey:  key,
+		Payload: string(data),
+		RunAt:   runAt,
+	})
+}
+
+// Cancel removes the pending jobs with the given type and key.
+func Cancel(jobType, key string) error {
+	_, err := db.CancelJobs(jobType, key)
+	return err
+}
+
+// DecodePayload decodes the JSON payload of a job into v.
+func DecodePayload(job *db.ScheduledJob, v any) error {
+	return json.Unmarshal([]byte(job.Payload), v)
+}
+
+// Scheduler claims due jobs from the database and runs them on a pool of workers.
+type Scheduler struct {
+	bot          *gotgbot.Bot
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	jobs         chan *db.ScheduledJob
+	workers      int
+	useRedisLock bool
+}
+
+// NewScheduler creates a scheduler that runs jobs with the given bot.
+func NewScheduler(b *gotgbot.Bot) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		bot:          b,
+		ctx:          ctx,
+		cancel:       cancel,
+		jobs:         make(chan *db.ScheduledJob),
+		workers:      config.SchedulerWorkers,
+		useRedisLock: config.SchedulerRedisLock,
+	}
+}
+
+// Start launches the workers and the polling loop.
+// Jobs that became due while the bot was down are picked up on the first poll.
+func (s *Scheduler) Start() {
+	if overdue, err := db.CountOverdueJobs(); err == nil && overdue > 0 {
+		log.Infof("[Scheduler] Recovering %d overdue jobs", overdue)
+	}
+	log.Infof("[Scheduler] Starting with %d workers (redis lock: %v)", s.workers, s.useRedisLock)
+
+	for range s.workers {
+		s.wg.Add(1)
+		go s.worker()
+	}
+
+	s.wg.Add(1)
+	go s.pollLoop()
+}
+
+// Stop stops claiming new jobs and waits for running jobs to finish.
+func (s *Scheduler) Stop() {
+	log.Info("[Scheduler] Stopping scheduler")
+	s.cancel()
+	s.wg.Wait()
+}
+
+// pollLoop periodically claims due jobs and hands them to the workers.
+func (s *Scheduler) pollLoop() {
+	defer s.wg.Done()
+	defer close(s.jobs)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	lastCleanup := time.Now()
+
+	for {
+		s.dispatchDueJobs()
+
+		if time.Since(lastCleanup) >= cleanupInterval {
+			if count, err := db.CleanupFinishedJobs(finishedJobRetention); err == nil && count > 0 {
+				log.Infof("[Scheduler] Removed %d finished jobs", count)
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatchDueJobs claims as many due jobs as there are workers and queues them.
+// Jobs that could not be handed over before shutdown are released for the next start.
+func (s *Scheduler) dispatchDueJobs() {
+	jobs, err := db.ClaimDueJobs(s.workers, jobLease)
+	if err != nil {
+		return
+	}
+
+	for i, job := range jobs {
+		select {
+		case s.jobs <- job:
+		case <-s.ctx.Done():
+			for _, pending := range jobs[i:] {
+				_ = db.RetryJob(pending.ID, pending.RunAt, pending.LastError)
+			}
+			return
+		}
+	}
+}
+
+// worker runs jobs until the polling loop stops.
+func (s *Scheduler) worker() {
+	defer s.wg.Done()
+	for job := range s.jobs {
+		s.runJob(job)
+	}
+}
+
+// runJob runs a single job and records its outcome, retrying it with exponential backoff on failure.
+func (s *Scheduler) runJob(job *db.ScheduledJob) {
+	if s.useRedisLock {
+		lockKey := fmt.Sprintf("alita:scheduler:job:%d", job.ID)
+		token := uuid.NewString()
+		locked, err := cache.AcquireLock(lockKey, token, jobLease)
+		if err != nil {
+			log.Warnf("[Scheduler] Failed to lock job %d, running it anyway: %v", job.ID, err)
+		} else if !locked {
+			log.Debugf("[Scheduler] Job %d is locked by another instance", job.ID)
+			return
+		} else {
+			defer func() {
+				_ = cache.ReleaseLock(lockKey, token)
+			}()
+		}
+	}
+
+	handler, ok := getHandler(job.JobType)
+	if !ok {
+		log.Errorf("[Scheduler] No handler registered for job type %s", job.JobType)
+		_ = db.FailJob(job.ID, "no handler registered")
+		return
+	}
+
+	err := s.callHandler(handler, job)
+	switch {
+	case err == nil:
+		_ = db.CompleteJob(job.ID)
+	case job.Attempts >= job.MaxAttempts:
+		log.Errorf("[Scheduler] Job %d (%s) failed after %d attempts: %v", job.ID, job.JobType, job.Attempts, err)
+		_ = db.FailJob(job.ID, err.Error())
+	default:
+		delay := retryDelay(job.Attempts)
+		log.Warnf("[Scheduler] Job %d (%s) failed, retrying in %v: %v", job.ID, job.JobType, delay, err)
+		_ = db.RetryJob(job.ID, time.Now().Add(delay), err.Error())
+	}
+}
+
+// callHandler runs a job handler, turning a panic into an error so the job is retried.
+func (s *Scheduler) callHandler(handler Handler, job *db.ScheduledJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.WithField("panic", r).Errorf("[Scheduler] Panic in %s job handler", job.JobType)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(s.bot, job)
+}
+
+// retryDelay returns the backoff before retrying a job that failed on its given attempt.
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMaxDelay)
+}
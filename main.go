@@ -21,6 +21,7 @@ import (
 	"github.com/divideprojects/Alita_Robot/alita/utils/errors"
 	"github.com/divideprojects/Alita_Robot/alita/utils/helpers"
 	"github.com/divideprojects/Alita_Robot/alita/utils/monitoring"
+	"github.com/divideprojects/Alita_Robot/alita/utils/scheduler"
 	"github.com/divideprojects/Alita_Robot/alita/utils/shutdown"
 	"github.com/divideprojects/Alita_Robot/alita/utils/webhook"
 
@@ -208,6 +209,13 @@ func main() {
 		return closeDBConnections()
 	})
 
+	// Setup scheduler for delayed jobs, started once modules have registered their job handlers
+	jobScheduler := scheduler.NewScheduler(b)
+	shutdownManager.RegisterHandler(func() error {
+		jobScheduler.Stop()
+		return nil
+	})
+
 	// Start shutdown handler in background
 	go shutdownManager.WaitForShutdown()
 
@@ -271,6 +279,7 @@ func main() {
 
 		// Load modules
 		alita.LoadModules(dispatcher)
+		jobScheduler.Start()
 
 		// list modules from modules dir
 		log.Infof(
@@ -346,6 +355,7 @@ func main() {
 
 		// Load modules
 		alita.LoadModules(dispatcher)
+		jobScheduler.Start()
 
 		// list modules from modules dir
 		log.Infof(
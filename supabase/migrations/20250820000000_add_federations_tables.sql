@@ -0,0 +1,53 @@
+-- Create federations table holding one shared ban list per federation
+CREATE TABLE IF NOT EXISTS federations (
+    id BIGSERIAL PRIMARY KEY,
+    fed_id VARCHAR(64) NOT NULL UNIQUE,
+    fed_name VARCHAR(255) NOT NULL,
+    owner_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_federations_owner_id ON federations(owner_id);
+
+-- Create fed_chats table; a chat can only be part of one federation
+CREATE TABLE IF NOT EXISTS fed_chats (
+    id BIGSERIAL PRIMARY KEY,
+    fed_id VARCHAR(64) NOT NULL,
+    chat_id BIGINT NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_fed_chats_fed FOREIGN KEY (fed_id) REFERENCES federations(fed_id) ON DELETE CASCADE,
+    CONSTRAINT fk_fed_chats_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_fed_chats_fed_id ON fed_chats(fed_id);
+
+-- Create fed_admins table for users allowed to fban in a federation
+CREATE TABLE IF NOT EXISTS fed_admins (
+    id BIGSERIAL PRIMARY KEY,
+    fed_id VARCHAR(64) NOT NULL,
+    user_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uk_fed_admins_fed_user UNIQUE (fed_id, user_id),
+    CONSTRAINT fk_fed_admins_fed FOREIGN KEY (fed_id) REFERENCES federations(fed_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_fed_admins_user_id ON fed_admins(user_id);
+
+-- Create fed_bans table for the shared ban list
+CREATE TABLE IF NOT EXISTS fed_bans (
+    id BIGSERIAL PRIMARY KEY,
+    fed_id VARCHAR(64) NOT NULL,
+    user_id BIGINT NOT NULL,
+    reason TEXT,
+    banned_by BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uk_fed_bans_fed_user UNIQUE (fed_id, user_id),
+    CONSTRAINT fk_fed_bans_fed FOREIGN KEY (fed_id) REFERENCES federations(fed_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_fed_bans_user_id ON fed_bans(user_id);
+
+COMMENT ON TABLE federations IS 'Federations share a ban list across all of their member chats';
+COMMENT ON TABLE fed_bans IS 'Users banned in every chat of a federation';
@@ -0,0 +1,22 @@
+-- Create gbans table for users banned across every chat of the bot
+CREATE TABLE IF NOT EXISTS gbans (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL UNIQUE,
+    reason TEXT NOT NULL,
+    banned_by BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create gban_settings table so chats can opt out of gban enforcement
+CREATE TABLE IF NOT EXISTS gban_settings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL UNIQUE,
+    enabled BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_gban_settings_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE gbans IS 'Users banned by sudo users in every chat where the bot is admin';
+COMMENT ON TABLE gban_settings IS 'Per-chat opt-out of global ban enforcement';
@@ -0,0 +1,29 @@
+-- Create warn_records table storing every warning as its own row
+CREATE TABLE IF NOT EXISTS warn_records (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    chat_id BIGINT NOT NULL,
+    issued_by BIGINT DEFAULT 0,
+    reason TEXT,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_warn_records_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_warn_records_user_chat ON warn_records(user_id, chat_id);
+CREATE INDEX IF NOT EXISTS idx_warn_records_expires_at ON warn_records(expires_at) WHERE expires_at IS NOT NULL;
+
+-- Number of seconds after which a warning expires; 0 means warnings never expire
+ALTER TABLE warns_settings ADD COLUMN IF NOT EXISTS warn_time BIGINT DEFAULT 0;
+
+-- Backfill records for existing warnings; the issuer of these is unknown
+INSERT INTO warn_records (user_id, chat_id, issued_by, reason, created_at)
+SELECT w.user_id, w.chat_id, 0, r.reason, COALESCE(w.updated_at, CURRENT_TIMESTAMP)
+FROM warns_users w
+CROSS JOIN LATERAL jsonb_array_elements_text(
+    CASE WHEN jsonb_typeof(w.warns) = 'array' THEN w.warns ELSE '[]'::jsonb END
+) AS r(reason)
+WHERE w.num_warns > 0
+  AND EXISTS (SELECT 1 FROM chats c WHERE c.chat_id = w.chat_id);
+
+COMMENT ON TABLE warn_records IS 'Individual warnings with issuer, creation time and optional expiry';
@@ -0,0 +1,16 @@
+-- Create warn_ladder_steps table for escalating warn punishments
+CREATE TABLE IF NOT EXISTS warn_ladder_steps (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    warn_count INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    duration BIGINT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uk_warn_ladder_steps_chat_count UNIQUE (chat_id, warn_count),
+    CONSTRAINT chk_warn_ladder_steps_action CHECK (action IN ('mute', 'tmute', 'kick', 'ban', 'tban')),
+    CONSTRAINT fk_warn_ladder_steps_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE warn_ladder_steps IS 'Punishments applied when a user reaches a given number of warnings';
+COMMENT ON COLUMN warn_ladder_steps.duration IS 'Duration in seconds for tmute and tban actions';
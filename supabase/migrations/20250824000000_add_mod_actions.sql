@@ -0,0 +1,19 @@
+-- Create mod_actions table as an audit log of moderation actions
+CREATE TABLE IF NOT EXISTS mod_actions (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    actor_id BIGINT NOT NULL,
+    target_id BIGINT DEFAULT 0,
+    action VARCHAR(32) NOT NULL,
+    reason TEXT,
+    duration BIGINT DEFAULT 0,
+    message_link TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_mod_actions_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_mod_actions_chat_created ON mod_actions(chat_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_mod_actions_chat_target ON mod_actions(chat_id, target_id);
+
+COMMENT ON TABLE mod_actions IS 'Audit log of bans, mutes, kicks, warns, purges and locks per chat';
+COMMENT ON COLUMN mod_actions.duration IS 'Duration in seconds for timed actions, 0 if permanent';
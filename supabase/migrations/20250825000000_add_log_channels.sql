@@ -0,0 +1,18 @@
+-- Create log_channels table for per-chat log channels
+CREATE TABLE IF NOT EXISTS log_channels (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL UNIQUE,
+    channel_id BIGINT NOT NULL,
+    log_moderation BOOLEAN DEFAULT TRUE,
+    log_settings BOOLEAN DEFAULT TRUE,
+    log_captcha BOOLEAN DEFAULT TRUE,
+    log_reports BOOLEAN DEFAULT TRUE,
+    log_joins BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_log_channels_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_log_channels_channel_id ON log_channels(channel_id);
+
+COMMENT ON TABLE log_channels IS 'Channels that moderation, settings, captcha, report and join events of a chat are posted to';
@@ -0,0 +1,20 @@
+-- Create scheduled_jobs table for delayed work that has to survive restarts
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    job_type VARCHAR(64) NOT NULL,
+    job_key VARCHAR(255),
+    payload JSONB DEFAULT '{}'::jsonb,
+    run_at TIMESTAMP NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    attempts INTEGER DEFAULT 0,
+    max_attempts INTEGER DEFAULT 5,
+    last_error TEXT,
+    locked_until TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_status_run_at ON scheduled_jobs(status, run_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_jobs_type_key ON scheduled_jobs(job_type, job_key);
+
+COMMENT ON TABLE scheduled_jobs IS 'Persistent queue of delayed jobs run by the scheduler';
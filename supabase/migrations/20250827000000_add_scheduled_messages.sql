@@ -0,0 +1,28 @@
+-- Create scheduled_messages table for one-off and recurring chat announcements
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    created_by BIGINT NOT NULL,
+    note_content TEXT,
+    file_id TEXT,
+    msg_type BIGINT DEFAULT 1,
+    buttons JSONB DEFAULT '[]'::jsonb,
+    web_preview BOOLEAN DEFAULT FALSE,
+    is_protected BOOLEAN DEFAULT FALSE,
+    no_notif BOOLEAN DEFAULT FALSE,
+    schedule_type VARCHAR(16) NOT NULL CHECK (schedule_type IN ('once', 'interval', 'cron')),
+    spec TEXT NOT NULL,
+    cron_expr TEXT,
+    interval_seconds BIGINT DEFAULT 0,
+    next_run_at TIMESTAMP NOT NULL,
+    auto_pin BOOLEAN DEFAULT FALSE,
+    delete_previous BOOLEAN DEFAULT FALSE,
+    last_message_id BIGINT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_scheduled_messages_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_chat_id ON scheduled_messages(chat_id);
+
+COMMENT ON TABLE scheduled_messages IS 'Messages posted to a chat at a set time or on a recurring schedule';
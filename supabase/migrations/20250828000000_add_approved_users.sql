@@ -0,0 +1,12 @@
+-- Create approved_users table for members exempt from automated moderation
+CREATE TABLE IF NOT EXISTS approved_users (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    approved_by BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_approved_users_chat_user UNIQUE (chat_id, user_id),
+    CONSTRAINT fk_approved_users_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE approved_users IS 'Non-admin members that blacklists, antiflood, locks and captcha do not act on';
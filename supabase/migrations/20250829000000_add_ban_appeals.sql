@@ -0,0 +1,28 @@
+-- Create appeal_settings table for the per-chat ban appeals toggle
+CREATE TABLE IF NOT EXISTS appeal_settings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL UNIQUE,
+    enabled BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_appeal_settings_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+-- Create ban_appeals table for appeals sent by banned users from PM
+CREATE TABLE IF NOT EXISTS ban_appeals (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    statement TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'info_requested', 'approved', 'denied')),
+    reviewed_by BIGINT,
+    reviewed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_ban_appeals_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ban_appeals_chat_user ON ban_appeals(chat_id, user_id, created_at DESC);
+
+COMMENT ON TABLE appeal_settings IS 'Whether banned users can appeal their ban in a chat';
+COMMENT ON TABLE ban_appeals IS 'Ban appeals with their review status, used for cooldowns between appeals';
@@ -0,0 +1,21 @@
+-- Create night_mode table for time-windowed chat permission lockdowns
+CREATE TABLE IF NOT EXISTS night_mode (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL UNIQUE,
+    enabled BOOLEAN DEFAULT FALSE,
+    start_minute INTEGER NOT NULL DEFAULT 1380 CHECK (start_minute BETWEEN 0 AND 1439),
+    end_minute INTEGER NOT NULL DEFAULT 420 CHECK (end_minute BETWEEN 0 AND 1439),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    preset VARCHAR(16) NOT NULL DEFAULT 'readonly' CHECK (preset IN ('readonly', 'media')),
+    start_message TEXT,
+    end_message TEXT,
+    active BOOLEAN DEFAULT FALSE,
+    saved_permissions TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_night_mode_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE night_mode IS 'Daily windows during which a chat is restricted, with the permissions to restore afterwards';
+COMMENT ON COLUMN night_mode.start_minute IS 'Minute of the day the window starts, in the chat timezone';
+COMMENT ON COLUMN night_mode.saved_permissions IS 'JSON encoded chat permissions from before the window started';
@@ -0,0 +1,5 @@
+-- Add an optional expiry to locks so timed locks are lifted automatically
+ALTER TABLE locks
+ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP;
+
+COMMENT ON COLUMN locks.expires_at IS 'When a timed lock is lifted, NULL for locks that stay until unlocked';
@@ -0,0 +1,13 @@
+-- Add a punishment to each lock, applied to users who break it
+ALTER TABLE locks
+ADD COLUMN IF NOT EXISTS action VARCHAR(10) NOT NULL DEFAULT 'delete',
+ADD COLUMN IF NOT EXISTS action_duration BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE locks
+DROP CONSTRAINT IF EXISTS chk_locks_action;
+
+ALTER TABLE locks
+ADD CONSTRAINT chk_locks_action CHECK (action IN ('delete', 'warn', 'mute', 'tmute', 'kick', 'ban', 'tban'));
+
+COMMENT ON COLUMN locks.action IS 'Punishment for breaking the lock, on top of deleting the message';
+COMMENT ON COLUMN locks.action_duration IS 'Duration in seconds of the tmute and tban actions';
@@ -0,0 +1,5 @@
+-- Add a threshold to locks that only apply above a count, such as the mention lock
+ALTER TABLE locks
+ADD COLUMN IF NOT EXISTS threshold INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN locks.threshold IS 'Count above which a count based lock applies, 0 for the default';
@@ -0,0 +1,13 @@
+-- Create lock_domains table for the domains allowed past the url lock or always blocked
+CREATE TABLE IF NOT EXISTS lock_domains (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    domain VARCHAR(253) NOT NULL,
+    list_type VARCHAR(10) NOT NULL CHECK (list_type IN ('allow', 'block')),
+    added_by BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_lock_domains_chat_domain UNIQUE (chat_id, domain),
+    CONSTRAINT fk_lock_domains_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE lock_domains IS 'Per chat domains that pass the url lock (allow) or are removed even without it (block)';
@@ -0,0 +1,9 @@
+-- Add a time window antiflood mode, counting the messages of each user in the last few seconds
+ALTER TABLE antiflood_settings
+ADD COLUMN IF NOT EXISTS timer_limit INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS timer_seconds INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS timer_action VARCHAR(10) NOT NULL DEFAULT 'mute';
+
+COMMENT ON COLUMN antiflood_settings.timer_limit IS 'Messages a user may send within timer_seconds, 0 when the timed mode is off';
+COMMENT ON COLUMN antiflood_settings.timer_seconds IS 'Length of the sliding window of the timed mode';
+COMMENT ON COLUMN antiflood_settings.timer_action IS 'Action taken on users tripping the timed mode';
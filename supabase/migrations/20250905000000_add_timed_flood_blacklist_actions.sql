@@ -0,0 +1,11 @@
+-- Add durations for the timed tmute and tban actions of antiflood and blacklists
+ALTER TABLE antiflood_settings
+ADD COLUMN IF NOT EXISTS action_duration BIGINT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS timer_action_duration BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE blacklists
+ADD COLUMN IF NOT EXISTS action_duration BIGINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN antiflood_settings.action_duration IS 'Duration in seconds of a tmute or tban flood action';
+COMMENT ON COLUMN antiflood_settings.timer_action_duration IS 'Duration in seconds of a tmute or tban timed flood action';
+COMMENT ON COLUMN blacklists.action_duration IS 'Duration in seconds of a tmute or tban blacklist action';
@@ -0,0 +1,36 @@
+-- Create antispam_settings table for the behaviour of chats under burst load
+CREATE TABLE IF NOT EXISTS antispam_settings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL UNIQUE,
+    action VARCHAR(16) NOT NULL DEFAULT 'drop' CHECK (action IN ('drop', 'slowmode', 'restrict')),
+    action_duration BIGINT NOT NULL DEFAULT 300,
+    active_until TIMESTAMP,
+    saved_permissions TEXT,
+    trips BIGINT NOT NULL DEFAULT 0,
+    last_tripped_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_antispam_settings_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE antispam_settings IS 'What happens when a chat sends messages faster than its antispam levels allow';
+COMMENT ON COLUMN antispam_settings.action_duration IS 'Duration in seconds of the slowmode and restrict actions';
+COMMENT ON COLUMN antispam_settings.active_until IS 'When the running slowmode or restrict action ends';
+COMMENT ON COLUMN antispam_settings.saved_permissions IS 'JSON encoded chat permissions from before the restrict action started';
+COMMENT ON COLUMN antispam_settings.trips IS 'Number of times the antispam levels of the chat were exceeded';
+
+-- Create antispam_levels table for the message rates allowed in a chat
+CREATE TABLE IF NOT EXISTS antispam_levels (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    scope VARCHAR(8) NOT NULL CHECK (scope IN ('chat', 'user')),
+    seconds INTEGER NOT NULL CHECK (seconds BETWEEN 1 AND 3600),
+    message_limit INTEGER NOT NULL CHECK (message_limit > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uk_antispam_levels_chat_scope_seconds UNIQUE (chat_id, scope, seconds),
+    CONSTRAINT fk_antispam_levels_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE antispam_levels IS 'Most messages a whole chat or a single user may send within a window';
+COMMENT ON COLUMN antispam_levels.scope IS 'Whether the level counts the messages of the whole chat or of each user';
@@ -0,0 +1,36 @@
+-- Create blacklist_chat_settings table for the default blacklist action of a chat,
+-- so the action and reason stored on a blacklist row only apply to that trigger
+CREATE TABLE IF NOT EXISTS blacklist_chat_settings (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL UNIQUE,
+    action VARCHAR(16) NOT NULL DEFAULT 'warn' CHECK (action IN ('warn', 'mute', 'tmute', 'kick', 'ban', 'tban', 'none')),
+    action_duration BIGINT NOT NULL DEFAULT 0,
+    reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_blacklist_chat_settings_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+COMMENT ON TABLE blacklist_chat_settings IS 'Blacklist action used for triggers without an action of their own';
+COMMENT ON COLUMN blacklist_chat_settings.action_duration IS 'Duration in seconds of a tmute or tban action';
+COMMENT ON COLUMN blacklist_chat_settings.reason IS 'Reason used for triggers without a reason of their own';
+
+-- Every row of a chat carried the same action until now, keep it as the chat default
+INSERT INTO blacklist_chat_settings (chat_id, action, action_duration)
+SELECT DISTINCT ON (chat_id) chat_id, action, COALESCE(action_duration, 0)
+FROM blacklists
+WHERE action IN ('warn', 'mute', 'tmute', 'kick', 'ban', 'tban', 'none')
+ORDER BY chat_id, id
+ON CONFLICT (chat_id) DO NOTHING;
+
+-- Triggers follow the chat default unless they have an action of their own
+ALTER TABLE blacklists ALTER COLUMN action DROP DEFAULT;
+UPDATE blacklists SET action = NULL, action_duration = 0;
+
+ALTER TABLE blacklists DROP CONSTRAINT IF EXISTS chk_blacklists_action;
+ALTER TABLE blacklists
+ADD CONSTRAINT chk_blacklists_action
+CHECK (action IS NULL OR action IN ('warn', 'mute', 'tmute', 'kick', 'ban', 'tban', 'none'));
+
+COMMENT ON COLUMN blacklists.action IS 'Action for this trigger, NULL to use the chat default';
+COMMENT ON COLUMN blacklists.reason IS 'Reason for this trigger, NULL to use the chat default';
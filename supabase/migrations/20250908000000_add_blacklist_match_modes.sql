@@ -0,0 +1,21 @@
+-- Add match modes to blacklist triggers: whole words, glob wildcards and regexes.
+-- Existing triggers keep matching anywhere in a message through the substring mode,
+-- while new plain triggers match whole words only.
+ALTER TABLE blacklists
+ADD COLUMN IF NOT EXISTS match_mode VARCHAR(16) NOT NULL DEFAULT 'substring';
+
+ALTER TABLE blacklists ALTER COLUMN match_mode SET DEFAULT 'word';
+
+ALTER TABLE blacklists DROP CONSTRAINT IF EXISTS chk_blacklists_match_mode;
+ALTER TABLE blacklists
+ADD CONSTRAINT chk_blacklists_match_mode
+CHECK (match_mode IN ('word', 'substring', 'glob', 'regex'));
+
+COMMENT ON COLUMN blacklists.match_mode IS 'How the trigger is matched: word, substring, glob or regex';
+
+-- A trigger is identified by its pattern and match mode, so the same word can be
+-- blacklisted in several modes at once.
+ALTER TABLE blacklists DROP CONSTRAINT IF EXISTS uk_blacklists_chat_word;
+ALTER TABLE blacklists DROP CONSTRAINT IF EXISTS uk_blacklists_chat_word_mode;
+ALTER TABLE blacklists
+ADD CONSTRAINT uk_blacklists_chat_word_mode UNIQUE (chat_id, word, match_mode);
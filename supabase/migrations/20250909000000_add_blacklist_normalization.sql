@@ -0,0 +1,11 @@
+-- Add the text normalization level used by blacklists and filters of a chat,
+-- folding homoglyphs, zero-width characters, diacritics and leetspeak before matching
+ALTER TABLE blacklist_chat_settings
+ADD COLUMN IF NOT EXISTS normalization VARCHAR(16) NOT NULL DEFAULT 'off';
+
+ALTER TABLE blacklist_chat_settings DROP CONSTRAINT IF EXISTS chk_blacklist_chat_settings_normalization;
+ALTER TABLE blacklist_chat_settings
+ADD CONSTRAINT chk_blacklist_chat_settings_normalization
+CHECK (normalization IN ('off', 'basic', 'strict'));
+
+COMMENT ON COLUMN blacklist_chat_settings.normalization IS 'Text normalization applied before matching blacklists and filters: off, basic or strict';
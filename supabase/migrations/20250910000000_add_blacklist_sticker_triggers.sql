@@ -0,0 +1,8 @@
+-- Allow blacklisting stickers, either a single sticker by its file_unique_id
+-- or a whole pack by its set name
+ALTER TABLE blacklists DROP CONSTRAINT IF EXISTS chk_blacklists_match_mode;
+ALTER TABLE blacklists
+ADD CONSTRAINT chk_blacklists_match_mode
+CHECK (match_mode IN ('word', 'substring', 'glob', 'regex', 'sticker', 'sticker_set'));
+
+COMMENT ON COLUMN blacklists.match_mode IS 'How the trigger is matched: word, substring, glob or regex against text, sticker or sticker_set against stickers';
@@ -0,0 +1,43 @@
+-- Create tables for shareable blacklist packs: named trigger lists published from a chat
+-- that other chats subscribe to, always getting the latest published version
+CREATE TABLE IF NOT EXISTS blacklist_packs (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(32) NOT NULL UNIQUE CHECK (name ~ '^[a-z0-9][a-z0-9-]{2,31}$'),
+    owner_chat_id BIGINT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_blacklist_packs_chat FOREIGN KEY (owner_chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS blacklist_pack_triggers (
+    id BIGSERIAL PRIMARY KEY,
+    pack_id BIGINT NOT NULL,
+    word TEXT NOT NULL,
+    match_mode VARCHAR(16) NOT NULL DEFAULT 'word' CHECK (match_mode IN ('word', 'substring', 'glob', 'regex', 'sticker', 'sticker_set')),
+    action VARCHAR(16) CHECK (action IS NULL OR action IN ('warn', 'mute', 'tmute', 'kick', 'ban', 'tban', 'none')),
+    action_duration BIGINT NOT NULL DEFAULT 0,
+    reason TEXT,
+    CONSTRAINT fk_blacklist_pack_triggers_pack FOREIGN KEY (pack_id) REFERENCES blacklist_packs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_blacklist_pack_triggers_pack ON blacklist_pack_triggers(pack_id);
+
+CREATE TABLE IF NOT EXISTS blacklist_pack_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    chat_id BIGINT NOT NULL,
+    pack_id BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_blacklist_pack_subscriptions UNIQUE (chat_id, pack_id),
+    CONSTRAINT fk_blacklist_pack_subscriptions_chat FOREIGN KEY (chat_id) REFERENCES chats(chat_id) ON DELETE CASCADE,
+    CONSTRAINT fk_blacklist_pack_subscriptions_pack FOREIGN KEY (pack_id) REFERENCES blacklist_packs(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_blacklist_pack_subscriptions_pack ON blacklist_pack_subscriptions(pack_id);
+
+COMMENT ON TABLE blacklist_packs IS 'Named blacklist packs published from a chat for other chats to subscribe to';
+COMMENT ON COLUMN blacklist_packs.owner_chat_id IS 'Chat the pack is published from, the only one allowed to update it';
+COMMENT ON COLUMN blacklist_packs.version IS 'Bumped every time the pack is published again';
+COMMENT ON TABLE blacklist_pack_triggers IS 'Triggers of a blacklist pack, copied from its chat when published';
+COMMENT ON COLUMN blacklist_pack_triggers.action IS 'Action for this trigger, NULL to use the default of the subscribed chat';
+COMMENT ON TABLE blacklist_pack_subscriptions IS 'Blacklist packs enforced in a chat along with its own blacklist';
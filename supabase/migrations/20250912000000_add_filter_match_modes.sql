@@ -0,0 +1,27 @@
+-- Add match modes and trigger groups to filters.
+-- Existing filters keep matching anywhere in a message through the substring mode.
+-- Triggers added together for one reply share a group, the id of the first of them.
+ALTER TABLE filters
+ADD COLUMN IF NOT EXISTS match_mode VARCHAR(16) NOT NULL DEFAULT 'substring';
+
+ALTER TABLE filters DROP CONSTRAINT IF EXISTS chk_filters_match_mode;
+ALTER TABLE filters
+ADD CONSTRAINT chk_filters_match_mode
+CHECK (match_mode IN ('substring', 'exact', 'prefix', 'regex'));
+
+ALTER TABLE filters
+ADD COLUMN IF NOT EXISTS group_id BIGINT NOT NULL DEFAULT 0;
+
+UPDATE filters SET group_id = id WHERE group_id = 0;
+
+CREATE INDEX IF NOT EXISTS idx_filters_chat_group ON filters(chat_id, group_id);
+
+COMMENT ON COLUMN filters.match_mode IS 'How the trigger is matched: substring, exact, prefix or regex';
+COMMENT ON COLUMN filters.group_id IS 'Id of the first filter added along with this one, shared by triggers of the same reply';
+
+-- A trigger is identified by its keyword and match mode, so the same keyword can
+-- reply in several modes at once.
+ALTER TABLE filters DROP CONSTRAINT IF EXISTS uk_filters_chat_keyword;
+ALTER TABLE filters DROP CONSTRAINT IF EXISTS uk_filters_chat_keyword_mode;
+ALTER TABLE filters
+ADD CONSTRAINT uk_filters_chat_keyword_mode UNIQUE (chat_id, keyword, match_mode);